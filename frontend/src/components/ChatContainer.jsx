@@ -16,6 +16,7 @@ const ChatContainer = () => {
     selectedUser,
     subscribeToMessages,
     unsubscribeFromMessages,
+    removeExpiredMessages,
   } = useChatStore();
   const { authUser } = useAuthStore();
   const messageEndRef = useRef(null);
@@ -35,6 +36,13 @@ const ChatContainer = () => {
     unsubscribeFromMessages,
   ]);
 
+  // Disappearing messages vanish from the open chat once they expire.
+  useEffect(() => {
+    if (!messages.some((message) => message.expiresAt)) return;
+    const interval = setInterval(removeExpiredMessages, 1000);
+    return () => clearInterval(interval);
+  }, [messages, removeExpiredMessages]);
+
   useEffect(() => {
     if (messageEndRef.current && messages) {
       messageEndRef.current.scrollIntoView({ behavior: "smooth" });
@@ -91,8 +99,8 @@ const ChatContainer = () => {
                   <img
                     src={
                       message.senderId === authUser._id
-                        ? authUser.profilePic || "/avatar.png"
-                        : selectedUser.profilePic || "/avatar.png"
+                        ? authUser.thumbnailUrl || authUser.profilePic || "/avatar.png"
+                        : selectedUser.thumbnailUrl || selectedUser.profilePic || "/avatar.png"
                     }
                     alt="profile pic"
                     className="w-8 h-8 rounded-full border-2 border-gray-700/50"
@@ -127,13 +135,20 @@ const ChatContainer = () => {
                     {message.image && (
                       <div className="mb-2">
                         <img
-                          src={message.image}
+                          src={message.thumbnailUrl || message.image}
                           alt="Attachment"
-                          className="max-w-[250px] rounded-xl border border-gray-600/30 cursor-pointer hover:opacity-80 transition-opacity duration-200"
+                          width={message.imageWidth || undefined}
+                          height={message.imageHeight || undefined}
+                          className="max-w-[250px] h-auto rounded-xl border border-gray-600/30 cursor-pointer hover:opacity-80 transition-opacity duration-200"
                           onClick={() => openImagePreview(message.image)}
                         />
                       </div>
                     )}
+                    {message.audio && (
+                      <div className="mb-2">
+                        <audio controls preload="metadata" src={message.audio} className="max-w-[250px]" />
+                      </div>
+                    )}
                     {message.text && (
                       <p className="text-sm leading-relaxed break-words whitespace-pre-wrap">
                         {message.text}
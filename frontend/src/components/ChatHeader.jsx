@@ -4,7 +4,8 @@ import { useChatStore } from "../store/useChatStore";
 
 const ChatHeader = () => {
   const { selectedUser, setSelectedUser } = useChatStore();
-  const { onlineUsers } = useAuthStore();
+  const { onlineUsers, presenceStatuses, typingUsers } = useAuthStore();
+  const status = presenceStatuses[selectedUser._id];
 
   const handleBack = () => {
     setSelectedUser(null);
@@ -40,7 +41,13 @@ const ChatHeader = () => {
               {selectedUser.fullName}
             </h3>
             <p className="text-sm font-medium">
-              {onlineUsers.includes(selectedUser._id) ? (
+              {typingUsers.includes(selectedUser._id) ? (
+                <span className="text-green-400">typing…</span>
+              ) : status === "away" ? (
+                <span className="text-yellow-400">Away</span>
+              ) : status === "dnd" ? (
+                <span className="text-red-400">Do not disturb</span>
+              ) : onlineUsers.includes(selectedUser._id) ? (
                 <span className="text-green-400">Online</span>
               ) : (
                 <span className="text-gray-500">Offline</span>
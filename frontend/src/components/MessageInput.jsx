@@ -1,5 +1,6 @@
-import { useRef, useState } from "react";
+import { useEffect, useRef, useState } from "react";
 import { useChatStore } from "../store/useChatStore";
+import { useAuthStore } from "../store/useAuthStore";
 import { Image, Send, X } from "lucide-react";
 import toast from "react-hot-toast";
 
@@ -8,7 +9,16 @@ const MessageInput = () => {
   const [imagePreview, setImagePreview] = useState(null);
   const fileInputRef = useRef(null);
   const textareaRef = useRef(null);
-  const { sendMessage } = useChatStore();
+  const { sendMessage, selectedUser } = useChatStore();
+  const { sendTyping } = useAuthStore();
+  const receiverId = selectedUser?._id;
+
+  // Stop typing when switching to another chat or leaving this one.
+  useEffect(() => {
+    return () => {
+      if (receiverId) sendTyping(receiverId, false);
+    };
+  }, [receiverId, sendTyping]);
 
   const handleImageChange = (e) => {
     const file = e.target.files[0];
@@ -33,6 +43,7 @@ const MessageInput = () => {
     e.preventDefault();
     if (!text.trim() && !imagePreview) return;
 
+    sendTyping(receiverId, false);
     try {
       await sendMessage({
         text: text.trim(),
@@ -68,6 +79,7 @@ const MessageInput = () => {
 
   const handleTextareaChange = (e) => {
     setText(e.target.value);
+    sendTyping(receiverId, e.target.value.trim() !== "");
 
     // Auto-resize textarea
     if (textareaRef.current) {
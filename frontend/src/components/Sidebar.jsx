@@ -2,13 +2,21 @@ import { useEffect, useState } from "react";
 import { useChatStore } from "../store/useChatStore";
 import { useAuthStore } from "../store/useAuthStore";
 import SidebarSkeleton from "./skeletons/SidebarSkeleton";
-import { Users } from "lucide-react";
+import { UserMinus, UserPlus, Users } from "lucide-react";
 
 const Sidebar = () => {
-  const { getUsers, users, selectedUser, setSelectedUser, isUsersLoading } =
-    useChatStore();
+  const {
+    getUsers,
+    users,
+    selectedUser,
+    setSelectedUser,
+    isUsersLoading,
+    showAllUsers,
+    setShowAllUsers,
+    setContact,
+  } = useChatStore();
 
-  const { onlineUsers } = useAuthStore();
+  const { onlineUsers, typingUsers } = useAuthStore();
   const [showOnlineOnly, setShowOnlineOnly] = useState(false);
 
   useEffect(() => {
@@ -49,6 +57,19 @@ const Sidebar = () => {
             ({onlineUsers.length - 1} online)
           </span>
         </div>
+
+        {/* Contacts / everyone toggle */}
+        <label className="mt-2 cursor-pointer flex items-center gap-2">
+          <input
+            type="checkbox"
+            checked={showAllUsers}
+            onChange={(e) => setShowAllUsers(e.target.checked)}
+            className="w-4 h-4 rounded bg-gray-800/50 border border-gray-700/50 text-gray-600 focus:ring-2 focus:ring-gray-600/50 focus:ring-offset-0"
+          />
+          <span className="text-sm text-gray-300 font-medium">
+            Show all users
+          </span>
+        </label>
       </div>
 
       {/* User list */}
@@ -69,7 +90,7 @@ const Sidebar = () => {
           >
             <div className="relative flex-shrink-0">
               <img
-                src={user.profilePic || "/avatar.png"}
+                src={user.thumbnailUrl || user.profilePic || "/avatar.png"}
                 alt={user.name}
                 className="w-12 h-12 object-cover rounded-full border-2 border-gray-700/50"
               />
@@ -80,9 +101,42 @@ const Sidebar = () => {
 
             {/* User info - always visible now */}
             <div className="text-left min-w-0 flex-1">
-              <div className="font-semibold text-white truncate">
-                {user.fullName}
+              <div className="flex items-center justify-between gap-2">
+                <span className="font-semibold text-white truncate">
+                  {user.fullName}
+                </span>
+                {user.unreadCount > 0 && (
+                  <span className="flex-shrink-0 min-w-5 h-5 px-1.5 rounded-full bg-green-500 text-black text-xs font-bold flex items-center justify-center">
+                    {user.unreadCount}
+                  </span>
+                )}
+                {showAllUsers && (
+                  <span
+                    role="button"
+                    title={user.isContact ? "Remove contact" : "Add contact"}
+                    onClick={(e) => {
+                      e.stopPropagation(); // Don't open the chat
+                      setContact(user._id, !user.isContact);
+                    }}
+                    className="flex-shrink-0 p-1 rounded-lg text-gray-400 hover:text-white hover:bg-gray-700/50"
+                  >
+                    {user.isContact ? (
+                      <UserMinus className="w-4 h-4" />
+                    ) : (
+                      <UserPlus className="w-4 h-4" />
+                    )}
+                  </span>
+                )}
               </div>
+              {typingUsers.includes(user._id) ? (
+                <div className="text-sm text-green-400 truncate">typing…</div>
+              ) : user.lastMessage && (
+                <div className="text-sm text-gray-400 truncate">
+                  {user.lastMessage.deleted
+                    ? "Message deleted"
+                    : user.lastMessage.text || "Photo"}
+                </div>
+              )}
               <div className="text-sm text-gray-400 font-medium">
                 {onlineUsers.includes(user._id) ? (
                   <span className="text-green-400">Online</span>
@@ -98,7 +152,13 @@ const Sidebar = () => {
           <div className="text-center text-gray-500 py-8">
             <div className="space-y-2">
               <Users className="w-8 h-8 mx-auto text-gray-600" />
-              <p className="text-sm font-medium">No online users</p>
+              <p className="text-sm font-medium">
+                {showOnlineOnly
+                  ? "No online users"
+                  : showAllUsers
+                    ? "No other users yet"
+                    : "No contacts yet. Show all users to add some."}
+              </p>
             </div>
           </div>
         )}
@@ -5,3 +5,32 @@ export const axiosInstance = axios.create({
   baseURL: import.meta.env.MODE === "development" ? "http://localhost:5000/api" : "/api", 
   withCredentials: true,
 });
+
+// Access tokens are short-lived. When a request fails with 401, try once to
+// get a new one with the refresh token cookie, then replay the request.
+axiosInstance.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    if (
+      error.response?.status !== 401 ||
+      !original ||
+      original._retried ||
+      original.url === "/auth/refresh"
+    ) {
+      return Promise.reject(error);
+    }
+    original._retried = true;
+    try {
+      await axiosInstance.post("/auth/refresh");
+    } catch {
+      return Promise.reject(error);
+    }
+    return axiosInstance(original);
+  }
+);
+
+// Error responses look like { error: { code, message } }. Returns the message
+// to show for a failed request, or fallback when there is none (e.g. network errors).
+export const errorMessage = (error, fallback = "Something went wrong") =>
+  error.response?.data?.error?.message || fallback;
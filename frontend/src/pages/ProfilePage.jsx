@@ -1,10 +1,14 @@
 import { useState } from "react";
 import { useAuthStore } from "../store/useAuthStore";
-import { Camera, Mail, User, Calendar, Shield } from "lucide-react";
+import { Camera, Mail, User, Calendar, Shield, Info } from "lucide-react";
+
+const MAX_BIO_LENGTH = 160; // Same limit as the backend
 
 const ProfilePage = () => {
-  const { authUser, isUpdatingProfile, updateProfile } = useAuthStore();
+  const { authUser, isUpdatingProfile, updateProfile, updateAccount } =
+    useAuthStore();
   const [selectedImg, setSelectedImg] = useState(null);
+  const [bio, setBio] = useState(authUser?.bio || "");
 
   const handleImageUpload = async (e) => {
     const file = e.target.files[0];
@@ -112,6 +116,36 @@ const ProfilePage = () => {
               </div>
             </div>
 
+            {/* Bio */}
+            <div className="space-y-3">
+              <div className="flex items-center gap-3 text-gray-300">
+                <div className="p-2 rounded-xl bg-gradient-to-r from-gray-800 to-gray-900">
+                  <Info className="w-4 h-4" />
+                </div>
+                <span className="text-sm font-medium">About</span>
+              </div>
+              <textarea
+                value={bio}
+                onChange={(e) => setBio(e.target.value)}
+                maxLength={MAX_BIO_LENGTH}
+                rows={2}
+                placeholder="Write something about yourself"
+                className="w-full px-4 py-3 bg-black/20 border border-gray-700/50 rounded-2xl text-white backdrop-blur-sm resize-none focus:outline-none focus:ring-2 focus:ring-gray-600/50"
+              />
+              <div className="flex items-center justify-between">
+                <span className="text-xs text-gray-500">
+                  {bio.length}/{MAX_BIO_LENGTH}
+                </span>
+                <button
+                  onClick={() => updateAccount({ bio })}
+                  disabled={isUpdatingProfile || bio === (authUser?.bio || "")}
+                  className="px-4 py-2 rounded-xl bg-gray-800 text-white text-sm font-medium hover:bg-gray-700 disabled:opacity-50"
+                >
+                  Save
+                </button>
+              </div>
+            </div>
+
             {/* Account Information Card */}
             <div className="mt-8 backdrop-blur-xl bg-black/20 border border-gray-800/50 rounded-2xl p-6">
               <div className="flex items-center gap-3 mb-6">
@@ -1,10 +1,22 @@
 import { create } from "zustand";
-import { axiosInstance } from "../lib/axios.js";
+import { axiosInstance, errorMessage } from "../lib/axios.js";
 import toast from "react-hot-toast";
+import { useChatStore } from "./useChatStore.js"; // For applying other users' profile updates
 
 // WS_URL for WebSocket connection
 const WS_URL = import.meta.env.MODE === "development" ? "ws://localhost:5000/ws" : "wss://your-production-domain.com/ws"; // Use wss:// for production HTTPS
 
+// The server shows idle users as away; any event we send counts as activity.
+// Report user interaction at most once per ACTIVITY_INTERVAL_MS.
+const ACTIVITY_INTERVAL_MS = 60 * 1000;
+const ACTIVITY_EVENTS = ["keydown", "pointerdown", "focus"];
+let lastActivitySent = 0;
+
+// While the user types, "typing" is repeated this often; the server drops a
+// typing state that isn't refreshed within 3 seconds.
+const TYPING_INTERVAL_MS = 1000;
+let lastTypingSent = { receiverId: null, at: 0 };
+
 export const useAuthStore = create((set, get) => ({
   authUser: null,
   isSigningUp: false,
@@ -12,6 +24,8 @@ export const useAuthStore = create((set, get) => ({
   isUpdatingProfile: false,
   isCheckingAuth: true,
   onlineUsers: [],
+  presenceStatuses: {}, // userId -> "online" | "away" | "dnd", for users in onlineUsers
+  typingUsers: [], // IDs of the users currently typing a message to us
   socket: null, // This will now hold a native WebSocket object
 
   checkAuth: async () => {
@@ -31,11 +45,16 @@ export const useAuthStore = create((set, get) => ({
     set({ isSigningUp: true });
     try {
       const res = await axiosInstance.post("/auth/signup", data);
+      // With email verification required, the server doesn't log us in yet.
+      if (res.data.verificationRequired) {
+        toast.success(res.data.message);
+        return;
+      }
       set({ authUser: res.data });
       toast.success("Account created successfully");
       get().connectSocket(); // Connect WebSocket after successful signup
     } catch (error) {
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
     } finally {
       set({ isSigningUp: false });
     }
@@ -49,7 +68,7 @@ export const useAuthStore = create((set, get) => ({
       toast.success("Logged in successfully");
       get().connectSocket(); // Connect WebSocket after successful login
     } catch (error) {
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
     } finally {
       set({ isLoggingIn: false });
     }
@@ -62,7 +81,7 @@ export const useAuthStore = create((set, get) => ({
       toast.success("Logged out successfully");
       get().disconnectSocket(); // Disconnect WebSocket on logout
     } catch (error) {
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
     }
   },
 
@@ -74,7 +93,22 @@ export const useAuthStore = create((set, get) => ({
       toast.success("Profile updated successfully");
     } catch (error) {
       console.log("error in update profile:", error);
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
+    } finally {
+      set({ isUpdatingProfile: false });
+    }
+  },
+
+  // Partially update the profile (fullName, bio, email, profilePic); only
+  // the fields present in data change.
+  updateAccount: async (data) => {
+    set({ isUpdatingProfile: true });
+    try {
+      const res = await axiosInstance.patch("/auth/profile", data);
+      set({ authUser: { ...get().authUser, ...res.data } });
+      toast.success("Profile updated successfully");
+    } catch (error) {
+      toast.error(errorMessage(error));
     } finally {
       set({ isUpdatingProfile: false });
     }
@@ -87,12 +121,21 @@ export const useAuthStore = create((set, get) => ({
       return;
     }
 
-    // Create a new native WebSocket connection
-    const socket = new WebSocket(WS_URL);
+    // Create a new native WebSocket connection.
+    // presence=status asks for getOnlineUsers as { userId, status } entries.
+    const socket = new WebSocket(`${WS_URL}?presence=status`);
+
+    const reportActivity = () => {
+      const now = Date.now();
+      if (socket.readyState !== WebSocket.OPEN || now - lastActivitySent < ACTIVITY_INTERVAL_MS) return;
+      lastActivitySent = now;
+      socket.send(JSON.stringify({ event: "activity" }));
+    };
 
     // Event listener for when the WebSocket connection is established
     socket.onopen = () => {
       console.log("WebSocket connected to Go backend!");
+      ACTIVITY_EVENTS.forEach((name) => window.addEventListener(name, reportActivity));
     };
 
     // Event listener for incoming messages from the WebSocket
@@ -104,7 +147,40 @@ export const useAuthStore = create((set, get) => ({
         // MODIFIED: Read payload for getOnlineUsers.
         // Removed the `else` block as `useChatStore` now handles "newMessage" directly.
         if (data.event === "getOnlineUsers") {
-          set({ onlineUsers: data.payload });
+          set({
+            onlineUsers: data.payload.map((entry) => entry.userId),
+            presenceStatuses: Object.fromEntries(data.payload.map((entry) => [entry.userId, entry.status])),
+          });
+        }
+        if (data.event === "typingStates") {
+          set({ typingUsers: data.payload });
+        }
+        if (data.event === "typing") {
+          const { userId, typing } = data.payload;
+          set((state) => ({
+            typingUsers: typing
+              ? [...state.typingUsers.filter((id) => id !== userId), userId]
+              : state.typingUsers.filter((id) => id !== userId),
+          }));
+        }
+        if (data.event === "userUpdated") {
+          const { _id, fullName, profilePic, thumbnailUrl, bio } = data.payload;
+          if (_id === get().authUser?._id) {
+            // Our own profile, changed from another device.
+            set((state) => ({ authUser: { ...state.authUser, fullName, profilePic, thumbnailUrl, bio } }));
+          } else {
+            useChatStore.getState().applyUserUpdate(data.payload);
+          }
+        }
+        if (data.event === "missedMessages") {
+          // Messages that arrived while we were offline were just replayed as
+          // newMessage events: refresh the sidebar's previews and unread counts,
+          // and reload the open chat if the replay didn't cover everything.
+          const chat = useChatStore.getState();
+          chat.getUsers();
+          if (data.payload.hasMore && chat.selectedUser) {
+            chat.getMessages(chat.selectedUser._id);
+          }
         }
         // No `else if (data.event === "newMessage")` here.
         // `useChatStore`'s `subscribeToMessages` will handle "newMessage" events directly
@@ -122,7 +198,8 @@ export const useAuthStore = create((set, get) => ({
     // Event listener for when the WebSocket connection is closed
     socket.onclose = (event) => {
       console.log("WebSocket disconnected:", event.code, event.reason);
-      set({ socket: null, onlineUsers: [] }); // Clear socket and online users on close
+      ACTIVITY_EVENTS.forEach((name) => window.removeEventListener(name, reportActivity));
+      set({ socket: null, onlineUsers: [], presenceStatuses: {}, typingUsers: [] }); // Clear socket and online users on close
     };
 
     set({ socket: socket }); // Store the native WebSocket object in state
@@ -132,6 +209,29 @@ export const useAuthStore = create((set, get) => ({
     if (get().socket && get().socket.readyState === WebSocket.OPEN) {
       get().socket.close(); // Close the native WebSocket connection
     }
-    set({ socket: null, onlineUsers: [] }); // Clear state
+    set({ socket: null, onlineUsers: [], presenceStatuses: {}, typingUsers: [] }); // Clear state
+  },
+
+  // Tell receiverId we're typing (throttled to TYPING_INTERVAL_MS), or that we stopped.
+  sendTyping: (receiverId, typing) => {
+    const { socket } = get();
+    if (!socket || socket.readyState !== WebSocket.OPEN) return;
+    const now = Date.now();
+    if (typing) {
+      if (lastTypingSent.receiverId === receiverId && now - lastTypingSent.at < TYPING_INTERVAL_MS) return;
+      lastTypingSent = { receiverId, at: now };
+    } else {
+      if (lastTypingSent.receiverId !== receiverId) return; // Never said we were typing
+      lastTypingSent = { receiverId: null, at: 0 };
+    }
+    socket.send(JSON.stringify({ event: typing ? "typing" : "stopTyping", payload: { receiverId } }));
+  },
+
+  // Choose how others see us: "online", "away" or "dnd" (do not disturb).
+  setStatus: (status) => {
+    const { socket } = get();
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify({ event: "setStatus", payload: { status } }));
+    }
   },
 }));
@@ -1,6 +1,6 @@
 import { create } from "zustand";
 import toast from "react-hot-toast";
-import { axiosInstance } from "../lib/axios";
+import { axiosInstance, errorMessage } from "../lib/axios";
 import { useAuthStore } from "./useAuthStore"; // Import useAuthStore to get the socket instance
 
 export const useChatStore = create((set, get) => ({
@@ -9,26 +9,57 @@ export const useChatStore = create((set, get) => ({
   selectedUser: null,
   isUsersLoading: false,
   isMessagesLoading: false,
+  showAllUsers: false, // The sidebar lists contacts only unless this is set
 
   getUsers: async () => {
     set({ isUsersLoading: true });
     try {
-      const res = await axiosInstance.get("/messages/users");
-      set({ users: res.data });
+      const res = await axiosInstance.get("/messages/users", {
+        params: { all: get().showAllUsers },
+      });
+      set({ users: res.data.users });
     } catch (error) {
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
     } finally {
       set({ isUsersLoading: false });
     }
   },
 
+  setShowAllUsers: (showAllUsers) => {
+    set({ showAllUsers });
+    get().getUsers();
+  },
+
+  // Add or remove a user from our contacts, then refresh the sidebar.
+  setContact: async (userId, isContact) => {
+    try {
+      if (isContact) {
+        await axiosInstance.post(`/contacts/${userId}`);
+      } else {
+        await axiosInstance.delete(`/contacts/${userId}`);
+      }
+      get().getUsers();
+    } catch (error) {
+      toast.error(errorMessage(error));
+    }
+  },
+
+  // Applies a "userUpdated" event ({ _id, fullName, profilePic, bio }) to the
+  // sidebar and the open chat, so names and avatars stay current.
+  applyUserUpdate: (update) => {
+    set((state) => ({
+      users: state.users.map((user) => (user._id === update._id ? { ...user, ...update } : user)),
+      selectedUser: state.selectedUser?._id === update._id ? { ...state.selectedUser, ...update } : state.selectedUser,
+    }));
+  },
+
   getMessages: async (userId) => {
     set({ isMessagesLoading: true });
     try {
       const res = await axiosInstance.get(`/messages/${userId}`);
-      set({ messages: res.data });
+      set({ messages: res.data.messages });
     } catch (error) {
-      toast.error(error.response.data.message);
+      toast.error(errorMessage(error));
     } finally {
       set({ isMessagesLoading: false });
     }
@@ -47,7 +78,7 @@ export const useChatStore = create((set, get) => ({
       set({ messages: [...messages, res.data] });
     } catch (error) {
       console.error("Error sending message:", error);
-      toast.error(error.response.data.message || "Failed to send message.");
+      toast.error(errorMessage(error, "Failed to send message."));
     }
   },
 
@@ -56,20 +87,8 @@ export const useChatStore = create((set, get) => ({
     const { selectedUser } = get();
     const authUser = useAuthStore.getState().authUser;
 
-    // Extract the actual message payload from the WebSocket message
-    const rawNewMessage = receivedWsMessage.payload;
-
-    // Normalize the message structure to match HTTP fetched messages
-    const normalizedMessage = {
-      _id: rawNewMessage.ID,
-      senderId: rawNewMessage.SenderID,
-      receiverId: rawNewMessage.ReceiverID,
-      text: rawNewMessage.Text,
-      image: rawNewMessage.Image,
-      createdAt: rawNewMessage.CreatedAt,
-      updatedAt: rawNewMessage.UpdatedAt,
-      // Add any other fields if necessary, ensuring consistent naming
-    };
+    // The payload has the same shape as messages fetched over HTTP.
+    const newMessage = receivedWsMessage.payload;
 
     // Ensure selectedUser and authUser are available before comparison
     if (!selectedUser || !authUser) {
@@ -80,14 +99,17 @@ export const useChatStore = create((set, get) => ({
     // A message should be added to the current chat if:
     // 1. The message is from the currently selected user AND is for the authenticated user (incoming).
     // 2. The message is sent by the authenticated user AND is for the currently selected user (outgoing, for sender's own UI).
-    // Use normalizedMessage properties for comparison
     const isMessageForCurrentChat =
-        (normalizedMessage.senderId === selectedUser._id && normalizedMessage.receiverId === authUser._id) ||
-        (normalizedMessage.senderId === authUser._id && normalizedMessage.receiverId === selectedUser._id);
+        (newMessage.senderId === selectedUser._id && newMessage.receiverId === authUser._id) ||
+        (newMessage.senderId === authUser._id && newMessage.receiverId === selectedUser._id);
 
-    if (isMessageForCurrentChat) {
+    // The server also echoes our own messages to all our devices, so the tab that
+    // sent this one already has it from the HTTP response.
+    const alreadyShown = get().messages.some((message) => message._id === newMessage._id);
+
+    if (isMessageForCurrentChat && !alreadyShown) {
       set((state) => ({
-        messages: [...state.messages, normalizedMessage] // Add the normalized message
+        messages: [...state.messages, newMessage]
       }));
     } else {
       console.log("Message not for current chat or user. Skipping display (not for current chat or user).");
@@ -138,5 +160,14 @@ export const useChatStore = create((set, get) => ({
     }
   },
 
+  // Drops disappearing messages whose expiresAt has passed. The server stops
+  // returning them at that point, so there is no event to wait for.
+  removeExpiredMessages: () => {
+    const now = Date.now();
+    const { messages } = get();
+    const remaining = messages.filter((message) => !message.expiresAt || new Date(message.expiresAt).getTime() > now);
+    if (remaining.length !== messages.length) set({ messages: remaining });
+  },
+
   setSelectedUser: (selectedUser) => set({ selectedUser }),
 }));
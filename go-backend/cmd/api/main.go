@@ -1,15 +1,20 @@
 package main
 
 import (
-	"log"      // For logging messages
+	"context"  // For the shutdown deadline
+	"log"      // For fatal startup errors
+	"log/slog" // Structured logging
 	"os"       // For interacting with the operating system (e.g., signals)
 	"os/signal" // For handling OS signals (e.g., Ctrl+C)
 	"syscall"  // For specific system calls (e.g., SIGINT, SIGTERM)
+	"time"     // For the worker shutdown timeout
 
 	"go-backend/config" // Import your config package
 	"go-backend/pkg/db" // Import your db package for MongoDB connection
 	"go-backend/internal/server" // Import your server package
+	"go-backend/pkg/logging" // Structured JSON logger
 	"go-backend/pkg/utils" // ADDED: Import your utils package to initialize WebSocket Hub
+	"go-backend/pkg/workers" // Background worker lifecycle management
 )
 
 func main() {
@@ -19,18 +24,40 @@ func main() {
 		log.Fatal("Failed to load configuration.")
 	}
 
+	// Structured JSON logging. Setting it as the default also routes anything
+	// still written through the standard `log` package into the same JSON stream.
+	logger := logging.New(cfg)
+	slog.SetDefault(logger)
+
 	// 2. Connect to MongoDB.
-	db.ConnectDB(cfg)
+	if err := db.ConnectDB(cfg); err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
 	defer db.DisconnectDB()
 
+	// Lowercase emails stored before normalization; refuses to start if that
+	// would merge two accounts.
+	if err := db.NormalizeEmails(); err != nil {
+		log.Fatalf("Failed to normalize stored emails: %v", err)
+	}
+
+	// Make sure the indexes queries rely on exist (including the unique email index).
+	if err := db.EnsureIndexes(); err != nil {
+		log.Fatalf("Failed to create MongoDB indexes: %v", err)
+	}
+
 	// 3. Initialize the WebSocket Hub.
 	// This creates the Hub instance and starts its Run() method in a goroutine.
 	// The Hub will now manage WebSocket connections and message broadcasting.
-	hub := utils.InitWebSocketHub()
+	hub := utils.InitWebSocketHub(cfg)
 	// The hub.Run() is already started internally by InitWebSocketHub as a goroutine.
 
+	// Background workers (scheduled jobs, cleanup loops, ...) are started through
+	// this manager so they can all be stopped together on shutdown.
+	workerManager := workers.NewManager()
+
 	// 4. Initialize the Gin server.
-	appServer := server.NewServer(cfg)
+	appServer := server.NewServer(cfg, logger)
 
 	// 5. Setup all API routes.
 	// Pass the initialized WebSocket Hub to the server setup, so it can be used
@@ -47,9 +74,29 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logger.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Stop accepting requests and let in-flight ones finish.
+	if err := appServer.Shutdown(ctx); err != nil {
+		logger.Error("Error draining HTTP requests", "error", err)
+	}
+
+	// Close WebSocket connections with a "going away" frame so clients reconnect
+	// to another instance instead of seeing an abrupt drop.
+	if err := hub.Shutdown(ctx); err != nil {
+		logger.Error("Error closing WebSocket connections", "error", err)
+	}
+
+	// Stop background workers next so in-progress jobs can finish while the
+	// database connection is still open.
+	if err := workerManager.Shutdown(10 * time.Second); err != nil {
+		logger.Error("Error stopping background workers", "error", err)
+	}
 
-	// Perform any cleanup operations here before exiting.
-	// The `defer db.DisconnectDB()` will handle MongoDB disconnection.
-	log.Println("Server gracefully stopped.")
+	// The `defer db.DisconnectDB()` handles MongoDB disconnection last, once
+	// nothing else can touch the database.
+	logger.Info("Server gracefully stopped")
 }
@@ -0,0 +1,68 @@
+// Command seed fills the database with development users.
+//
+//	go run ./cmd/seed                # seed all users, skipping existing ones
+//	go run ./cmd/seed -count 50      # seed 50 users (generating extras)
+//	go run ./cmd/seed -wipe          # delete the seed users first, then reseed
+//	go run ./cmd/seed -file users.json -password s3cret!
+package main
+
+import (
+	"flag" // For command-line flags
+	"log"  // For fatal errors
+
+	"go-backend/config"    // Import your config package
+	"go-backend/pkg/db"    // Import your db package for MongoDB connection
+	"go-backend/pkg/seeds" // The seed data and seeding logic
+)
+
+func main() {
+	count := flag.Int("count", 0, "number of users to seed (0 = the built-in list; more adds generated users)")
+	wipe := flag.Bool("wipe", false, "delete the seed users before inserting them again")
+	file := flag.String("file", "", "JSON file with the users to seed (default: the built-in list)")
+	password := flag.String("password", seeds.DefaultPassword, "password for users that don't set their own")
+	flag.Parse()
+
+	if *count < 0 {
+		log.Fatalf("-count must not be negative, got %d", *count)
+	}
+
+	// Load the users file before connecting, so a bad file fails fast.
+	var users []seeds.SeedUser
+	if *file != "" {
+		var err error
+		users, err = seeds.LoadUsers(*file)
+		if err != nil {
+			log.Fatalf("Failed to load seed users: %v", err)
+		}
+	}
+
+	cfg := config.LoadConfig()
+	if cfg == nil {
+		log.Fatal("Failed to load configuration.")
+	}
+
+	if err := db.ConnectDB(cfg); err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer db.DisconnectDB()
+
+	// Lowercase emails stored before normalization; refuses to start if that
+	// would merge two accounts.
+	if err := db.NormalizeEmails(); err != nil {
+		log.Fatalf("Failed to normalize stored emails: %v", err)
+	}
+
+	// Seeding relies on the unique email index, like the API does.
+	if err := db.EnsureIndexes(); err != nil {
+		log.Fatalf("Failed to create MongoDB indexes: %v", err)
+	}
+
+	if err := seeds.SeedDatabase(seeds.Options{
+		Users:    users,
+		Count:    *count,
+		Password: *password,
+		Wipe:     *wipe,
+	}); err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+}
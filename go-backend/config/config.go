@@ -2,6 +2,9 @@ package config
 import(
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,11 +13,137 @@ import(
 type Config struct{
 	Port string
 	MongoDBURI           string
+	MongoDBName          string // Database name; empty means the one in MongoDBURI (or "chat-db" if it names none)
+
+	// Startup connection retries: attempts are spaced by RetryDelay, doubling each time (capped at 30s).
+	MongoDBConnectMaxAttempts int
+	MongoDBConnectRetryDelay  time.Duration
+
+	// Deadlines of individual operations: each database call a request or
+	// background job makes, each full-collection scan (like the admin
+	// signature check), and each Cloudinary upload or delete.
+	DBTimeout                 time.Duration
+	DBScanTimeout             time.Duration
+	UploadTimeout             time.Duration
 	JWTSecret            string
+
+	// Token lifetimes. Access tokens (the "jwt" cookie) are short-lived and sent
+	// with every request; the long-lived refresh token is only used to obtain new
+	// access tokens and is backed by a revocable session in the database.
+	JWTExpiry            time.Duration
+	RefreshTokenExpiry   time.Duration
+
 	CloudinaryCloudName  string
 	CloudinaryAPIKey     string
 	CloudinaryAPISecret  string
 	NodeEnv              string
+	LogLevel             string // "debug", "info", "warn" or "error"
+
+	// MongoDB read/write concerns. Empty means "use the driver/server defaults".
+	MongoDBWriteConcern  string // "majority", a node count like "1", or a tag set name
+	MongoDBReadConcern   string // "local", "available", "majority", "linearizable" or "snapshot"
+
+	// Content filtering applied to message text on send.
+	ContentFilterMode      string // "off", "reject" (400 the send) or "flag" (store it flagged for review)
+	ContentFilterBlocklist string // Comma-separated words, or /regex/ patterns
+
+	// Optional HMAC key for message integrity signatures. Empty disables signing.
+	MessageSigningKey      string
+
+	// Pagination limits. Requests asking for more than a max get a 400 (no silent clamping).
+	SidebarDefaultLimit    int // Sidebar page size when ?limit is omitted
+	SidebarMaxLimit        int // Largest ?limit accepted by the sidebar
+	MessageMaxLimit        int // Largest number of messages returned per conversation in one request
+	SearchMaxLimit         int // Largest ?limit accepted by search endpoints
+
+	// Most messages that can be pinned in one conversation at a time.
+	MaxPinnedMessages      int
+
+	// Reactions. With custom reactions off only the allowlist is accepted; with
+	// them on any single emoji is. Either way a message holds at most
+	// MaxDistinctReactions different emoji.
+	AllowCustomReactions   bool
+	ReactionAllowlist      string // Comma-separated emoji
+	MaxDistinctReactions   int
+
+	// When true, the sidebar's contact list only shows users who added each other.
+	ContactsRequireMutual  bool
+
+	// How long after sending the sender may still edit or delete a message (0 = forever).
+	MessageEditWindow      time.Duration
+	MessageDeleteWindow    time.Duration
+
+	// Proxies whose X-Forwarded-For headers are trusted when resolving client IPs.
+	// Empty means none: the client IP is the TCP peer address.
+	TrustedProxies         string // Comma-separated IPs or CIDRs
+
+	// Brute-force protection for the auth routes.
+	LoginMaxFailures       int           // Failed logins (per IP and per email) before lockouts start
+	LoginFailureWindow     time.Duration // Window in which failed logins are counted
+	SignupRateLimit        int           // Signups allowed per IP per SignupRateWindow
+	SignupRateWindow       time.Duration
+
+	// Frontend origins allowed to call the API (CORS) and open WebSockets.
+	AllowedOrigins         string // Comma-separated origins; "*" allows any (development only). Required in production
+
+	// SameSite attribute of the auth cookies: "lax", "strict", or "none" for a
+	// frontend on a different site (forces the Secure flag).
+	CookieSameSite         string
+
+	// WebSocket connection limits (0 = unlimited).
+	MaxWSConnectionsPerIP  int
+
+	// WebSocket frame sizes. The buffers only affect memory per connection;
+	// WSMaxMessageBytes caps a single incoming message (larger ones close the connection).
+	WSReadBufferSize       int
+	WSWriteBufferSize      int
+	WSMaxMessageBytes      int64
+
+	// Presence tuning for the WebSocket Hub.
+	PresenceOfflineGrace   time.Duration // How long a disconnected user still counts as online (0 = immediately offline)
+	PresenceBroadcastDebounce time.Duration // Coalesce presence broadcasts within this window (0 = send immediately)
+	PresenceMode           string        // "full-list", "contacts-only" or "subscription"
+	PresenceAwayAfter      time.Duration // Show online users as away after this long without activity (0 = never)
+
+	// WebSocket Hub backend: "memory" (single instance) or "redis" (fan-out
+	// and shared presence across instances through Redis pub/sub).
+	HubBackend             string
+	RedisURL               string        // redis://[user:password@]host[:port][/db], or rediss:// for TLS
+	RedisKeyPrefix         string        // Prefix of the Redis channel and keys, to share a Redis between deployments
+
+	// Image uploads (profile pictures and message images).
+	MaxUploadBytes         int           // Largest decoded image accepted, in bytes (0 = no limit)
+	MaxAudioBytes          int           // Largest decoded voice note accepted, in bytes (0 = no limit)
+	MaxAudioDuration       time.Duration // Longest voice note accepted (0 = no limit)
+	MaxRequestBodyBytes    int           // Largest request body on upload routes, in bytes, checked before decoding (0 = no limit)
+
+	// Cloudinary folders, and the size images are scaled down to fit before
+	// they are stored (in pixels, 0 = keep the original size).
+	CloudinaryProfilePicFolder string
+	CloudinaryImageFolder      string
+	CloudinaryAudioFolder      string
+	ProfilePicMaxDimension     int
+	ImageMaxDimension          int
+	ThumbnailSize              int // Bound of the thumbnail made of every uploaded image, in pixels (0 = no thumbnails)
+
+	// Password reset emails.
+	AppBaseURL             string        // Frontend URL that reset links point at
+	PasswordResetTTL       time.Duration // How long a reset token stays valid
+
+	// Email verification after signup.
+	RequireEmailVerification bool        // Refuse logins (and skip the signup session) until the email is verified
+	EmailVerificationTTL   time.Duration // How long a verification link stays valid
+
+	// Email users who are offline when they are @mentioned in a group.
+	MentionEmails          bool
+
+	// Response compression for API routes.
+	CompressionEnabled   bool // Gzip/deflate API responses when the client accepts it
+	CompressionMinBytes  int  // Responses smaller than this are sent uncompressed
+
+	// Prometheus metrics at GET /metrics.
+	MetricsEnabled         bool   // Serve /metrics at all
+	MetricsAllowedNetworks string // Comma-separated IPs/CIDRs allowed to scrape; empty = anyone
 }
 
 // LoadConfig reads environment variables and returns a Config struct   
@@ -25,20 +154,179 @@ func LoadConfig() *Config{
 	if err != nil{
 		log.Println("No .env file found, assuming environment variables are set directly in the environment.")
 	}
-	return &Config{
+	cfg := &Config{
 		Port:                 getEnv("PORT", "5000"), // Default to 5000 if not set
 		MongoDBURI:           getEnv("MONGODB_URI", "mongodb://localhost:27017/chat-app"), // Default URI
+		MongoDBName:          getEnv("MONGODB_DB_NAME", ""),
+		MongoDBConnectMaxAttempts: getEnvInt("MONGODB_CONNECT_MAX_ATTEMPTS", 5),
+		MongoDBConnectRetryDelay:  getEnvDuration("MONGODB_CONNECT_RETRY_DELAY", time.Second),
+		DBTimeout:                 getEnvDuration("DB_TIMEOUT", 5*time.Second),
+		DBScanTimeout:             getEnvDuration("DB_SCAN_TIMEOUT", 30*time.Second),
+		UploadTimeout:             getEnvDuration("UPLOAD_TIMEOUT", 30*time.Second),
 		JWTSecret:            getEnv("JWT_SECRET", "supersecretjwtkeyforlocaldevonly"), // IMPORTANT: Change this default in production, better to ensure it's always set in .env
+		JWTExpiry:            getEnvDuration("JWT_EXPIRY", 15*time.Minute),
+		RefreshTokenExpiry:   getEnvDuration("REFRESH_TOKEN_EXPIRY", 7*24*time.Hour),
 		CloudinaryCloudName:  getEnv("CLOUDINARY_CLOUD_NAME", ""),
 		CloudinaryAPIKey:     getEnv("CLOUDINARY_API_KEY", ""),
 		CloudinaryAPISecret:  getEnv("CLOUDINARY_API_SECRET", ""),
 		NodeEnv:              getEnv("NODE_ENV", "development"),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		MongoDBWriteConcern:  getEnv("MONGODB_WRITE_CONCERN", ""),
+		MongoDBReadConcern:   getEnv("MONGODB_READ_CONCERN", ""),
+		ContentFilterMode:      getEnv("CONTENT_FILTER_MODE", "off"),
+		ContentFilterBlocklist: getEnv("CONTENT_FILTER_BLOCKLIST", ""),
+		MessageSigningKey:      getEnv("MESSAGE_SIGNING_KEY", ""),
+		SidebarDefaultLimit:    getEnvInt("SIDEBAR_DEFAULT_LIMIT", 100),
+		SidebarMaxLimit:        getEnvInt("SIDEBAR_MAX_LIMIT", 200),
+		MessageMaxLimit:        getEnvInt("MESSAGE_MAX_LIMIT", 100),
+		SearchMaxLimit:         getEnvInt("SEARCH_MAX_LIMIT", 50),
+		MaxPinnedMessages:      getEnvInt("MAX_PINNED_MESSAGES", 3),
+		AllowCustomReactions:   getEnvBool("ALLOW_CUSTOM_REACTIONS", false),
+		ReactionAllowlist:      getEnv("REACTION_ALLOWLIST", "👍,❤️,😂,😮,😢,🙏"),
+		MaxDistinctReactions:   getEnvInt("MAX_DISTINCT_REACTIONS", 20),
+		ContactsRequireMutual:  getEnvBool("CONTACTS_REQUIRE_MUTUAL", false),
+		MessageEditWindow:      getEnvDuration("MESSAGE_EDIT_WINDOW", 15*time.Minute),
+		MessageDeleteWindow:    getEnvDuration("MESSAGE_DELETE_WINDOW", 48*time.Hour),
+		TrustedProxies:         getEnv("TRUSTED_PROXIES", ""),
+		LoginMaxFailures:       getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginFailureWindow:     getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		SignupRateLimit:        getEnvInt("SIGNUP_RATE_LIMIT", 5),
+		SignupRateWindow:       getEnvDuration("SIGNUP_RATE_WINDOW", time.Hour),
+		AllowedOrigins:         getAllowedOrigins(getEnv("NODE_ENV", "development")),
+		CookieSameSite:         getEnv("COOKIE_SAMESITE", "lax"),
+		MaxWSConnectionsPerIP:  getEnvInt("MAX_WS_CONNECTIONS_PER_IP", 20),
+		WSReadBufferSize:       getEnvInt("WS_READ_BUFFER_SIZE", 1024),
+		WSWriteBufferSize:      getEnvInt("WS_WRITE_BUFFER_SIZE", 1024),
+		WSMaxMessageBytes:      int64(getEnvInt("WS_MAX_MESSAGE_BYTES", 64<<10)), // 64 KB
+		PresenceOfflineGrace:   getEnvDuration("PRESENCE_OFFLINE_GRACE", 5*time.Second),
+		PresenceBroadcastDebounce: getEnvDuration("PRESENCE_BROADCAST_DEBOUNCE", 0),
+		PresenceMode:           getEnv("PRESENCE_MODE", "full-list"),
+		PresenceAwayAfter:      getEnvDuration("PRESENCE_AWAY_AFTER", 5*time.Minute),
+		HubBackend:             getEnv("HUB_BACKEND", "memory"),
+		RedisURL:               getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RedisKeyPrefix:         getEnv("REDIS_KEY_PREFIX", "chat"),
+		MaxUploadBytes:         getEnvInt("MAX_UPLOAD_BYTES", 5<<20), // 5 MB
+		MaxAudioBytes:          getEnvInt("MAX_AUDIO_UPLOAD_BYTES", 5<<20), // 5 MB; a few minutes of Opus
+		MaxAudioDuration:       getEnvDuration("MAX_AUDIO_DURATION", 2*time.Minute),
+		MaxRequestBodyBytes:    getEnvInt("MAX_REQUEST_BODY_BYTES", 8<<20), // 8 MB; base64 of a 5 MB upload plus the JSON around it
+		CloudinaryProfilePicFolder: getEnv("CLOUDINARY_PROFILE_PIC_FOLDER", "chat_app_profile_pics"),
+		CloudinaryImageFolder:  getEnv("CLOUDINARY_IMAGE_FOLDER", "chat_app_images"),
+		CloudinaryAudioFolder:  getEnv("CLOUDINARY_AUDIO_FOLDER", "chat_app_audio"),
+		ProfilePicMaxDimension: getEnvInt("PROFILE_PIC_MAX_DIMENSION", 512),
+		ImageMaxDimension:      getEnvInt("IMAGE_MAX_DIMENSION", 1920),
+		ThumbnailSize:          getEnvInt("THUMBNAIL_SIZE", 200),
+		AppBaseURL:             getEnv("APP_BASE_URL", "http://localhost:5173"),
+		PasswordResetTTL:       getEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		EmailVerificationTTL:   getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		MentionEmails:          getEnvBool("MENTION_EMAILS", false),
+		CompressionEnabled:   getEnvBool("COMPRESSION_ENABLED", true),
+		CompressionMinBytes:  getEnvInt("COMPRESSION_MIN_BYTES", 1024), // ~1KB; compressing tiny payloads costs more than it saves
+		MetricsEnabled:         getEnvBool("METRICS_ENABLED", true),
+		// Loopback and private networks by default: scrapers run next to the
+		// server, and the counts shouldn't be public.
+		MetricsAllowedNetworks: getEnv("METRICS_ALLOWED_NETWORKS", "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"),
+	}
+
+	// A zero lifetime would issue tokens that are already expired.
+	if cfg.JWTExpiry <= 0 || cfg.RefreshTokenExpiry <= 0{
+		log.Fatalf("JWT_EXPIRY and REFRESH_TOKEN_EXPIRY must be positive durations (e.g. \"15m\", \"168h\")")
+	}
+	// Without a cap a client could make the server buffer an arbitrarily large frame.
+	if cfg.WSReadBufferSize <= 0 || cfg.WSWriteBufferSize <= 0 || cfg.WSMaxMessageBytes <= 0{
+		log.Fatalf("WS_READ_BUFFER_SIZE, WS_WRITE_BUFFER_SIZE and WS_MAX_MESSAGE_BYTES must be positive")
+	}
+	// A zero deadline would fail every query (or upload) immediately.
+	if cfg.DBTimeout <= 0 || cfg.DBScanTimeout <= 0 || cfg.UploadTimeout <= 0{
+		log.Fatalf("DB_TIMEOUT, DB_SCAN_TIMEOUT and UPLOAD_TIMEOUT must be positive durations (e.g. \"5s\", \"30s\")")
+	}
+	if cfg.ProfilePicMaxDimension < 0 || cfg.ImageMaxDimension < 0 || cfg.ThumbnailSize < 0 {
+		log.Fatalf("PROFILE_PIC_MAX_DIMENSION, IMAGE_MAX_DIMENSION and THUMBNAIL_SIZE can't be negative (0 turns them off)")
+	}
+	// Zero would refuse every new reaction.
+	if cfg.MaxDistinctReactions <= 0{
+		log.Fatalf("MAX_DISTINCT_REACTIONS must be positive")
+	}
+	return cfg
+}
+// devAllowedOrigins are the frontend origins allowed when none are configured
+// outside production: the Vite dev server.
+const devAllowedOrigins = "http://localhost:5173,http://127.0.0.1:5173"
+
+// getAllowedOrigins reads the allowed frontend origins from ALLOWED_ORIGINS or,
+// if that is unset, CORS_ALLOWED_ORIGINS (the two are synonyms).
+// Outside production it falls back to devAllowedOrigins; in production the
+// variable is required and "*" is refused, since a wrong value there either
+// breaks the frontend or opens credentialed CORS to every site.
+func getAllowedOrigins(nodeEnv string) string{
+	value, exists := os.LookupEnv("ALLOWED_ORIGINS")
+	if !exists{
+		value, exists = os.LookupEnv("CORS_ALLOWED_ORIGINS")
+	}
+	if nodeEnv != "production"{
+		if !exists{
+			return devAllowedOrigins
+		}
+		return value
+	}
+	if strings.TrimSpace(value) == ""{
+		log.Fatalf("CORS_ALLOWED_ORIGINS (or ALLOWED_ORIGINS) must be set in production")
 	}
+	for _, origin := range strings.Split(value, ","){
+		if strings.TrimSpace(origin) == "*"{
+			log.Fatalf("CORS_ALLOWED_ORIGINS must list explicit origins in production, not \"*\"")
+		}
+	}
+	return value
 }
+
 // Helper function to get environment variable with a fallback default value
 func getEnv(key string , defaultvalue string) string{
 	if value, exists := os.LookupEnv(key); exists{
 		return value
 	}
 	return defaultvalue
-}
\ No newline at end of file
+}
+
+// Helper function to get a boolean environment variable with a fallback default value.
+// Accepts the values understood by strconv.ParseBool ("true", "1", "false", "0", ...).
+func getEnvBool(key string, defaultvalue bool) bool{
+	value, exists := os.LookupEnv(key)
+	if !exists{
+		return defaultvalue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil{
+		log.Printf("Invalid boolean for %s (%q), using default %v", key, value, defaultvalue)
+		return defaultvalue
+	}
+	return parsed
+}
+
+// Helper function to get an integer environment variable with a fallback default value.
+func getEnvInt(key string, defaultvalue int) int{
+	value, exists := os.LookupEnv(key)
+	if !exists{
+		return defaultvalue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil{
+		log.Printf("Invalid integer for %s (%q), using default %d", key, value, defaultvalue)
+		return defaultvalue
+	}
+	return parsed
+}
+
+// Helper function to get a duration environment variable (e.g. "5s", "15m") with a fallback default value.
+func getEnvDuration(key string, defaultvalue time.Duration) time.Duration{
+	value, exists := os.LookupEnv(key)
+	if !exists{
+		return defaultvalue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0{
+		log.Printf("Invalid duration for %s (%q), using default %s", key, value, defaultvalue)
+		return defaultvalue
+	}
+	return parsed
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"context"  // For context with MongoDB operations
+	"net/http" // For HTTP status codes
+	"sync"     // For guarding the cached stats
+	"time"     // For time windows and the cache TTL
+
+	"go-backend/pkg/db"    // Import db to access MongoDB client
+	"go-backend/pkg/utils" // Import utils for the WebSocket Hub
+
+	"github.com/gin-gonic/gin"         // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
+)
+
+// statsCacheTTL is how long GetStats serves a cached result before recomputing.
+// The counts scan whole collections, so a dashboard refreshing every few
+// seconds shouldn't trigger a scan each time.
+const statsCacheTTL = 30 * time.Second
+
+// AdminHandler holds dependencies for admin-only operations.
+// All of its routes are mounted behind AuthMiddleware + AdminMiddleware.
+type AdminHandler struct {
+	Hub *utils.Hub
+
+	statsMu       sync.Mutex
+	cachedStats   gin.H
+	statsCachedAt time.Time
+}
+
+// NewAdminHandler creates a new instance of AdminHandler.
+func NewAdminHandler(hub *utils.Hub) *AdminHandler {
+	return &AdminHandler{Hub: hub}
+}
+
+// GetStats returns aggregate activity numbers for the ops dashboard.
+// "Active" users are those who sent at least one message in the window.
+// The database counts are cached for statsCacheTTL; the online count comes
+// straight from the Hub and is always current.
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	if h.cachedStats == nil || time.Since(h.statsCachedAt) > statsCacheTTL {
+		stats, err := computeStats()
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error computing stats", err)
+			return
+		}
+		h.cachedStats = stats
+		h.statsCachedAt = time.Now()
+	}
+
+	response := gin.H{"onlineUsers": h.Hub.OnlineCount(), "computedAt": h.statsCachedAt}
+	for key, value := range h.cachedStats {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// computeStats runs the database queries behind GetStats.
+func computeStats() (gin.H, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	users := db.DB.Collection("users")
+	messages := db.DB.Collection("messages")
+	now := time.Now()
+
+	totalUsers, err := users.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	// Collection metadata count: exact enough for a dashboard and doesn't scan.
+	totalMessages, err := messages.EstimatedDocumentCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messagesLast24h, err := messages.CountDocuments(ctx, bson.M{"createdAt": bson.M{"$gte": now.Add(-24 * time.Hour)}})
+	if err != nil {
+		return nil, err
+	}
+	active24h, err := messages.Distinct(ctx, "senderId", bson.M{"createdAt": bson.M{"$gte": now.Add(-24 * time.Hour)}})
+	if err != nil {
+		return nil, err
+	}
+	active7d, err := messages.Distinct(ctx, "senderId", bson.M{"createdAt": bson.M{"$gte": now.Add(-7 * 24 * time.Hour)}})
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"totalUsers":      totalUsers,
+		"activeUsers24h":  len(active24h),
+		"activeUsers7d":   len(active7d),
+		"totalMessages":   totalMessages,
+		"messagesLast24h": messagesLast24h,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"net/http" // For HTTP status codes
+
+	"go-backend/internal/models" // Import models for the Report and User structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For error responses and pagination
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For find options
+)
+
+// Page size of ListReports.
+const (
+	defaultReportsPerPage = 50
+	maxReportsPerPage     = 200
+)
+
+// reportUser identifies the reporter or the reported user of a report.
+type reportUser struct {
+	ID       string `json:"_id"`
+	FullName string `json:"fullName"`
+	Email    string `json:"email"`
+}
+
+// reportResponse is a report as ListReports returns it.
+type reportResponse struct {
+	ID        string          `json:"_id"`
+	MessageID string          `json:"messageId"`
+	Reason    string          `json:"reason"`
+	Details   string          `json:"details"`
+	Message   reportedMessage `json:"message"`  // The message as it was reported
+	Reporter  *reportUser     `json:"reporter"` // null if the account was deleted
+	Sender    *reportUser     `json:"sender"`   // null if the account was deleted
+	CreatedAt utils.Timestamp `json:"createdAt"`
+}
+
+// reportedMessage is the snapshot of a reported message.
+type reportedMessage struct {
+	Text  string `json:"text"`
+	Image string `json:"image"`
+	Audio string `json:"audio"`
+}
+
+// ListReports returns message reports, newest first, one page at a time.
+// Supports ?limit (default 50, max 200), ?page, ?userId (reports against
+// messages sent by that user) and ?messageId. `total` counts all matching
+// reports.
+func (h *AdminHandler) ListReports(c *gin.Context) {
+	limit, ok := utils.ParseLimit(c, defaultReportsPerPage, maxReportsPerPage)
+	if !ok {
+		return
+	}
+	page, ok := utils.ParsePage(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{}
+	for param, field := range map[string]string{"userId": "senderId", "messageId": "messageId"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid "+param+" format")
+			return
+		}
+		filter[field] = id
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	reportsCollection := db.DB.Collection("reports")
+	total, err := reportsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error counting reports", err)
+		return
+	}
+
+	// One extra report tells whether another page exists.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit + 1))
+	cursor, err := reportsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching reports", err)
+		return
+	}
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil { // All closes the cursor
+		utils.RespondInternalError(c, "Error decoding reports", err)
+		return
+	}
+	hasMore := len(reports) > limit
+	if hasMore {
+		reports = reports[:limit]
+	}
+
+	// Reporters and senders of this page, in one query.
+	var userIDs []primitive.ObjectID
+	for _, report := range reports {
+		userIDs = append(userIDs, report.ReporterID, report.SenderID)
+	}
+	users := make(map[primitive.ObjectID]*reportUser)
+	if len(userIDs) > 0 {
+		userOptions := options.Find().SetProjection(bson.M{"fullName": 1, "email": 1})
+		userCursor, err := db.DB.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}}, userOptions)
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error fetching users", err)
+			return
+		}
+		var found []models.User
+		if err := userCursor.All(ctx, &found); err != nil {
+			utils.RespondInternalError(c, "Error decoding users", err)
+			return
+		}
+		for _, user := range found {
+			users[user.ID] = &reportUser{ID: user.ID.Hex(), FullName: user.FullName, Email: user.Email}
+		}
+	}
+
+	response := make([]reportResponse, len(reports))
+	for i, report := range reports {
+		response[i] = reportResponse{
+			ID:        report.ID.Hex(),
+			MessageID: report.MessageID.Hex(),
+			Reason:    report.Reason,
+			Details:   report.Details,
+			Message: reportedMessage{
+				Text:  report.MessageText,
+				Image: report.MessageImage,
+				Audio: report.MessageAudio,
+			},
+			Reporter:  users[report.ReporterID],
+			Sender:    users[report.SenderID],
+			CreatedAt: utils.Timestamp(report.CreatedAt),
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reports": response,
+		"page":    page,
+		"limit":   limit,
+		"total":   total,
+		"hasMore": hasMore,
+	})
+}
@@ -0,0 +1,285 @@
+package admin
+
+import (
+	"context"  // For the helpers' context parameter
+	"net/http" // For HTTP status codes
+	"regexp"   // For escaping the search term
+	"strconv"  // For parsing ?banned
+	"time"     // For the ban timestamp
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Per-request structured logger
+	"go-backend/pkg/utils"       // For error responses, pagination and the Hub
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"github.com/gorilla/websocket"               // For the close code sent to a banned user's connections
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For find options
+)
+
+// Page size of ListUsers.
+const (
+	defaultUsersPerPage = 50
+	maxUsersPerPage     = 200
+)
+
+// adminUserResponse is a user as the admin user list shows it: the account
+// details other users don't see, plus activity numbers.
+type adminUserResponse struct {
+	ID            string          `json:"_id"`
+	FullName      string          `json:"fullName"`
+	Email         string          `json:"email"`
+	ProfilePic    string          `json:"profilePic"`
+	IsAdmin       bool            `json:"isAdmin"`
+	EmailVerified bool            `json:"emailVerified"`
+	Banned        bool            `json:"banned"`
+	BannedAt      utils.Timestamp `json:"bannedAt"` // null unless banned
+	BannedBy      *string         `json:"bannedBy"` // null unless banned
+	Online        bool            `json:"online"`
+	LastSeen      utils.Timestamp `json:"lastSeen"`
+	MessageCount  int64           `json:"messageCount"` // Messages the user has sent
+	CreatedAt     utils.Timestamp `json:"createdAt"`
+}
+
+// newAdminUserResponse builds the admin view of a user.
+func newAdminUserResponse(user models.User, messageCount int64, online bool) adminUserResponse {
+	response := adminUserResponse{
+		ID:            user.ID.Hex(),
+		FullName:      user.FullName,
+		Email:         user.Email,
+		ProfilePic:    user.ProfilePic,
+		IsAdmin:       user.IsAdmin,
+		EmailVerified: user.EmailVerified,
+		Banned:        user.IsBanned(),
+		BannedAt:      utils.Timestamp(user.BannedAt),
+		Online:        online,
+		LastSeen:      utils.Timestamp(user.LastSeen),
+		MessageCount:  messageCount,
+		CreatedAt:     utils.Timestamp(user.CreatedAt),
+	}
+	if user.IsBanned() && !user.BannedBy.IsZero() {
+		bannedBy := user.BannedBy.Hex()
+		response.BannedBy = &bannedBy
+	}
+	return response
+}
+
+// findUser loads the user in the URL, answering 404 or 500 itself when it
+// can't.
+func findUser(c *gin.Context, ctx context.Context, userID primitive.ObjectID) (models.User, bool) {
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "User not found")
+		return user, false
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching user", err)
+		return user, false
+	}
+	return user, true
+}
+
+// respondWithUser answers with the admin view of a single user.
+func (h *AdminHandler) respondWithUser(c *gin.Context, ctx context.Context, user models.User) {
+	messageCount, err := db.DB.Collection("messages").CountDocuments(ctx, bson.M{"senderId": user.ID})
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error counting messages", err)
+		return
+	}
+	online := false
+	for _, userID := range h.Hub.OnlineUserIDs() {
+		if userID == user.ID {
+			online = true
+			break
+		}
+	}
+	c.JSON(http.StatusOK, newAdminUserResponse(user, messageCount, online))
+}
+
+// ListUsers returns every account, newest first, one page at a time.
+// Supports ?limit (default 50, max 200), ?page, ?q (case-insensitive match on
+// name or email) and ?banned=true|false. `total` counts all matching users.
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, ok := utils.ParseLimit(c, defaultUsersPerPage, maxUsersPerPage)
+	if !ok {
+		return
+	}
+	page, ok := utils.ParsePage(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{}
+	if query := c.Query("q"); query != "" {
+		// Matched literally, like the user search.
+		pattern := bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"}
+		filter["$or"] = []bson.M{{"fullName": pattern}, {"email": pattern}}
+	}
+	if raw := c.Query("banned"); raw != "" {
+		banned, err := strconv.ParseBool(raw)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "banned must be true or false")
+			return
+		}
+		filter["bannedAt"] = bson.M{"$exists": banned}
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	usersCollection := db.DB.Collection("users")
+	total, err := usersCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error counting users", err)
+		return
+	}
+
+	// One extra user tells whether another page exists.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit + 1)).
+		SetProjection(bson.M{"password": 0})
+	cursor, err := usersCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching users", err)
+		return
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil { // All closes the cursor
+		utils.RespondInternalError(c, "Error decoding users", err)
+		return
+	}
+	hasMore := len(users) > limit
+	if hasMore {
+		users = users[:limit]
+	}
+
+	// Messages sent by the users on this page, in one query.
+	userIDs := make([]primitive.ObjectID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	countCursor, err := db.DB.Collection("messages").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"senderId": bson.M{"$in": userIDs}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$senderId", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error counting messages", err)
+		return
+	}
+	var counts []struct {
+		UserID primitive.ObjectID `bson:"_id"`
+		Count  int64              `bson:"count"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		utils.RespondInternalError(c, "Error decoding message counts", err)
+		return
+	}
+	messageCounts := make(map[primitive.ObjectID]int64, len(counts))
+	for _, count := range counts {
+		messageCounts[count.UserID] = count.Count
+	}
+
+	online := make(map[primitive.ObjectID]bool)
+	for _, userID := range h.Hub.OnlineUserIDs() {
+		online[userID] = true
+	}
+
+	response := make([]adminUserResponse, len(users))
+	for i, user := range users {
+		response[i] = newAdminUserResponse(user, messageCounts[user.ID], online[user.ID])
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users":   response,
+		"page":    page,
+		"limit":   limit,
+		"total":   total,
+		"hasMore": hasMore,
+	})
+}
+
+// BanUser bans the account in the URL: it can no longer log in, every token
+// and session it holds is revoked, and its WebSocket connections are closed.
+// Its messages and conversations are left as they are. Banning a banned user
+// is a no-op; admins (including the caller) can't be banned.
+func (h *AdminHandler) BanUser(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	admin := userAny.(models.User)
+	targetID := utils.ObjectIDParam(c, "id")
+
+	// Not the request's context: a ban must not stop halfway, with the account
+	// marked banned but its sessions still alive, because the admin's client
+	// went away.
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	target, ok := findUser(c, ctx, targetID)
+	if !ok {
+		return
+	}
+	if target.IsAdmin {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Admins can't be banned; remove their admin rights first")
+		return
+	}
+	if target.IsBanned() {
+		h.respondWithUser(c, ctx, target)
+		return
+	}
+
+	// Bumping the token version revokes every access token at once, like
+	// logging out everywhere.
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{"bannedAt": now, "bannedBy": admin.ID, "updatedAt": now},
+		"$inc": bson.M{"tokenVersion": 1},
+	}
+	if _, err := db.DB.Collection("users").UpdateByID(ctx, targetID, update); err != nil {
+		utils.RespondInternalError(c, "Error banning user", err)
+		return
+	}
+	if _, err := db.DB.Collection("sessions").DeleteMany(ctx, bson.M{"userId": targetID}); err != nil {
+		// The token version already locks them out once their access token is refreshed.
+		logging.FromContext(c).Error("Error revoking sessions of banned user", "target_id", targetID.Hex(), "error", err)
+	}
+	h.Hub.DisconnectUser(targetID, websocket.ClosePolicyViolation, "Account banned")
+
+	logging.FromContext(c).Info("User banned", "target_id", targetID.Hex())
+	target.BannedAt, target.BannedBy = now, admin.ID
+	h.respondWithUser(c, ctx, target)
+}
+
+// UnbanUser lifts a ban. The user has to log in again, as their sessions are
+// gone. Unbanning a user who isn't banned is a no-op.
+func (h *AdminHandler) UnbanUser(c *gin.Context) {
+	targetID := utils.ObjectIDParam(c, "id")
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	target, ok := findUser(c, ctx, targetID)
+	if !ok {
+		return
+	}
+	if target.IsBanned() {
+		update := bson.M{
+			"$unset": bson.M{"bannedAt": "", "bannedBy": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		}
+		if _, err := db.DB.Collection("users").UpdateByID(ctx, targetID, update); err != nil {
+			utils.RespondInternalError(c, "Error unbanning user", err)
+			return
+		}
+		logging.FromContext(c).Info("User unbanned", "target_id", targetID.Hex())
+		target.BannedAt, target.BannedBy = time.Time{}, primitive.NilObjectID
+	}
+	h.respondWithUser(c, ctx, target)
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"  // For context with MongoDB operations
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps and the query timeout
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Per-request structured logger
+	"go-backend/pkg/utils"       // Import utils for cookies
+
+	"github.com/gin-gonic/gin"         // Gin context for handling HTTP requests and responses
+	"github.com/gorilla/websocket"     // For the close code sent to open connections
+	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
+	"golang.org/x/crypto/bcrypt"       // For confirming the password
+)
+
+// DeleteAccount permanently deletes the authenticated user's account after
+// confirming their password.
+//
+// Messages are not hard-deleted, because that would punch holes in the other
+// party's history. Instead every message the user sent becomes a deleted-message
+// placeholder (content removed, like DeleteMessage), and the sender ID then
+// refers to a user that no longer exists, which clients show as a deleted user.
+// Messages the user received belong to the other party's history and are kept.
+//
+// Their sessions, password resets and starred messages are removed, they are
+// taken out of group conversations (whose members get a "left the group"
+// notice) and other users' block lists, their Cloudinary profile picture is
+// deleted, their WebSocket connections are closed and the auth cookies are
+// cleared.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
+		return
+	}
+	user := userAny.(models.User)
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Password is required to delete your account")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeIncorrectPassword, "Password is incorrect")
+		return
+	}
+
+	// Not the request's context: once deletion has started it should run to
+	// the end, even if the client disconnects.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Blank the messages first: if this fails the account still exists and the
+	// user can simply try again.
+	now := time.Now()
+	_, err := db.DB.Collection("messages").UpdateMany(ctx,
+		bson.M{"senderId": user.ID, "deleted": bson.M{"$ne": true}},
+		bson.M{
+			"$set": bson.M{"deleted": true, "deletedAt": now, "updatedAt": now},
+			// The signature covered the removed content, so it can't verify any more.
+			"$unset": bson.M{"text": "", "image": "", "imagePublicId": "", "imageWidth": "", "imageHeight": "", "thumbnailUrl": "",
+				"audio": "", "audioPublicId": "", "audioDuration": "", "editHistory": "", "reactions": "", "mentions": "", "signature": "",
+				"pinned": "", "pinnedBy": "", "pinnedAt": ""},
+		})
+	if err != nil {
+		utils.RespondInternalError(c, "Error removing messages", err)
+		return
+	}
+
+	if _, err := db.DB.Collection("users").DeleteOne(ctx, bson.M{"_id": user.ID}); err != nil {
+		utils.RespondInternalError(c, "Error deleting account", err)
+		return
+	}
+
+	// The account is gone (AuthMiddleware now rejects its tokens); the rest is
+	// cleanup, so failures are logged rather than reported.
+	logger := logging.FromContext(c)
+	cleanups := []struct {
+		what string
+		run  func() error
+	}{
+		{"sessions", func() error {
+			_, err := db.DB.Collection("sessions").DeleteMany(ctx, bson.M{"userId": user.ID})
+			return err
+		}},
+		{"password resets", func() error {
+			_, err := db.DB.Collection("password_resets").DeleteMany(ctx, bson.M{"userId": user.ID})
+			return err
+		}},
+		{"email verifications", func() error {
+			_, err := db.DB.Collection("email_verifications").DeleteMany(ctx, bson.M{"userId": user.ID})
+			return err
+		}},
+		{"starred messages", func() error {
+			_, err := db.DB.Collection("starred_messages").DeleteMany(ctx, bson.M{"userId": user.ID})
+			return err
+		}},
+		{"block lists", func() error {
+			_, err := db.DB.Collection("users").UpdateMany(ctx, bson.M{"blockedUsers": user.ID}, bson.M{"$pull": bson.M{"blockedUsers": user.ID, "silentBlocks": user.ID}})
+			return err
+		}},
+		{"contact lists", func() error {
+			_, err := db.DB.Collection("users").UpdateMany(ctx, bson.M{"contacts": user.ID}, bson.M{"$pull": bson.M{"contacts": user.ID}})
+			return err
+		}},
+		{"reply previews", func() error {
+			return utils.RedactReplyPreviews(ctx, bson.M{"replyPreview.senderId": user.ID})
+		}},
+		{"reactions", func() error {
+			_, err := db.DB.Collection("messages").UpdateMany(ctx, bson.M{"reactions.userId": user.ID},
+				bson.M{"$pull": bson.M{"reactions": bson.M{"userId": user.ID}}, "$set": bson.M{"updatedAt": time.Now()}})
+			return err
+		}},
+		{"conversations", func() error {
+			var groups []models.Conversation
+			cursor, err := db.DB.Collection("conversations").Find(ctx, bson.M{"participants": user.ID})
+			if err == nil {
+				err = cursor.All(ctx, &groups)
+			}
+			if err != nil {
+				return err
+			}
+			if len(groups) == 0 {
+				return nil
+			}
+			if _, err := db.DB.Collection("conversations").UpdateMany(ctx, bson.M{"participants": user.ID},
+				bson.M{"$pull": bson.M{"participants": user.ID, "adminIds": user.ID}}); err != nil {
+				return err
+			}
+			// Tell the remaining members, with a notice in each group.
+			notices := make([]interface{}, len(groups))
+			for i, conv := range groups {
+				notices[i] = models.NewSystemMessage(conv.ID, user.ID, models.SystemTypeMemberLeft, user.FullName+" left the group")
+			}
+			if _, err := db.DB.Collection("messages").InsertMany(ctx, notices); err != nil {
+				return err
+			}
+			for i, conv := range groups {
+				h.Hub.EmitConversationMessage(notices[i].(models.Message), conv.Participants)
+			}
+			return nil
+		}},
+	}
+	for _, cleanup := range cleanups {
+		if err := cleanup.run(); err != nil {
+			logger.Error("Error cleaning up after account deletion", "step", cleanup.what, "error", err)
+		}
+	}
+	h.deleteOldProfilePic(user)
+	h.Hub.DisconnectUser(user.ID, websocket.ClosePolicyViolation, "Account deleted")
+
+	utils.SetAuthCookie(c, h.Config, "jwt", "", -1, "/")
+	utils.SetAuthCookie(c, h.Config, refreshCookieName, "", -1, refreshCookiePath)
+
+	logger.Info("Account deleted")
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"      // For context with MongoDB operations
+	"crypto/rand"  // For generating unguessable verification tokens
+	"encoding/hex" // For encoding the token as a string
+	"fmt"          // For formatted error messages
+	"net/http"     // For HTTP status codes
+	"net/url"      // For escaping the token in the verification link
+	"time"         // For handling timestamps
+
+	"go-backend/internal/models" // Import models for User and EmailVerification structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // For logging failures we hide from the client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For error checking
+)
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// resendVerificationResponse is returned whether or not the email belongs to an
+// unverified account, so the endpoint can't be used to discover registered emails.
+const resendVerificationResponse = "If an unverified account exists for that email, a verification link has been sent"
+
+// sendEmailVerification stores a fresh verification token for the user
+// (dropping older ones, so only the latest link works) and emails the link.
+func (h *AuthHandler) sendEmailVerification(ctx context.Context, user models.User) error {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	verifications := db.DB.Collection("email_verifications")
+	if _, err := verifications.DeleteMany(ctx, bson.M{"userId": user.ID}); err != nil {
+		return fmt.Errorf("failed to clear old verification tokens: %w", err)
+	}
+
+	now := time.Now()
+	verification := models.EmailVerification{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.Config.EmailVerificationTTL),
+	}
+	if _, err := verifications.InsertOne(ctx, verification); err != nil {
+		return fmt.Errorf("failed to save verification token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", h.Config.AppBaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address with this link. It expires in %s:\n%s\n\nIf you didn't create an account, you can ignore this email.",
+		user.FullName, h.Config.EmailVerificationTTL, link)
+	return h.Mailer.Send(user.Email, "Confirm your email address", body)
+}
+
+// VerifyEmail marks the account's email as verified using a token from
+// sendEmailVerification. Tokens are single-use.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Verification token is required")
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Atomically claim the token, so it can only be used once. The TTL index
+	// removes expired tokens lazily, hence the explicit expiry check.
+	var verification models.EmailVerification
+	err := db.DB.Collection("email_verifications").FindOneAndDelete(ctx, bson.M{
+		"tokenHash": hashToken(token),
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&verification)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired verification token")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error checking verification token", err)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"emailVerified": true, "updatedAt": time.Now()}}
+	result, err := db.DB.Collection("users").UpdateByID(ctx, verification.UserID, update)
+	if err != nil {
+		utils.RespondInternalError(c, "Error verifying email", err)
+		return
+	}
+	if result.MatchedCount == 0 {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired verification token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification emails a new verification link to an unverified account.
+// It takes an email rather than requiring a login, because logging in may
+// itself require a verified email (REQUIRE_EMAIL_VERIFICATION).
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A valid email is required")
+		return
+	}
+	req.Email = normalizeEmail(req.Email)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"email": req.Email, "emailVerified": bson.M{"$ne": true}}).Decode(&user)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.FromContext(c).Error("Error looking up user for email verification", "error", err)
+		}
+		// Same answer as the success path: don't reveal whether the email exists.
+		c.JSON(http.StatusOK, gin.H{"message": resendVerificationResponse})
+		return
+	}
+
+	if err := h.sendEmailVerification(ctx, user); err != nil {
+		logging.FromContext(c).Error("Error issuing email verification", "user_id", user.ID.Hex(), "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": resendVerificationResponse})
+}
@@ -1,20 +1,25 @@
 package auth
 
 import (
-	"context"    // For context with MongoDB operations
 	"fmt"        // For formatted error messages
+	"log/slog"   // For logging non-fatal errors outside a request
 	"net/http"   // For HTTP status codes
+	"strings"    // For email normalization
 	"time"       // For handling timestamps
+	"unicode/utf8" // For the bio length limit
 
 	"go-backend/config" // Import config for JWT secret and other settings
 	"go-backend/internal/models" // Import models for User struct
 	"go-backend/pkg/db" // Import db to access MongoDB client
+	"go-backend/pkg/logging" // Per-request structured logger
+	"go-backend/pkg/metrics" // Login success/failure counters
 	"go-backend/pkg/utils" // Import utils for JWT generation AND CloudinaryService
 
 	"github.com/gin-gonic/gin" // Gin context for handling requests
 	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
 	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
 	"go.mongodb.org/mongo-driver/mongo" // For MongoDB client operations and error checking
+	"go.mongodb.org/mongo-driver/mongo/options" // For count options
 	"golang.org/x/crypto/bcrypt" // For password hashing
 )
 
@@ -30,6 +35,35 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type EmailAvailableRequest struct {
+	Email string `form:"email" binding:"required,email"`
+}
+
+type UpdatePreferencesRequest struct {
+	SendReadReceipts *bool `json:"sendReadReceipts"` // Optional; omitted fields are left unchanged
+	HideLastSeen     *bool `json:"hideLastSeen"`     // Optional; true hides your last-seen time from others
+}
+
+// UpdateAccountRequest is the body of PATCH /api/auth/profile. Every field is
+// optional; only the ones present are changed.
+type UpdateAccountRequest struct {
+	FullName   *string `json:"fullName"`
+	Bio        *string `json:"bio"`                             // Trimmed; at most models.MaxBioRunes characters, "" clears it
+	Email      *string `json:"email" binding:"omitempty,email"` // Same validation as SignupRequest
+	ProfilePic *string `json:"profilePic"`                      // Base64 image, uploaded to Cloudinary
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=6"` // Same rule as SignupRequest
+}
+
+// DeleteAccountRequest is the body of DELETE /api/auth/account. The password
+// confirms the deletion.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 type UpdateProfileRequest struct {
 	ProfilePic string `json:"profilePic" binding:"required"` // This will be the base64 string
 }
@@ -39,45 +73,57 @@ type UpdateProfileRequest struct {
 type AuthHandler struct {
 	Config          *config.Config
 	CloudinaryService *utils.CloudinaryService // Add Cloudinary service
+	Mailer          utils.Mailer             // Sends password reset emails
+	Hub             *utils.Hub               // Closes a deleted account's WebSocket connections
 }
 
 // NewAuthHandler creates a new instance of AuthHandler.
-// MODIFIED: Accepts CloudinaryService
-func NewAuthHandler(cfg *config.Config, cldService *utils.CloudinaryService) *AuthHandler {
+// MODIFIED: Accepts CloudinaryService, the Mailer and the WebSocket Hub
+func NewAuthHandler(cfg *config.Config, cldService *utils.CloudinaryService, mailer utils.Mailer, hub *utils.Hub) *AuthHandler {
 	return &AuthHandler{
 		Config:          cfg,
 		CloudinaryService: cldService,
+		Mailer:          mailer,
+		Hub:             hub,
 	}
 }
 
+// normalizeEmail trims surrounding whitespace and lowercases an email address,
+// so "Alice@Example.com " and "alice@example.com" refer to the same account.
+// Every lookup or insert by email must go through it.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // Signup handles new user registration.
 // Mirrors backend/src/controllers/auth.controller.js -> signup
 func (h *AuthHandler) Signup(c *gin.Context) {
 	var req SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "All fields are required or invalid format"})
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "All fields are required or invalid format")
 		return
 	}
+	req.Email = normalizeEmail(req.Email)
 
-	// Check if user already exists
+	// Check if user already exists (fast path; see the insert below)
 	var existingUser models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := utils.RequestContext(c)
 	defer cancel()
 
 	err := db.DB.Collection("users").FindOne(ctx, bson.M{"email": req.Email}).Decode(&existingUser)
 	if err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Email already exists"})
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeEmailTaken, "Email already exists")
 		return
 	}
 	if err != mongo.ErrNoDocuments {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Internal server error checking user: %v", err)})
+		utils.RespondInternalError(c, "Internal server error checking user", err)
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Error hashing password"})
+		utils.RespondInternalError(c, "Error hashing password", err)
 		return
 	}
 
@@ -92,25 +138,56 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		UpdatedAt:  time.Now(),
 	}
 
-	// Insert user into database
+	// Insert user into database. The existence check above is only a fast path:
+	// two concurrent signups can both pass it, so the unique email index is the
+	// source of truth and a duplicate-key error means the email is taken.
 	_, err = db.DB.Collection("users").InsertOne(ctx, newUser)
+	if mongo.IsDuplicateKeyError(err) {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeEmailTaken, "Email already exists")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error saving user: %v", err)})
+		utils.RespondInternalError(c, "Error saving user", err)
+		return
+	}
+
+	// Email the verification link. The account exists either way; the user can
+	// ask for a new link via /resend-verification if this fails.
+	if err := h.sendEmailVerification(ctx, newUser); err != nil {
+		logging.FromContext(c).Error("Error issuing email verification", "user_id", newUser.ID.Hex(), "error", err)
+	}
+
+	// When verification is required, the user can't log in yet, so don't start a session.
+	if h.Config.RequireEmailVerification {
+		c.JSON(http.StatusCreated, gin.H{
+			"_id":                  newUser.ID.Hex(),
+			"fullName":             newUser.FullName,
+			"email":                newUser.Email,
+			"profilePic":           newUser.ProfilePic,
+			"emailVerified":        false,
+			"verificationRequired": true,
+			"message":              "Account created. Check your email to verify your address before logging in",
+		})
 		return
 	}
 
 	// Generate JWT token and set cookie
-	if err := utils.GenerateToken(newUser.ID, c, h.Config); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error generating token: %v", err)})
+	if err := utils.GenerateToken(newUser.ID, newUser.TokenVersion, c, h.Config); err != nil {
+		utils.RespondInternalError(c, "Error generating token", err)
+		return
+	}
+	if err := h.startSession(c, newUser.ID); err != nil {
+		utils.RespondInternalError(c, "Error starting session", err)
 		return
 	}
 
 	// Respond with user data (excluding password)
 	c.JSON(http.StatusCreated, gin.H{
-		"_id":        newUser.ID.Hex(), // Convert ObjectID to hex string for frontend
-		"fullName":   newUser.FullName,
-		"email":      newUser.Email,
-		"profilePic": newUser.ProfilePic,
+		"_id":           newUser.ID.Hex(), // Convert ObjectID to hex string for frontend
+		"fullName":      newUser.FullName,
+		"email":         newUser.Email,
+		"profilePic":    newUser.ProfilePic,
+		"emailVerified": newUser.EmailVerified,
 	})
 }
 
@@ -119,113 +196,274 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid email or password format"})
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Invalid email or password format")
 		return
 	}
+	req.Email = normalizeEmail(req.Email)
 
 	// Find user by email
 	var user models.User
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := utils.RequestContext(c)
 	defer cancel()
 
 	err := db.DB.Collection("users").FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid credentials"})
+			c.Set(AuthFailedKey, true) // Counted by LoginThrottleMiddleware
+			metrics.LoginAttempts.WithLabelValues("failure").Inc()
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidCredentials, "Invalid credentials")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Internal server error finding user: %v", err)})
+		utils.RespondInternalError(c, "Internal server error finding user", err)
 		return
 	}
 
 	// Compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid credentials"})
+		c.Set(AuthFailedKey, true) // Counted by LoginThrottleMiddleware
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidCredentials, "Invalid credentials")
+		return
+	}
+
+	// The password was right, so neither of these counts as a failed login.
+	if user.IsBanned() {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeAccountBanned, "This account has been banned")
+		return
+	}
+	if h.Config.RequireEmailVerification && !user.EmailVerified {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeEmailNotVerified, "Please verify your email address before logging in")
 		return
 	}
 
 	// Generate JWT token and set cookie
-	if err := utils.GenerateToken(user.ID, c, h.Config); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error generating token: %v", err)})
+	if err := utils.GenerateToken(user.ID, user.TokenVersion, c, h.Config); err != nil {
+		utils.RespondInternalError(c, "Error generating token", err)
 		return
 	}
+	if err := h.startSession(c, user.ID); err != nil {
+		utils.RespondInternalError(c, "Error starting session", err)
+		return
+	}
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 
 	// Respond with user data (excluding password)
 	c.JSON(http.StatusOK, gin.H{
-		"_id":        user.ID.Hex(),
-		"fullName":   user.FullName,
-		"email":      user.Email,
-		"profilePic": user.ProfilePic,
+		"_id":           user.ID.Hex(),
+		"fullName":      user.FullName,
+		"bio":           user.Bio,
+		"email":         user.Email,
+		"profilePic":    user.ProfilePic,
+		"thumbnailUrl":  user.ProfilePicThumbnailURL,
+		"emailVerified": user.EmailVerified,
 	})
 }
 
-// Logout handles user logout by clearing the JWT cookie.
+// Logout handles user logout by clearing the JWT cookie and revoking the refresh token.
 // Mirrors backend/src/controllers/auth.controller.js -> logout
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// Clear the "jwt" cookie by setting its maxAge to 0.
-	// CORRECTED: Removed http.SameSiteStrictMode as it's not accepted by this Gin SetCookie signature.
-	c.SetCookie("jwt", "", -1, "/", "", h.Config.NodeEnv == "production", true)
+	// Clear the "jwt" cookie by setting its maxAge to -1, with the same attributes it was set with.
+	utils.SetAuthCookie(c, h.Config, "jwt", "", -1, "/")
+	// Also revoke the refresh token, so the session can't be resumed.
+	if err := h.endSession(c); err != nil {
+		utils.RespondInternalError(c, "Error ending session", err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
+// deleteOldProfilePic removes the user's previous profile picture from
+// Cloudinary once a new one has been saved, so replaced pictures don't pile up.
+// Pictures we didn't upload (external URLs such as the seed avatars) are left
+// alone. Runs in the background: a failed cleanup is only logged.
+func (h *AuthHandler) deleteOldProfilePic(old models.User) {
+	publicID := old.ProfilePicPublicID
+	if publicID == "" {
+		// Uploaded before public IDs were stored: recover it from the URL.
+		publicID = h.CloudinaryService.PublicIDFromURL(old.ProfilePic)
+	}
+	if publicID == "" {
+		return
+	}
+	go func() {
+		if err := h.CloudinaryService.DeleteImage(publicID); err != nil {
+			slog.Error("Error deleting old profile picture", "user_id", old.ID.Hex(), "error", err)
+		}
+	}()
+}
+
 // UpdateProfile handles updating the user's profile picture.
 // Mirrors backend/src/controllers/auth.controller.js -> updateProfile
 func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	// Get the authenticated user from the context (set by AuthMiddleware)
 	userAny, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "User not found in context"})
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
 		return
 	}
 	user := userAny.(models.User) // Type assertion
 
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "Profile pic is required"})
+		utils.RespondBindError(c, err, utils.ErrCodeValidation, "Profile pic is required")
 		return
 	}
 
 	// INTEGRATED CLOUDINARY: Upload the base64 image to Cloudinary
-	uploadResultURL, err := h.CloudinaryService.UploadImage(req.ProfilePic)
+	image, err := h.CloudinaryService.UploadImage(c.Request.Context(), req.ProfilePic, h.CloudinaryService.ProfilePicUpload)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error uploading profile picture: %v", err)})
+		utils.RespondUploadError(c, utils.ImageUploadStatus(err), "Error uploading profile picture", err)
 		return
 	}
 
-	newProfilePicURL := uploadResultURL // Use the secure URL from Cloudinary
+	newProfilePicURL := image.SecureURL // Use the secure URL from Cloudinary
 
 	// Update user in database
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := utils.RequestContext(c)
 	defer cancel()
 
 	// Define the update operation using bson.M for a map-like update document
 	update := bson.M{
 		"$set": bson.M{
-			"profilePic": newProfilePicURL,
-			"updatedAt":  time.Now(), // Manually update updatedAt
+			"profilePic":             newProfilePicURL,
+			"profilePicPublicId":     image.PublicID,
+			"profilePicThumbnailUrl": image.ThumbnailURL, // Empty clears the old picture's
+			"updatedAt":              time.Now(),         // Manually update updatedAt
 		},
 	}
 
 	// Find and update the user by their ID
 	_, err = db.DB.Collection("users").UpdateByID(ctx, user.ID, update)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error updating profile: %v", err)})
+		utils.RespondInternalError(c, "Error updating profile", err)
 		return
 	}
+	h.deleteOldProfilePic(user) // The new picture is saved; the old asset is no longer referenced
 
 	// Fetch the updated user to return the latest data
 	var updatedUser models.User
 	err = db.DB.Collection("users").FindOne(ctx, bson.M{"_id": user.ID}).Decode(&updatedUser)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Error fetching updated user: %v", err)})
+		utils.RespondInternalError(c, "Error fetching updated user", err)
+		return
+	}
+	h.emitUserUpdated(updatedUser)
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":          updatedUser.ID.Hex(),
+		"fullName":     updatedUser.FullName,
+		"bio":          updatedUser.Bio,
+		"email":        updatedUser.Email,
+		"profilePic":   updatedUser.ProfilePic,
+		"thumbnailUrl": updatedUser.ProfilePicThumbnailURL,
+	})
+}
+
+// UpdateAccount partially updates the authenticated user's profile: full name,
+// bio, email and/or profile picture. A new email must not belong to another account.
+func (h *AuthHandler) UpdateAccount(c *gin.Context) {
+	// Get the authenticated user from the context (set by AuthMiddleware)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
+		return
+	}
+	user := userAny.(models.User) // Type assertion
+
+	var req UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	set := bson.M{}
+	if req.FullName != nil {
+		fullName := strings.TrimSpace(*req.FullName)
+		if fullName == "" {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Full name cannot be empty")
+			return
+		}
+		set["fullName"] = fullName
+	}
+	if req.Bio != nil {
+		bio := strings.TrimSpace(*req.Bio)
+		if utf8.RuneCountInString(bio) > models.MaxBioRunes {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("Bio must be at most %d characters", models.MaxBioRunes))
+			return
+		}
+		set["bio"] = bio
+	}
+	if req.Email != nil {
+		email := normalizeEmail(*req.Email)
+		if email != user.Email {
+			// Same uniqueness check as Signup, ignoring the user's own account.
+			var existingUser models.User
+			err := db.DB.Collection("users").FindOne(ctx, bson.M{"email": email, "_id": bson.M{"$ne": user.ID}}).Decode(&existingUser)
+			if err == nil {
+				utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeEmailTaken, "Email already exists")
+				return
+			}
+			if err != mongo.ErrNoDocuments {
+				utils.RespondInternalError(c, "Internal server error checking user", err)
+				return
+			}
+			set["email"] = email
+		}
+	}
+	if req.ProfilePic != nil {
+		// INTEGRATED CLOUDINARY: Upload the base64 image to Cloudinary
+		image, err := h.CloudinaryService.UploadImage(c.Request.Context(), *req.ProfilePic, h.CloudinaryService.ProfilePicUpload)
+		if err != nil {
+			utils.RespondUploadError(c, utils.ImageUploadStatus(err), "Error uploading profile picture", err)
+			return
+		}
+		set["profilePic"] = image.SecureURL
+		set["profilePicPublicId"] = image.PublicID
+		set["profilePicThumbnailUrl"] = image.ThumbnailURL // Empty clears the old picture's
+	}
+
+	if len(set) > 0 {
+		set["updatedAt"] = time.Now()
+		_, err := db.DB.Collection("users").UpdateByID(ctx, user.ID, bson.M{"$set": set})
+		if mongo.IsDuplicateKeyError(err) {
+			// Lost a race with another account taking the same email.
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeEmailTaken, "Email already exists")
+			return
+		}
+		if err != nil {
+			utils.RespondInternalError(c, "Error updating profile", err)
+			return
+		}
+		if _, changed := set["profilePic"]; changed {
+			h.deleteOldProfilePic(user)
+		}
+	}
+
+	// Fetch the updated user to return the latest data
+	var updatedUser models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": user.ID}).Decode(&updatedUser)
+	if err != nil {
+		utils.RespondInternalError(c, "Error fetching updated user", err)
 		return
 	}
+	// Only the public fields are pushed to others; an email change isn't news to them.
+	_, nameChanged := set["fullName"]
+	_, bioChanged := set["bio"]
+	_, picChanged := set["profilePic"]
+	if nameChanged || bioChanged || picChanged {
+		h.emitUserUpdated(updatedUser)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"_id":        updatedUser.ID.Hex(),
-		"fullName":   updatedUser.FullName,
-		"email":      updatedUser.Email,
-		"profilePic": updatedUser.ProfilePic,
+		"_id":          updatedUser.ID.Hex(),
+		"fullName":     updatedUser.FullName,
+		"bio":          updatedUser.Bio,
+		"email":        updatedUser.Email,
+		"profilePic":   updatedUser.ProfilePic,
+		"thumbnailUrl": updatedUser.ProfilePicThumbnailURL,
 	})
 }
 
@@ -237,16 +475,142 @@ func (h *AuthHandler) CheckAuth(c *gin.Context) {
 	if !exists {
 		// This case should ideally not be hit if middleware works correctly,
 		// but it's a good safeguard.
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "User not authenticated"})
+		utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeUnauthorized, "User not authenticated")
 		return
 	}
 	user := userAny.(models.User) // Type assertion
 
+	response := gin.H{
+		"_id":              user.ID.Hex(),
+		"fullName":         user.FullName,
+		"bio":              user.Bio,
+		"email":            user.Email,
+		"profilePic":       user.ProfilePic,
+		"thumbnailUrl":     user.ProfilePicThumbnailURL,
+		"emailVerified":    user.EmailVerified,
+		"sendReadReceipts": user.ReadReceiptsEnabled(),
+		"hideLastSeen":     user.HideLastSeen,
+	}
+
+	// Include the token expiry so the client can schedule a refresh. The cookie is
+	// HttpOnly, so the frontend has no other way to learn when its session ends.
+	if claimsAny, ok := c.Get("claims"); ok {
+		if claims := claimsAny.(*utils.Claims); claims.ExpiresAt != nil {
+			response["tokenExpiresAt"] = claims.ExpiresAt.Time
+		}
+	}
+
 	// Respond with user data (excluding password)
+	c.JSON(http.StatusOK, response)
+}
+
+// EmailAvailable reports whether an email address is still free to sign up with,
+// for instant feedback on the signup form. The route is heavily rate-limited and
+// restricted to the frontend origin to make bulk enumeration impractical.
+func (h *AuthHandler) EmailAvailable(c *gin.Context) {
+	var req EmailAvailableRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A valid email is required")
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	count, err := db.DB.Collection("users").CountDocuments(ctx, bson.M{"email": normalizeEmail(req.Email)}, options.Count().SetLimit(1))
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking email", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"available": count == 0})
+}
+
+// UpdatePreferences updates the authenticated user's privacy/notification settings.
+// Turning sendReadReceipts off stops read receipts in both directions for this
+// user; hideLastSeen hides their last-seen time from everyone else.
+func (h *AuthHandler) UpdatePreferences(c *gin.Context) {
+	// Get the authenticated user from the context (set by AuthMiddleware)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
+		return
+	}
+	user := userAny.(models.User)
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	if req.SendReadReceipts != nil {
+		set["sendReadReceipts"] = *req.SendReadReceipts
+		user.SendReadReceipts = req.SendReadReceipts
+	}
+	if req.HideLastSeen != nil {
+		set["hideLastSeen"] = *req.HideLastSeen
+		user.HideLastSeen = *req.HideLastSeen
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	if _, err := db.DB.Collection("users").UpdateByID(ctx, user.ID, bson.M{"$set": set}); err != nil {
+		utils.RespondInternalError(c, "Error updating preferences", err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"_id":        user.ID.Hex(),
-		"fullName":   user.FullName,
-		"email":      user.Email,
-		"profilePic": user.ProfilePic,
+		"sendReadReceipts": user.ReadReceiptsEnabled(),
+		"hideLastSeen":     user.HideLastSeen,
 	})
 }
+
+// ChangePassword lets a logged-in user set a new password after confirming the
+// current one. Every other session is signed out; the current one stays logged in.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	// Get the authenticated user from the context (set by AuthMiddleware)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
+		return
+	}
+	user := userAny.(models.User)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Current password and a new password of at least 6 characters are required")
+		return
+	}
+
+	// Compare the current password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeIncorrectPassword, "Current password is incorrect")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Error hashing password")
+		return
+	}
+
+	// Not the request's context: once the password has changed, revoking the
+	// sessions must not be cut short by the client disconnecting.
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"password": string(hashedPassword), "updatedAt": time.Now()}}
+	if _, err := db.DB.Collection("users").UpdateByID(ctx, user.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error updating password", err)
+		return
+	}
+
+	if err := h.revokeOtherSessions(ctx, c, user.ID); err != nil {
+		logging.FromContext(c).Error("Error revoking sessions after password change", "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
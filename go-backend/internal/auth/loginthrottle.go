@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"bytes"         // For restoring the request body after peeking at it
+	"encoding/json" // For reading the email from the login body
+	"io"            // For reading the request body
+	"math"          // For the exponential backoff
+	"net/http"      // For HTTP status codes
+	"strconv"       // For the Retry-After header
+	"sync"          // For guarding the records map
+	"time"          // For windows and lockouts
+
+	"go-backend/pkg/utils" // For the standard error response
+
+	"github.com/gin-gonic/gin" // Gin context for handling HTTP requests and responses
+)
+
+// AuthFailedKey is the context key a credential-checking handler sets to true
+// when the credentials were wrong, so LoginThrottleMiddleware can count it.
+// Other 4xx responses (e.g. a malformed body) don't count as failed attempts.
+const AuthFailedKey = "authFailed"
+
+// Lockout timing: the first lockout lasts loginBackoffBase and each further
+// failure while over the threshold doubles it, up to loginBackoffMax.
+const (
+	loginBackoffBase = 30 * time.Second
+	loginBackoffMax  = time.Hour
+)
+
+// maxLoginBodyBytes bounds how much of the body the middleware reads to find the email.
+const maxLoginBodyBytes = 64 << 10
+
+// LoginThrottle counts failed login attempts per client IP and per email. Once
+// a key reaches maxFailures within window, it is locked out with exponential
+// backoff. A successful login clears both keys. In-memory and per-process,
+// like RateLimiter.
+type LoginThrottle struct {
+	mu          sync.Mutex
+	maxFailures int
+	window      time.Duration
+	records     map[string]*loginRecord
+	lastSweep   time.Time
+}
+
+// loginRecord tracks the recent failures of one key.
+type loginRecord struct {
+	failures    int
+	firstFail   time.Time
+	lockedUntil time.Time
+}
+
+// NewLoginThrottle locks a key out after maxFailures failed attempts within window.
+func NewLoginThrottle(maxFailures int, window time.Duration) *LoginThrottle {
+	return &LoginThrottle{
+		maxFailures: maxFailures,
+		window:      window,
+		records:     make(map[string]*loginRecord),
+		lastSweep:   time.Now(),
+	}
+}
+
+// retryAfter returns how long the longest-locked of keys stays locked (0 if none is).
+func (lt *LoginThrottle) retryAfter(keys ...string) time.Duration {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	lt.sweep(now)
+
+	var wait time.Duration
+	for _, key := range keys {
+		if record, ok := lt.records[key]; ok && record.lockedUntil.After(now) {
+			if remaining := record.lockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait
+}
+
+// recordFailure counts a failed attempt for each key, locking out the ones over the threshold.
+func (lt *LoginThrottle) recordFailure(keys ...string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		record, ok := lt.records[key]
+		if !ok || (now.Sub(record.firstFail) >= lt.window && !record.lockedUntil.After(now)) {
+			record = &loginRecord{firstFail: now}
+			lt.records[key] = record
+		}
+		record.failures++
+		if over := record.failures - lt.maxFailures; over >= 0 {
+			backoff := time.Duration(float64(loginBackoffBase) * math.Pow(2, float64(over)))
+			if backoff > loginBackoffMax || backoff <= 0 {
+				backoff = loginBackoffMax
+			}
+			record.lockedUntil = now.Add(backoff)
+		}
+	}
+}
+
+// reset forgets the failures of each key.
+func (lt *LoginThrottle) reset(keys ...string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for _, key := range keys {
+		delete(lt.records, key)
+	}
+}
+
+// sweep drops records that are neither locked nor inside their window, once
+// per window. Must be called with lt.mu held.
+func (lt *LoginThrottle) sweep(now time.Time) {
+	if now.Sub(lt.lastSweep) < lt.window {
+		return
+	}
+	for key, record := range lt.records {
+		if now.Sub(record.firstFail) >= lt.window && !record.lockedUntil.After(now) {
+			delete(lt.records, key)
+		}
+	}
+	lt.lastSweep = now
+}
+
+// LoginThrottleMiddleware guards a credential-checking route (login). It
+// rejects locked-out clients with 429 and a Retry-After header before the
+// handler runs, then counts the attempt: a handler that sets AuthFailedKey
+// records a failure for the client IP and the submitted email, a 200 clears both.
+func LoginThrottleMiddleware(lt *LoginThrottle) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := []string{"ip:" + c.ClientIP()}
+		if email := peekEmail(c); email != "" {
+			keys = append(keys, "email:"+email)
+		}
+
+		if wait := lt.retryAfter(keys...); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.RespondError(c, http.StatusTooManyRequests, utils.ErrCodeRateLimited, "Too many failed login attempts, please try again later")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if c.GetBool(AuthFailedKey) {
+			lt.recordFailure(keys...)
+		} else if c.Writer.Status() == http.StatusOK {
+			lt.reset(keys...)
+		}
+	}
+}
+
+// peekEmail reads the normalized "email" field of a JSON body without
+// consuming it, so the handler can still bind the body. Returns "" if there is none.
+func peekEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoginBodyBytes))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return normalizeEmail(payload.Email)
+}
@@ -1,7 +1,7 @@
 package auth
 
 import (
-	"context"    // For context with MongoDB operations (e.g., timeouts)
+	"context"    // For the request's context passed to the user lookup
 	"fmt"        // For formatted error messages
 	"net/http"   // For HTTP status codes (e.g., 401 Unauthorized, 404 Not Found)
 	"strings"    // For string manipulation (e.g., checking if an error message contains "token is expired")
@@ -10,6 +10,7 @@ import (
 	"go-backend/config" // Import your config package to access JWT_SECRET. IMPORTANT: Replace "go-backend" with your actual Go module name from go.mod
 	"go-backend/internal/models" // Import models to use the User struct for database operations
 	"go-backend/pkg/db" // Import db to access the global MongoDB client (db.DB)
+	"go-backend/pkg/logging" // Per-request logger, tagged with the user ID once authenticated
 	"go-backend/pkg/utils" // Import utils for the JWT Claims struct (defined in jwt.go)
 
 	"github.com/gin-gonic/gin" // Gin context for handling HTTP requests and responses
@@ -21,12 +22,11 @@ import (
 
 // AuthMiddleware creates a Gin middleware to protect routes.
 // It performs the following steps:
-// 1. Retrieves the JWT token from the "jwt" HTTP-only cookie.
-// 2. Parses and validates the token's signature and expiration using the configured JWT secret.
-// 3. Extracts the UserID from the token's claims.
-// 4. Queries the MongoDB database to find the user corresponding to the UserID.
-// 5. If the token is valid and the user is found, it attaches the user object to the Gin context.
-// 6. Calls the next handler in the Gin chain.
+// 1. Retrieves the JWT token from the "jwt" HTTP-only cookie, or from an
+//    `Authorization: Bearer <token>` header for non-browser clients.
+// 2. Validates it and loads the user it belongs to (see authenticateToken).
+// 3. If the token is valid and the user is found, it attaches the user object to the Gin context.
+// 4. Calls the next handler in the Gin chain.
 // If any step fails (e.g., no token, invalid token, user not found), it aborts the request
 // and sends an appropriate JSON error response.
 // This function directly mirrors the functionality of your `protectRoute` middleware in Node.js.
@@ -39,114 +39,240 @@ import (
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	// The returned function is the actual middleware that Gin will execute for protected routes.
 	return func(c *gin.Context) {
-		// 1. Get the JWT token string from the "jwt" cookie.
-		// `c.Cookie("jwt")` attempts to read the cookie by its name.
-		tokenString, err := c.Cookie("jwt")
-		if err != nil {
-			// If the "jwt" cookie is not found (meaning no token was provided),
+		// 1. Get the JWT token string from the "jwt" cookie or, failing that,
+		// the Authorization header (see tokenFromRequest for the lookup order).
+		tokenString, ok := tokenFromRequest(c)
+		if !ok {
+			// If neither the cookie nor the header carries a token,
 			// send a 401 Unauthorized response and abort the request.
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - No Token Provided"})
+			utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeNoToken, "Unauthorized - No Token Provided")
 			c.Abort() // Stop processing this request and don't call subsequent handlers
 			return
 		}
+		authenticateRequest(c, cfg, tokenString)
+	}
+}
 
-		// Initialize a new `utils.Claims` struct. This struct will be populated
-		// with the claims extracted from the JWT after parsing.
-		claims := &utils.Claims{}
-
-		// Parse the token string using `jwt.ParseWithClaims`.
-		// This function performs several critical steps:
-		//   - Decodes the token string.
-		//   - Validates its signature using the provided secret key.
-		//   - Unmarshals the token's payload (claims) into the `claims` struct.
-		// The `func(token *jwt.Token) (interface{}, error)` is a callback function
-		// that provides the secret key used for signature verification.
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// A security check: ensure the signing method used in the token's header
-			// is the expected HMAC SHA256 (`jwt.SigningMethodHS256`).
-			// This prevents attackers from changing the algorithm to a weaker one.
-			// CORRECTED LINE: Directly compare the method with the expected signing method constant.
-			if token.Method != jwt.SigningMethodHS256 {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			// Return the JWT secret key (from your config) as a byte slice for verification.
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		// Handle any errors that occurred during token parsing or validation.
-		if err != nil {
-			// Differentiate between common JWT errors for more specific messages.
-			if err == jwt.ErrSignatureInvalid {
-				// If the token's signature is invalid (e.g., tampered or wrong secret).
-				c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - Invalid Token Signature"})
-			} else if strings.Contains(err.Error(), "token is expired") {
-				// If the token has expired. The `jwt.ParseWithClaims` will automatically check `exp`.
-				c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - Token Expired"})
-			} else {
-				// Catch-all for other parsing/validation errors.
-				c.JSON(http.StatusUnauthorized, gin.H{"message": fmt.Sprintf("Unauthorized - Invalid Token: %v", err)})
-			}
-			c.Abort() // Abort the request
+// WebSocketAuthMiddleware authenticates the WebSocket upgrade request. It
+// accepts everything AuthMiddleware does and, as a last resort, a `?token=`
+// query parameter for clients that can set neither cookies nor headers on the
+// upgrade (some native clients and testing tools). The query parameter is only
+// accepted here: tokens in URLs end up in proxy logs and browser history, so
+// ordinary API routes don't take them.
+// It runs before the upgrade, so a missing or invalid token is a plain 401.
+func WebSocketAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := tokenFromRequest(c)
+		if !ok {
+			tokenString = c.Query("token")
+		}
+		if tokenString == "" {
+			utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeNoToken, "Unauthorized - No Token Provided")
+			c.Abort()
 			return
 		}
+		authenticateRequest(c, cfg, tokenString)
+	}
+}
+
+// authenticateRequest validates tokenString and, if it is a valid access
+// token of an existing user, attaches the user to the context and calls the
+// next handler. Otherwise it aborts with the matching error response.
+func authenticateRequest(c *gin.Context, cfg *config.Config, tokenString string) {
+	user, claims, authErr := authenticateToken(c.Request.Context(), cfg, tokenString)
+	if authErr != nil {
+		if authErr.status == http.StatusInternalServerError {
+			utils.RespondInternalError(c, authErr.message, authErr.err)
+		} else {
+			utils.RespondError(c, authErr.status, authErr.code, authErr.message)
+		}
+		c.Abort() // Abort the request if the token is invalid or the user cannot be loaded.
+		return
+	}
+
+	// If everything is successful (token valid, user found), attach the `user` object
+	// to the Gin context. This makes the authenticated user's information easily
+	// accessible to subsequent handlers in the request chain (e.g., controllers).
+	// The key "user" is used to retrieve it later: `c.Get("user")`.
+	c.Set("user", user)
+	logging.With(c, "user_id", user.ID.Hex()) // Every later log line of this request names the user
+
+	// Also expose the validated claims, so handlers can read token metadata
+	// such as the expiry (`exp`) without re-parsing the cookie.
+	c.Set("claims", claims)
+
+	// Call the next handler in the Gin chain. If there are other middlewares, they run next.
+	// If not, the final route handler will be executed.
+	c.Next()
+}
+
+// authError is why a token was refused: the response to send and, for
+// internal failures, the underlying error to log.
+type authError struct {
+	status  int
+	code    string
+	message string
+	err     error
+}
+
+// authenticateToken validates an access token and loads the user it was issued to.
+// It is the token check shared by AuthMiddleware and WebSocketAuthMiddleware.
+// The user lookup ends with ctx (the request's context), within DB_TIMEOUT.
+func authenticateToken(ctx context.Context, cfg *config.Config, tokenString string) (models.User, *utils.Claims, *authError) {
+	// Initialize a new `utils.Claims` struct. This struct will be populated
+	// with the claims extracted from the JWT after parsing.
+	claims := &utils.Claims{}
+
+	// Parse the token string using `jwt.ParseWithClaims`.
+	// This function performs several critical steps:
+	//   - Decodes the token string.
+	//   - Validates its signature using the provided secret key.
+	//   - Unmarshals the token's payload (claims) into the `claims` struct.
+	// The `func(token *jwt.Token) (interface{}, error)` is a callback function
+	// that provides the secret key used for signature verification.
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// A security check: ensure the signing method used in the token's header
+		// is the expected HMAC SHA256 (`jwt.SigningMethodHS256`).
+		// This prevents attackers from changing the algorithm to a weaker one.
+		// CORRECTED LINE: Directly compare the method with the expected signing method constant.
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		// Return the JWT secret key (from your config) as a byte slice for verification.
+		return []byte(cfg.JWTSecret), nil
+	})
+
+	// Handle any errors that occurred during token parsing or validation.
+	if err != nil {
+		// Differentiate between common JWT errors for more specific messages.
+		if err == jwt.ErrSignatureInvalid {
+			// If the token's signature is invalid (e.g., tampered or wrong secret).
+			return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeInvalidToken, message: "Unauthorized - Invalid Token Signature"}
+		} else if strings.Contains(err.Error(), "token is expired") {
+			// If the token has expired. The `jwt.ParseWithClaims` will automatically check `exp`.
+			return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeTokenExpired, message: "Unauthorized - Token Expired"}
+		}
+		// Catch-all for other parsing/validation errors.
+		return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeInvalidToken, message: fmt.Sprintf("Unauthorized - Invalid Token: %v", err)}
+	}
+
+	// After parsing, explicitly check if the token is considered valid by the JWT library.
+	// This checks overall validity including expiration (if not caught by string check above)
+	// and other registered claims.
+	if !token.Valid {
+		return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeInvalidToken, message: "Unauthorized - Invalid Token"}
+	}
+
+	// Refresh tokens are signed with the same secret; they may only be used
+	// with POST /api/auth/refresh, never as an access token.
+	for _, audience := range claims.Audience {
+		if audience == utils.RefreshTokenAudience {
+			return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeInvalidToken, message: "Unauthorized - Invalid Token"}
+		}
+	}
+
+	// Although `jwt.ParseWithClaims` often handles expiration, an explicit check
+	// provides clarity and can be useful for debugging or specific logic.
+	if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
+		return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeTokenExpired, message: "Unauthorized - Token Expired"}
+	}
+
+	// Find the user in the database using the UserID extracted from the claims.
+	// The UserID from claims is already a `primitive.ObjectID`.
+	userID := claims.UserID
+
+	// Get a reference to the "users" collection in your MongoDB database.
+	usersCollection := db.DB.Collection("users")
+
+	var user models.User // Declare a variable of type `models.User` to hold the retrieved user data.
+
+	// Create a context with a timeout for the database query.
+	// This prevents the application from hanging indefinitely if the database is slow.
+	ctx, cancel := db.ContextFrom(ctx)
+	defer cancel() // Ensure the context resources are released when the function exits.
+
+	// Execute the MongoDB query: Find one document in the "users" collection
+	// where the "_id" field matches the `userID` from the token claims.
+	// `bson.M` is a convenient type for creating BSON documents (maps) for queries.
+	// `.Decode(&user)` attempts to unmarshal the found MongoDB document into our `user` struct.
+	err = usersCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		// Handle specific MongoDB errors.
+		if err == mongo.ErrNoDocuments {
+			// If no document was found for the given ID, even if the token was valid.
+			return models.User{}, nil, &authError{status: http.StatusNotFound, code: utils.ErrCodeUserNotFound, message: "User not found"}
+		}
+		// Catch-all for other database errors (e.g., connection issues).
+		return models.User{}, nil, &authError{status: http.StatusInternalServerError, code: utils.ErrCodeInternal, message: "Internal server error fetching user", err: err}
+	}
 
-		// After parsing, explicitly check if the token is considered valid by the JWT library.
-		// This checks overall validity including expiration (if not caught by string check above)
-		// and other registered claims.
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - Invalid Token"})
+	// The user logged out everywhere after this token was issued.
+	if claims.TokenVersion != user.TokenVersion {
+		return models.User{}, nil, &authError{status: http.StatusUnauthorized, code: utils.ErrCodeInvalidToken, message: "Unauthorized - Token Revoked"}
+	}
+	// Banning also bumps the token version, so this only catches a ban that
+	// raced with the token's issue.
+	if user.IsBanned() {
+		return models.User{}, nil, &authError{status: http.StatusForbidden, code: utils.ErrCodeAccountBanned, message: "This account has been banned"}
+	}
+	return user, claims, nil
+}
+
+// tokenFromRequest returns the JWT sent with the request. The lookup order is:
+//  1. the "jwt" cookie set by login/signup (browsers), which wins if both are present;
+//  2. an `Authorization: Bearer <token>` header (mobile and CLI clients).
+// Returns false if neither carries a token.
+func tokenFromRequest(c *gin.Context) (string, bool) {
+	if tokenString, err := c.Cookie("jwt"); err == nil && tokenString != "" {
+		return tokenString, true
+	}
+
+	// The scheme name is case-insensitive per RFC 7235.
+	header := c.GetHeader("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		if tokenString := strings.TrimSpace(header[len("Bearer "):]); tokenString != "" {
+			return tokenString, true
+		}
+	}
+	return "", false
+}
+
+// AdminMiddleware restricts a route to admin users.
+// It must run after AuthMiddleware, which puts the authenticated user in the context.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userAny, exists := c.Get("user")
+		if !exists {
+			utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeNoToken, "Unauthorized - No Token Provided")
 			c.Abort()
 			return
 		}
-
-		// Although `jwt.ParseWithClaims` often handles expiration, an explicit check
-		// provides clarity and can be useful for debugging or specific logic.
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Before(time.Now()) {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - Token Expired"})
+		if !userAny.(models.User).IsAdmin {
+			utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Forbidden - Admin access required")
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		// 2. Find the user in the database using the UserID extracted from the claims.
-		// The UserID from claims is already a `primitive.ObjectID`.
-		userID := claims.UserID
-
-		// Get a reference to the "users" collection in your MongoDB database.
-		usersCollection := db.DB.Collection("users")
-
-		var user models.User // Declare a variable of type `models.User` to hold the retrieved user data.
-
-		// Create a context with a timeout for the database query.
-		// This prevents the application from hanging indefinitely if the database is slow.
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel() // Ensure the context resources are released when the function exits.
-
-		// Execute the MongoDB query: Find one document in the "users" collection
-		// where the "_id" field matches the `userID` from the token claims.
-		// `bson.M` is a convenient type for creating BSON documents (maps) for queries.
-		// `.Decode(&user)` attempts to unmarshal the found MongoDB document into our `user` struct.
-		err = usersCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
-		if err != nil {
-			// Handle specific MongoDB errors.
-			if err == mongo.ErrNoDocuments {
-				// If no document was found for the given ID, even if the token was valid.
-				c.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
-			} else {
-				// Catch-all for other database errors (e.g., connection issues).
-				c.JSON(http.StatusInternalServerError, gin.H{"message": fmt.Sprintf("Internal server error fetching user: %v", err)})
-			}
-			c.Abort() // Abort the request if the user cannot be found or there's a DB error.
+// RequireOrigin only lets through requests made by the frontend: either the
+// Origin header is one of the allowed origins, or (for same-origin requests,
+// where browsers may omit Origin) the browser marked it Sec-Fetch-Site: same-origin.
+// It is a cheap deterrent against scripted use of browser-only endpoints, not
+// authentication: non-browser clients can forge both headers.
+func RequireOrigin(allowedOrigins ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if utils.OriginAllowed(allowedOrigins, origin) {
+			c.Next()
 			return
 		}
-
-		// 3. If everything is successful (token valid, user found), attach the `user` object
-		// to the Gin context. This makes the authenticated user's information easily
-		// accessible to subsequent handlers in the request chain (e.g., controllers).
-		// The key "user" is used to retrieve it later: `c.Get("user")`.
-		c.Set("user", user)
-
-		// Call the next handler in the Gin chain. If there are other middlewares, they run next.
-		// If not, the final route handler will be executed.
-		c.Next()
+		if origin == "" && c.GetHeader("Sec-Fetch-Site") == "same-origin" {
+			c.Next()
+			return
+		}
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeOriginNotAllowed, "Forbidden - Origin not allowed")
+		c.Abort()
 	}
-}
\ No newline at end of file
+}
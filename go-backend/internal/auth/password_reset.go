@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"      // For context with MongoDB operations
+	"crypto/rand"  // For generating unguessable reset tokens
+	"encoding/hex" // For encoding the token as a string
+	"fmt"          // For formatted error messages
+	"net/http"     // For HTTP status codes
+	"net/url"      // For escaping the token in the reset link
+	"time"         // For handling timestamps
+
+	"go-backend/internal/models" // Import models for User and PasswordReset structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // For logging failures we hide from the client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For error checking
+	"golang.org/x/crypto/bcrypt"                 // For password hashing
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=6"` // Same rule as SignupRequest
+}
+
+// forgotPasswordResponse is returned whether or not the email belongs to an
+// account, so the endpoint can't be used to discover registered emails.
+const forgotPasswordResponse = "If an account exists for that email, a password reset link has been sent"
+
+// ForgotPassword emails a single-use password reset link to the account with
+// the given email, replacing any earlier unused link.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A valid email is required")
+		return
+	}
+	req.Email = normalizeEmail(req.Email)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.FromContext(c).Error("Error looking up user for password reset", "error", err)
+		}
+		// Same answer as the success path: don't reveal whether the email exists.
+		c.JSON(http.StatusOK, gin.H{"message": forgotPasswordResponse})
+		return
+	}
+
+	if err := h.sendPasswordReset(ctx, user); err != nil {
+		logging.FromContext(c).Error("Error issuing password reset", "user_id", user.ID.Hex(), "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": forgotPasswordResponse})
+}
+
+// sendPasswordReset stores a fresh reset token for the user (dropping older
+// unused ones, so only the latest link works) and emails the link.
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, user models.User) error {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	resets := db.DB.Collection("password_resets")
+	if _, err := resets.DeleteMany(ctx, bson.M{"userId": user.ID, "usedAt": bson.M{"$exists": false}}); err != nil {
+		return fmt.Errorf("failed to clear old reset tokens: %w", err)
+	}
+
+	now := time.Now()
+	reset := models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.Config.PasswordResetTTL),
+	}
+	if _, err := resets.InsertOne(ctx, reset); err != nil {
+		return fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", h.Config.AppBaseURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Hi %s,\n\nUse this link to choose a new password. It expires in %s and can only be used once:\n%s\n\nIf you didn't ask for this, you can ignore this email.",
+		user.FullName, h.Config.PasswordResetTTL, link)
+	return h.Mailer.Send(user.Email, "Reset your password", body)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword.
+// The token is consumed even if the password update then fails, and all of the
+// user's sessions are revoked so a stolen refresh token stops working.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Token and a new password of at least 6 characters are required")
+		return
+	}
+
+	// Not the request's context: once the password has changed, revoking the
+	// sessions must not be cut short by the client disconnecting.
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	// Atomically claim the token, so two concurrent requests can't both use it.
+	now := time.Now()
+	var reset models.PasswordReset
+	err := db.DB.Collection("password_resets").FindOneAndUpdate(ctx,
+		bson.M{
+			"tokenHash": hashToken(req.Token),
+			"usedAt":    bson.M{"$exists": false},
+			"expiresAt": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"usedAt": now}},
+	).Decode(&reset)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired reset token")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error checking reset token", err)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Error hashing password")
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"password": string(hashedPassword), "updatedAt": now}}
+	result, err := db.DB.Collection("users").UpdateByID(ctx, reset.UserID, update)
+	if err != nil {
+		utils.RespondInternalError(c, "Error updating password", err)
+		return
+	}
+	if result.MatchedCount == 0 {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired reset token")
+		return
+	}
+
+	if _, err := db.DB.Collection("sessions").DeleteMany(ctx, bson.M{"userId": reset.UserID}); err != nil {
+		logging.FromContext(c).Error("Error revoking sessions after password reset", "user_id", reset.UserID.Hex(), "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"log/slog" // Structured logging
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projections
+)
+
+// userUpdatedEvent is the payload of the "userUpdated" WebSocket event: the
+// public part of a profile, as shown in sidebars and chat headers.
+type userUpdatedEvent struct {
+	ID           string `json:"_id"`
+	FullName     string `json:"fullName"`
+	ProfilePic   string `json:"profilePic"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	Bio          string `json:"bio"`
+}
+
+// emitUserUpdated tells every online user (including the user's own other
+// devices) about a changed profile, so avatars and names update without a
+// reload. Users on either side of a block are left out, as they can't see the
+// profile. Runs in the background: a failed lookup is only logged.
+func (h *AuthHandler) emitUserUpdated(user models.User) {
+	online := h.Hub.OnlineUserIDs()
+	if len(online) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := db.Context()
+		defer cancel()
+
+		excluded := make(map[primitive.ObjectID]bool, len(user.BlockedUsers))
+		for _, blockedID := range user.BlockedUsers {
+			excluded[blockedID] = true
+		}
+		// Online users who blocked this user.
+		cursor, err := db.DB.Collection("users").Find(ctx,
+			bson.M{"_id": bson.M{"$in": online}, "blockedUsers": user.ID},
+			options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			slog.Error("Error finding blockers before sending profile update", "user_id", user.ID.Hex(), "error", err)
+			return
+		}
+		var blockers []models.User
+		if err := cursor.All(ctx, &blockers); err != nil {
+			slog.Error("Error decoding blockers before sending profile update", "user_id", user.ID.Hex(), "error", err)
+			return
+		}
+		for _, blocker := range blockers {
+			excluded[blocker.ID] = true
+		}
+
+		recipients := make([]primitive.ObjectID, 0, len(online))
+		for _, userID := range online {
+			if !excluded[userID] {
+				recipients = append(recipients, userID)
+			}
+		}
+		h.Hub.SendToUsers(recipients, "userUpdated", userUpdatedEvent{
+			ID:           user.ID.Hex(),
+			FullName:     user.FullName,
+			ProfilePic:   user.ProfilePic,
+			ThumbnailURL: user.ProfilePicThumbnailURL,
+			Bio:          user.Bio,
+		})
+	}()
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"net/http" // For HTTP status codes
+	"strconv"  // For the Retry-After header
+	"sync"     // For guarding the counters map
+	"time"     // For window handling
+
+	"go-backend/pkg/utils" // For the standard error response
+
+	"github.com/gin-gonic/gin" // Gin context for handling HTTP requests and responses
+)
+
+// RateLimiter is a simple in-memory fixed-window limiter keyed by an arbitrary
+// string (usually the client IP). Counters live in this process only, so each
+// backend instance enforces its own limits.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	counters  map[string]*windowCounter
+	lastSweep time.Time
+}
+
+// windowCounter counts hits for one key in the current window.
+type windowCounter struct {
+	count int
+	start time.Time
+}
+
+// NewRateLimiter allows up to `limit` hits per key in each `window`.
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:     limit,
+		window:    window,
+		counters:  make(map[string]*windowCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records a hit for key and reports whether it is within the limit.
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.sweep(now)
+
+	counter, ok := rl.counters[key]
+	if !ok || now.Sub(counter.start) >= rl.window {
+		counter = &windowCounter{start: now}
+		rl.counters[key] = counter
+	}
+	counter.count++
+	return counter.count <= rl.limit
+}
+
+// sweep drops expired counters once per window so the map doesn't grow forever.
+// Must be called with rl.mu held.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+	for key, counter := range rl.counters {
+		if now.Sub(counter.start) >= rl.window {
+			delete(rl.counters, key)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// RateLimitMiddleware rejects requests from a client IP that exceeds the limiter's
+// budget with 429 Too Many Requests.
+func RateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.Allow(c.ClientIP()) {
+			c.Header("Retry-After", strconv.Itoa(int(rl.window.Seconds())))
+			utils.RespondError(c, http.StatusTooManyRequests, utils.ErrCodeRateLimited, "Too many requests, please try again later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
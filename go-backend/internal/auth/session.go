@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"       // For context with MongoDB operations
+	"crypto/sha256" // For hashing tokens before storing them
+	"encoding/hex"  // For encoding the hash as a string
+	"fmt"           // For formatted error messages
+	"net/http"      // For HTTP status codes
+	"time"          // For handling timestamps
+
+	"go-backend/internal/models" // Import models for the Session struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Request-scoped logger
+	"go-backend/pkg/utils"       // Import utils for token generation
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"github.com/gorilla/websocket"               // For the close code sent to open connections
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For error checking
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projecting the token version
+)
+
+// The refresh token cookie is scoped to the auth routes, so it isn't sent
+// along with every API request the way the access token cookie is.
+const (
+	refreshCookieName = "refresh_token"
+	refreshCookiePath = "/api/auth"
+)
+
+// hashToken returns the hex SHA-256 of a secret token (refresh or password
+// reset), the form stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession records a new session for the user and sets the refresh token cookie.
+// Called by Signup and Login alongside utils.GenerateToken.
+func (h *AuthHandler) startSession(c *gin.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	session := models.Session{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.Config.RefreshTokenExpiry),
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken(userID, session.ID, session.ExpiresAt, h.Config)
+	if err != nil {
+		return err
+	}
+	session.TokenHash = hashToken(refreshToken)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+	if _, err := db.DB.Collection("sessions").InsertOne(ctx, session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	utils.SetAuthCookie(c, h.Config, refreshCookieName, refreshToken, int(h.Config.RefreshTokenExpiry/time.Second), refreshCookiePath)
+	return nil
+}
+
+// endSession revokes the session behind the request's refresh token cookie, if
+// any, and clears the cookie. Invalid or unknown tokens are simply ignored.
+func (h *AuthHandler) endSession(c *gin.Context) error {
+	utils.SetAuthCookie(c, h.Config, refreshCookieName, "", -1, refreshCookiePath)
+
+	refreshToken, err := c.Cookie(refreshCookieName)
+	if err != nil || refreshToken == "" {
+		return nil
+	}
+	claims, err := utils.ParseRefreshToken(refreshToken, h.Config)
+	if err != nil {
+		return nil
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+	_, err = db.DB.Collection("sessions").DeleteOne(ctx, bson.M{"_id": claims.SessionID, "tokenHash": hashToken(refreshToken)})
+	return err
+}
+
+// revokeOtherSessions deletes all of the user's sessions except the one behind
+// the request's refresh token cookie (if it has a valid one).
+func (h *AuthHandler) revokeOtherSessions(ctx context.Context, c *gin.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"userId": userID}
+	if refreshToken, err := c.Cookie(refreshCookieName); err == nil && refreshToken != "" {
+		if claims, err := utils.ParseRefreshToken(refreshToken, h.Config); err == nil {
+			filter["_id"] = bson.M{"$ne": claims.SessionID}
+		}
+	}
+	_, err := db.DB.Collection("sessions").DeleteMany(ctx, filter)
+	return err
+}
+
+// Refresh issues a new access token cookie in exchange for a valid refresh token.
+// The refresh token itself is left unchanged and keeps its original expiry.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	refreshToken, err := c.Cookie(refreshCookieName)
+	if err != nil || refreshToken == "" {
+		utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeNoToken, "Unauthorized - No Refresh Token Provided")
+		return
+	}
+
+	claims, err := utils.ParseRefreshToken(refreshToken, h.Config)
+	if err != nil {
+		utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeInvalidToken, "Unauthorized - Invalid Refresh Token")
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// The session must still exist (not revoked by logout) and match this exact token.
+	var session models.Session
+	err = db.DB.Collection("sessions").FindOne(ctx, bson.M{
+		"_id":       claims.SessionID,
+		"tokenHash": hashToken(refreshToken),
+	}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeInvalidToken, "Unauthorized - Session Revoked")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching session", err)
+		return
+	}
+	if time.Now().After(session.ExpiresAt) {
+		utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeTokenExpired, "Unauthorized - Session Expired")
+		return
+	}
+
+	// The new access token carries the user's current token version. A
+	// missing user means the account was deleted after the session started.
+	var user models.User
+	err = db.DB.Collection("users").FindOne(ctx, bson.M{"_id": session.UserID},
+		options.FindOne().SetProjection(bson.M{"tokenVersion": 1})).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusUnauthorized, utils.ErrCodeInvalidToken, "Unauthorized - Session Revoked")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching user", err)
+		return
+	}
+
+	if err := utils.GenerateToken(session.UserID, user.TokenVersion, c, h.Config); err != nil {
+		utils.RespondInternalError(c, "Error generating token", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Token refreshed",
+		"tokenExpiresAt": time.Now().Add(h.Config.JWTExpiry),
+	})
+}
+
+// LogoutAll signs the user out on every device, this one included. It bumps
+// the user's token version, so every access token issued so far stops being
+// accepted, revokes all of their sessions (refresh tokens), closes their
+// WebSocket connections and clears the auth cookies.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "User not found in context")
+		return
+	}
+	user := userAny.(models.User)
+
+	// Not the request's context: revoking must not stop halfway because the
+	// client disconnected.
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	// Bump the version first: once it has changed, no existing access token
+	// works, even if revoking the sessions below fails.
+	update := bson.M{"$inc": bson.M{"tokenVersion": 1}, "$set": bson.M{"updatedAt": time.Now()}}
+	if _, err := db.DB.Collection("users").UpdateByID(ctx, user.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error revoking tokens", err)
+		return
+	}
+	if _, err := db.DB.Collection("sessions").DeleteMany(ctx, bson.M{"userId": user.ID}); err != nil {
+		utils.RespondInternalError(c, "Error revoking sessions", err)
+		return
+	}
+	h.Hub.DisconnectUser(user.ID, websocket.ClosePolicyViolation, "Logged out everywhere")
+
+	utils.SetAuthCookie(c, h.Config, "jwt", "", -1, "/")
+	utils.SetAuthCookie(c, h.Config, refreshCookieName, "", -1, refreshCookiePath)
+
+	logging.FromContext(c).Info("Logged out of all sessions")
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
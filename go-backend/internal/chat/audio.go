@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+
+	"go-backend/pkg/utils" // For CloudinaryService and the audio upload errors
+
+	"github.com/gin-gonic/gin" // Gin context for handling requests
+)
+
+// uploadVoiceNote validates the voice note of a message being sent and
+// uploads it, returning a zero UploadedAudio when the message has none.
+// The client-reported duration is checked first so an obviously too long
+// recording isn't uploaded at all; the stored duration is Cloudinary's.
+// Returns false if a response has already been written.
+func (h *ChatHandler) uploadVoiceNote(c *gin.Context, req SendMessageRequest) (utils.UploadedAudio, bool) {
+	if req.Audio == "" {
+		return utils.UploadedAudio{}, true
+	}
+	if req.Image != "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A message can carry an image or a voice note, not both")
+		return utils.UploadedAudio{}, false
+	}
+	maxDuration := h.CloudinaryService.MaxAudioDuration
+	if req.AudioDuration < 0 || maxDuration > 0 && req.AudioDuration > maxDuration.Seconds() {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("Voice notes can be at most %s long", maxDuration))
+		return utils.UploadedAudio{}, false
+	}
+
+	audio, err := h.CloudinaryService.UploadAudio(c.Request.Context(), req.Audio)
+	if err != nil {
+		utils.RespondUploadError(c, utils.AudioUploadStatus(err), "Error uploading audio", err)
+		return utils.UploadedAudio{}, false
+	}
+	if audio.Duration == 0 {
+		audio.Duration = req.AudioDuration // Cloudinary couldn't measure it
+	}
+	return audio, true
+}
@@ -0,0 +1,448 @@
+package chat
+
+import (
+	"context"  // For context with MongoDB operations
+	"fmt"      // For formatted error messages
+	"log/slog" // For logging errors outside a request
+	"net/http" // For HTTP status codes
+	"strings"  // For trimming group names
+	"time"     // For handling timestamps
+
+	"go-backend/internal/models" // Import models for Conversation and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Per-request structured logger
+	"go-backend/pkg/metrics"     // Messages sent counter
+	"go-backend/pkg/utils"       // Import utils for the content filter constants
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For error checking
+	"go.mongodb.org/mongo-driver/mongo/options"  // For MongoDB find options (e.g., sort)
+)
+
+// Struct for CreateGroup request body
+type CreateGroupRequest struct {
+	Name           string   `json:"name" binding:"required"`
+	ParticipantIDs []string `json:"participantIds" binding:"required"` // The other members; the creator is added automatically
+}
+
+// maxGroupParticipants caps group size (creator included), since every group
+// message fans out to every online member.
+const maxGroupParticipants = 100
+
+// conversationResponse converts a stored conversation into the JSON shape the frontend expects.
+func conversationResponse(conv models.Conversation) gin.H {
+	return gin.H{
+		"_id":          conv.ID.Hex(),
+		"isGroup":      conv.IsGroup,
+		"name":         conv.Name,
+		"participants": hexIDs(conv.Participants),
+		"adminIds":     hexIDs(conv.AdminIDs),
+		"createdAt":    conv.CreatedAt,
+		"updatedAt":    conv.UpdatedAt,
+	}
+}
+
+// hexIDs converts ObjectIDs to hex strings, preserving order.
+func hexIDs(ids []primitive.ObjectID) []string {
+	hexes := make([]string, len(ids))
+	for i, id := range ids {
+		hexes[i] = id.Hex()
+	}
+	return hexes
+}
+
+// findConversation loads a conversation and checks that userID is a participant.
+// Returns false if an error response has already been written.
+func findConversation(ctx context.Context, c *gin.Context, userID primitive.ObjectID) (models.Conversation, bool) {
+	var conv models.Conversation
+	conversationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid conversation ID format")
+		return conv, false
+	}
+
+	err = db.DB.Collection("conversations").FindOne(ctx, bson.M{"_id": conversationID}).Decode(&conv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeConversationNotFound, "Conversation not found")
+			return conv, false
+		}
+		utils.RespondInternalError(c, "Internal server error fetching conversation", err)
+		return conv, false
+	}
+
+	if !conv.HasParticipant(userID) {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "You are not a participant in this conversation")
+		return conv, false
+	}
+	return conv, true
+}
+
+// isParticipant reports whether userID may see the message: the sender or
+// receiver of a 1-to-1 message, or any member of a group message's conversation.
+func isParticipant(ctx context.Context, message models.Message, userID primitive.ObjectID) (bool, error) {
+	if message.SenderID == userID {
+		return true, nil
+	}
+	if !message.IsGroupMessage() {
+		return message.ReceiverID == userID, nil
+	}
+	count, err := db.DB.Collection("conversations").CountDocuments(ctx, bson.M{
+		"_id":          message.ConversationID,
+		"participants": userID,
+	})
+	return count > 0, err
+}
+
+// notifyOtherParticipants sends an event about an existing message (edit,
+// delete, ...) to everyone in its conversation except the sender.
+func (h *ChatHandler) notifyOtherParticipants(ctx context.Context, message models.Message, event string, payload interface{}) {
+	h.notifyParticipantsExcept(ctx, message, message.SenderID, event, payload)
+}
+
+// notifyParticipantsExcept sends an event about an existing message to
+// everyone in its conversation except exceptID (the user who caused it).
+func (h *ChatHandler) notifyParticipantsExcept(ctx context.Context, message models.Message, exceptID primitive.ObjectID, event string, payload interface{}) {
+	if !message.IsGroupMessage() {
+		for _, participantID := range []primitive.ObjectID{message.SenderID, message.ReceiverID} {
+			if participantID != exceptID && !(message.Silenced && participantID == message.ReceiverID) {
+				h.Emitter.SendToUser(participantID, event, payload)
+			}
+		}
+		return
+	}
+
+	var conv models.Conversation
+	if err := db.DB.Collection("conversations").FindOne(ctx, bson.M{"_id": message.ConversationID}).Decode(&conv); err != nil {
+		slog.Error("Error loading conversation to notify participants", "conversation_id", message.ConversationID.Hex(), "event", event, "error", err)
+		return
+	}
+	for _, participantID := range conv.Participants {
+		if participantID != exceptID {
+			h.Emitter.SendToUser(participantID, event, payload)
+		}
+	}
+}
+
+// CreateGroup creates a group conversation with the logged-in user as its
+// first admin, and tells the other members with a "conversationCreated" event.
+func (h *ChatHandler) CreateGroup(c *gin.Context) {
+	// Get the authenticated user from the context (the creator)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Group name and participants are required")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Group name cannot be empty")
+		return
+	}
+
+	// The creator always comes first; duplicates are dropped.
+	participants := []primitive.ObjectID{loggedInUser.ID}
+	seen := map[primitive.ObjectID]bool{loggedInUser.ID: true}
+	for _, rawID := range req.ParticipantIDs {
+		participantID, err := primitive.ObjectIDFromHex(rawID)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, fmt.Sprintf("Invalid participant ID format: %q", rawID))
+			return
+		}
+		if !seen[participantID] {
+			seen[participantID] = true
+			participants = append(participants, participantID)
+		}
+	}
+	if len(participants) < 2 {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A group needs at least one other participant")
+		return
+	}
+	if len(participants) > maxGroupParticipants {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("A group can have at most %d participants", maxGroupParticipants))
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Every participant must be an existing user. Their names go into the
+	// "member joined" notice.
+	var users []models.User
+	cursor, err := db.DB.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": participants}},
+		options.Find().SetProjection(bson.M{"fullName": 1}))
+	if err == nil {
+		err = cursor.All(ctx, &users)
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching users", err)
+		return
+	}
+	if len(users) != len(participants) {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "One or more participants do not exist")
+		return
+	}
+	names := make(map[primitive.ObjectID]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.FullName
+	}
+
+	now := time.Now()
+	conv := models.Conversation{
+		ID:           primitive.NewObjectID(),
+		Participants: participants,
+		IsGroup:      true,
+		Name:         name,
+		AdminIDs:     []primitive.ObjectID{loggedInUser.ID},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := db.DB.Collection("conversations").InsertOne(ctx, conv); err != nil {
+		utils.RespondInternalError(c, "Error saving conversation", err)
+		return
+	}
+
+	response := conversationResponse(conv)
+	for _, participantID := range participants[1:] {
+		h.Emitter.SendToUser(participantID, "conversationCreated", response)
+	}
+
+	// Open the group with a notice of who was added. The group exists even if
+	// this fails, so it is only logged.
+	added := make([]string, 0, len(participants)-1)
+	for _, participantID := range participants[1:] {
+		added = append(added, names[participantID])
+	}
+	notice := models.NewSystemMessage(conv.ID, loggedInUser.ID, models.SystemTypeMemberJoined,
+		fmt.Sprintf("%s added %s", loggedInUser.FullName, strings.Join(added, ", ")))
+	if _, err := db.DB.Collection("messages").InsertOne(ctx, notice); err != nil {
+		logging.FromContext(c).Error("Error saving group notice", "conversation_id", conv.ID.Hex(), "error", err)
+	} else {
+		h.Emitter.EmitConversationMessage(notice, participants)
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetConversations lists the group conversations the logged-in user belongs
+// to, most recently active first. 1-to-1 chats are listed by GetUsersForSidebar.
+func (h *ChatHandler) GetConversations(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}})
+	cursor, err := db.DB.Collection("conversations").Find(ctx, bson.M{"participants": loggedInUser.ID}, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching conversations", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var conversations []models.Conversation
+	if err = cursor.All(ctx, &conversations); err != nil {
+		utils.RespondInternalError(c, "Error decoding conversations", err)
+		return
+	}
+
+	response := make([]gin.H, len(conversations))
+	for i, conv := range conversations {
+		response[i] = conversationResponse(conv)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetConversationMessages returns one page of a group conversation's messages,
+// with the same ?limit and ?before cursor as GetMessages.
+func (h *ChatHandler) GetConversationMessages(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	limit, ok := utils.ParseLimit(c, defaultMessagesPerPage, h.Config.MessageMaxLimit)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	conv, ok := findConversation(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+
+	messages, hasMore, nextCursor, ok := findMessagePage(ctx, c, bson.M{"conversationId": conv.ID}, limit)
+	if !ok {
+		return
+	}
+
+	response := messageResponses(messages)
+	if h.Signer != nil {
+		// Flag any message whose stored content no longer matches its signature.
+		for i, msg := range messages {
+			response[i].Integrity = h.Signer.Status(msg)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   response,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
+}
+
+// SendConversationMessage sends a message to a group conversation and
+// delivers it to every online participant. Takes the same body as SendMessage.
+func (h *ChatHandler) SendConversationMessage(c *gin.Context) {
+	// Get the authenticated user from the context (sender)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+
+	// Ensure at least text, an image or a voice note is provided
+	if req.Text == "" && req.Image == "" && req.Audio == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Message text, image or audio is required")
+		return
+	}
+
+	// Validate the priority against the small set we support.
+	switch req.Priority {
+	case "":
+		req.Priority = models.PriorityNormal
+	case models.PriorityNormal, models.PriorityUrgent:
+	default:
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Priority must be \"normal\" or \"urgent\"")
+		return
+	}
+
+	now := time.Now()
+	expiresAt, ok := messageExpiry(c, req, now)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	conv, ok := findConversation(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+
+	// Run the moderation blocklist before doing any work (like uploading the image).
+	flagged := false
+	if h.ContentFilter.Matches(req.Text) {
+		if h.ContentFilter.Mode == utils.ContentFilterReject {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeContentBlocked, "Message contains blocked content")
+			return
+		}
+		flagged = true
+	}
+
+	// A reply must quote a message of this same group.
+	replyTo, replyPreview, ok := resolveReplyTo(ctx, c, req.ReplyTo, func(quoted models.Message) bool {
+		return quoted.ConversationID == conv.ID
+	})
+	if !ok {
+		return
+	}
+
+	var image utils.UploadedImage
+	if req.Image != "" {
+		var err error
+		image, err = h.CloudinaryService.UploadImage(c.Request.Context(), req.Image, h.CloudinaryService.MessageImageUpload)
+		if err != nil {
+			utils.RespondUploadError(c, utils.ImageUploadStatus(err), "Error uploading image", err)
+			return
+		}
+	}
+	audio, ok := h.uploadVoiceNote(c, req)
+	if !ok {
+		return
+	}
+
+	// Resolve @mentions against the participants.
+	var mentionCandidates []models.User
+	var mentions []primitive.ObjectID
+	if strings.Contains(req.Text, "@") {
+		var err error
+		if mentionCandidates, err = loadMentionCandidates(ctx, conv); err != nil {
+			utils.RespondInternalError(c, "Internal server error resolving mentions", err)
+			return
+		}
+		mentions = parseMentions(req.Text, mentionCandidates, loggedInUser.ID)
+	}
+
+	newMessage := models.Message{
+		ID:             primitive.NewObjectID(),
+		SenderID:       loggedInUser.ID,
+		ConversationID: conv.ID,
+		Text:           req.Text,
+		Image:          image.SecureURL, // Use the secure URL from Cloudinary
+		ImagePublicID:  image.PublicID,
+		ImageWidth:     image.Width,
+		ImageHeight:    image.Height,
+		ThumbnailURL:   image.ThumbnailURL,
+		Audio:          audio.SecureURL,
+		AudioPublicID:  audio.PublicID,
+		AudioDuration:  audio.Duration,
+		Priority:       req.Priority,
+		ReplyTo:        replyTo,
+		ReplyPreview:   replyPreview,
+		Mentions:       mentions,
+		Flagged:        flagged,
+		ExpiresAt:      expiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	newMessage.Signature = h.Signer.Sign(newMessage) // Empty when signing is disabled
+
+	if _, err := db.DB.Collection("messages").InsertOne(ctx, newMessage); err != nil {
+		utils.RespondInternalError(c, "Error saving message", err)
+		return
+	}
+
+	if flagged {
+		logging.FromContext(c).Warn("Message flagged by content filter", "message_id", newMessage.ID.Hex())
+	}
+
+	// Bump the conversation so it sorts first in GetConversations.
+	if _, err := db.DB.Collection("conversations").UpdateByID(ctx, conv.ID, bson.M{"$set": bson.M{"updatedAt": now}}); err != nil {
+		logging.FromContext(c).Error("Error updating conversation", "conversation_id", conv.ID.Hex(), "error", err)
+	}
+
+	metrics.MessagesSent.WithLabelValues("group").Inc()
+	h.Emitter.EmitConversationMessage(newMessage, conv.Participants)
+	h.notifyMentions(newMessage, conv, mentions, mentionCandidates)
+
+	c.JSON(http.StatusCreated, messageResponse(newMessage))
+}
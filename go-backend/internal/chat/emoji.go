@@ -0,0 +1,141 @@
+package chat
+
+import (
+	"sort"         // For looking code points up in emojiRanges
+	"unicode/utf8" // For walking the emoji's code points
+)
+
+// Code points that shape an emoji grapheme without being emoji themselves.
+const (
+	variationSelector16 = 0xFE0F // Emoji presentation of the preceding character
+	zeroWidthJoiner     = 0x200D // Joins emoji into one (e.g. families, "woman technologist")
+	combiningKeycap     = 0x20E3 // Turns 0-9, # and * into keycaps
+)
+
+// emojiRanges are the code points below the pictograph blocks that have the
+// Unicode Emoji property (emoji-data.txt), sorted. These blocks mostly hold
+// plain text symbols ("→", "⌂", "✓"), so only the listed ones count. Digits,
+// '#' and '*' are left out: they are emoji only as keycaps.
+var emojiRanges = [][2]rune{
+	{0x00A9, 0x00A9}, {0x00AE, 0x00AE}, {0x203C, 0x203C}, {0x2049, 0x2049},
+	{0x2122, 0x2122}, {0x2139, 0x2139}, {0x2194, 0x2199}, {0x21A9, 0x21AA},
+	{0x231A, 0x231B}, {0x2328, 0x2328}, {0x23CF, 0x23CF}, {0x23E9, 0x23F3},
+	{0x23F8, 0x23FA}, {0x24C2, 0x24C2}, {0x25AA, 0x25AB}, {0x25B6, 0x25B6},
+	{0x25C0, 0x25C0}, {0x25FB, 0x25FE}, {0x2600, 0x2604}, {0x260E, 0x260E},
+	{0x2611, 0x2611}, {0x2614, 0x2615}, {0x2618, 0x2618}, {0x261D, 0x261D},
+	{0x2620, 0x2620}, {0x2622, 0x2623}, {0x2626, 0x2626}, {0x262A, 0x262A},
+	{0x262E, 0x262F}, {0x2638, 0x263A}, {0x2640, 0x2640}, {0x2642, 0x2642},
+	{0x2648, 0x2653}, {0x265F, 0x2660}, {0x2663, 0x2663}, {0x2665, 0x2666},
+	{0x2668, 0x2668}, {0x267B, 0x267B}, {0x267E, 0x267F}, {0x2692, 0x2697},
+	{0x2699, 0x2699}, {0x269B, 0x269C}, {0x26A0, 0x26A1}, {0x26A7, 0x26A7},
+	{0x26AA, 0x26AB}, {0x26B0, 0x26B1}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5},
+	{0x26C8, 0x26C8}, {0x26CE, 0x26CF}, {0x26D1, 0x26D1}, {0x26D3, 0x26D4},
+	{0x26E9, 0x26EA}, {0x26F0, 0x26F5}, {0x26F7, 0x26FA}, {0x26FD, 0x26FD},
+	{0x2702, 0x2702}, {0x2705, 0x2705}, {0x2708, 0x270D}, {0x270F, 0x270F},
+	{0x2712, 0x2712}, {0x2714, 0x2714}, {0x2716, 0x2716}, {0x271D, 0x271D},
+	{0x2721, 0x2721}, {0x2728, 0x2728}, {0x2733, 0x2734}, {0x2744, 0x2744},
+	{0x2747, 0x2747}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2763, 0x2764}, {0x2795, 0x2797}, {0x27A1, 0x27A1},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2934, 0x2935}, {0x2B05, 0x2B07},
+	{0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x3030, 0x3030},
+	{0x303D, 0x303D}, {0x3297, 0x3297}, {0x3299, 0x3299},
+	// Mahjong, playing cards and enclosed characters: a few each.
+	{0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF}, {0x1F170, 0x1F171}, {0x1F17E, 0x1F17F},
+	{0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F201, 0x1F202}, {0x1F21A, 0x1F21A},
+	{0x1F22F, 0x1F22F}, {0x1F232, 0x1F23A}, {0x1F250, 0x1F251},
+}
+
+// emojiPresentationRanges are the emojiRanges drawn as emoji by default
+// (Emoji_Presentation), sorted. The others default to text ("⬅", "❤") and
+// are only emoji when followed by a variation selector or a skin tone.
+var emojiPresentationRanges = [][2]rune{
+	{0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F},
+	{0x2693, 0x2693}, {0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE},
+	{0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD},
+	{0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF}, {0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A},
+	{0x1F201, 0x1F201}, {0x1F21A, 0x1F21A}, {0x1F22F, 0x1F22F}, {0x1F232, 0x1F236},
+	{0x1F238, 0x1F23A}, {0x1F250, 0x1F251},
+}
+
+// inRanges reports whether r falls in one of the sorted ranges.
+func inRanges(ranges [][2]rune, r rune) bool {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i][1] >= r })
+	return i < len(ranges) && ranges[i][0] <= r
+}
+
+// emojiBase reports whether r is a character that can be drawn as an emoji on
+// its own: anything in the pictograph, emoticon, transport and supplemental
+// blocks, or one of emojiRanges. textDefault is set for the ones that need a
+// variation selector (or skin tone) to be drawn as emoji. Regional indicators
+// are not emoji bases, as they only count in pairs (flags).
+func emojiBase(r rune) (emoji, textDefault bool) {
+	if r >= 0x1F300 && r <= 0x1FAFF {
+		return true, false
+	}
+	if !inRanges(emojiRanges, r) {
+		return false, false
+	}
+	return true, !inRanges(emojiPresentationRanges, r)
+}
+
+func isRegionalIndicator(r rune) bool { return r >= 0x1F1E6 && r <= 0x1F1FF }
+func isSkinTone(r rune) bool          { return r >= 0x1F3FB && r <= 0x1F3FF }
+func isTag(r rune) bool               { return r >= 0xE0020 && r <= 0xE007F }
+
+// isSingleEmoji reports whether s is exactly one emoji grapheme: a flag (two
+// regional indicators), a keycap, or one or more emoji joined by zero-width
+// joiners, each optionally followed by a variation selector, a skin tone or
+// (for subdivision flags) tag characters. Text, text-default symbols without a
+// variation selector, several emoji and stray modifiers are rejected.
+func isSingleEmoji(s string) bool {
+	if !utf8.ValidString(s) || s == "" {
+		return false
+	}
+	runes := []rune(s)
+
+	// Flags: exactly two regional indicators.
+	if isRegionalIndicator(runes[0]) {
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	}
+	// Keycaps: 0-9, # or *, an optional variation selector, then the keycap.
+	if r := runes[0]; (r >= '0' && r <= '9') || r == '#' || r == '*' {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == variationSelector16 {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == combiningKeycap
+	}
+
+	for i := 0; i < len(runes); {
+		emoji, textDefault := emojiBase(runes[i])
+		if !emoji {
+			return false
+		}
+		i++
+		// A text-default symbol on its own is text ("⬅"), not a reaction.
+		// Inside a joined sequence it is drawn as part of the emoji anyway.
+		if textDefault && i == 1 && (i == len(runes) || !(runes[i] == variationSelector16 || isSkinTone(runes[i]))) {
+			return false
+		}
+		for i < len(runes) && (runes[i] == variationSelector16 || isSkinTone(runes[i]) || isTag(runes[i])) {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != zeroWidthJoiner {
+			return false // A second emoji (or text) that isn't joined to the first
+		}
+		i++
+		if i == len(runes) {
+			return false // Dangling joiner
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package chat
+
+import "testing"
+
+// TestIsSingleEmoji checks which reactions count as exactly one emoji when
+// custom reactions are allowed.
+func TestIsSingleEmoji(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"emoticon", "😂", true},
+		{"pictograph", "🎉", true},
+		{"supplemental", "🥰", true},
+		{"text-default with selector", "❤️", true},
+		{"dingbat", "✅", true},
+		{"arrow with emoji status", "↔️", true},
+		{"left arrow with selector", "⬅️", true},
+		{"text-default with skin tone", "☝🏽", true},
+		{"zwj with text-default part", "🏃‍♀️", true},
+		{"misc technical with emoji status", "⌚", true},
+		{"star", "⭐", true},
+		{"copyright", "©️", true},
+		{"skin tone", "👍🏽", true},
+		{"zwj sequence", "👩‍💻", true},
+		{"zwj family", "👨‍👩‍👧‍👦", true},
+		{"zwj with skin tones", "🧑🏻‍🤝‍🧑🏿", true},
+		{"flag", "🇫🇷", true},
+		{"subdivision flag", "🏴\U000E0067\U000E0062\U000E0065\U000E006E\U000E0067\U000E007F", true},
+		{"keycap", "1️⃣", true},
+		{"keycap without selector", "#⃣", true},
+
+		{"empty", "", false},
+		{"plain text", "ok", false},
+		{"digit", "1", false},
+		{"emoji and text", "👍 nice", false},
+		{"two emoji", "👍👍", false},
+		{"two different emoji", "😂🎉", false},
+		{"two flags", "🇫🇷🇩🇪", false},
+		{"lone regional indicator", "🇫", false},
+		{"dangling joiner", "👩‍", false},
+		{"leading joiner", "‍👩", false},
+		{"lone selector", "️", false},
+		{"keycap with extra", "1️⃣2", false},
+		{"plain arrow", "→", false},
+		{"house symbol", "⌂", false},
+		{"plain left arrow", "⬅", false},
+		{"text-default without selector", "❤", false},
+		{"check mark", "✓", false},
+		{"black square", "■", false},
+		{"mahjong back", "🀫", false},
+		{"invalid utf-8", "\xff", false},
+	}
+	for _, tc := range cases {
+		if got := isSingleEmoji(tc.input); got != tc.want {
+			t.Errorf("%s: isSingleEmoji(%q) = %v, want %v", tc.name, tc.input, got, tc.want)
+		}
+	}
+}
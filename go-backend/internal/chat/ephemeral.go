@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"time"     // For the expiry time
+
+	"go-backend/pkg/utils" // For the standard error response
+
+	"github.com/gin-gonic/gin"         // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
+)
+
+// Bounds of a disappearing message's lifetime (SendMessageRequest.TTL).
+const (
+	minMessageTTL = 5 * time.Second
+	maxMessageTTL = 7 * 24 * time.Hour
+)
+
+// messageExpiry turns the optional TTL of a message being sent into its
+// expiry time, zero for a message that doesn't disappear.
+// Returns false if a response has already been written.
+func messageExpiry(c *gin.Context, req SendMessageRequest, now time.Time) (time.Time, bool) {
+	if req.TTL == 0 {
+		return time.Time{}, true
+	}
+	ttl := time.Duration(req.TTL) * time.Second
+	if ttl < minMessageTTL || ttl > maxMessageTTL {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation,
+			fmt.Sprintf("ttl must be between %d and %d seconds", int64(minMessageTTL.Seconds()), int64(maxMessageTTL.Seconds())))
+		return time.Time{}, false
+	}
+	return now.Add(ttl), true
+}
+
+// notExpired is the expiresAt condition of queries that must skip expired
+// messages. MongoDB's TTL monitor only runs about once a minute, so expired
+// messages can linger briefly before they are removed. $not also matches
+// messages without an expiresAt, which never expire.
+func notExpired() bson.M {
+	return bson.M{"$not": bson.M{"$lte": time.Now()}}
+}
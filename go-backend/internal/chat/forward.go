@@ -0,0 +1,199 @@
+package chat
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Request-scoped logger
+	"go-backend/pkg/metrics"     // Messages sent counter
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+)
+
+// ForwardMessageRequest is the body of POST /api/messages/:id/forward.
+type ForwardMessageRequest struct {
+	ReceiverIDs []string `json:"receiverIds" binding:"required"` // Users to forward the message to
+}
+
+// maxForwardReceivers bounds how many users one message can be forwarded to at once.
+const maxForwardReceivers = 20
+
+// ForwardMessage copies a message the logged-in user can see into new 1-to-1
+// messages from them to each receiver, tagged as forwarded. The copies reuse
+// the original's Cloudinary URLs rather than uploading the media again.
+func (h *ChatHandler) ForwardMessage(c *gin.Context) {
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context (the forwarder)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+	senderID := loggedInUser.ID
+
+	var req ForwardMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err, utils.ErrCodeValidation, "receiverIds is required")
+		return
+	}
+
+	// Parse and de-duplicate the receivers.
+	var receiverIDs []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for _, idHex := range req.ReceiverIDs {
+		id, err := primitive.ObjectIDFromHex(idHex)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, fmt.Sprintf("Invalid receiver ID format: %s", idHex))
+			return
+		}
+		if id == senderID {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot forward a message to yourself")
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			receiverIDs = append(receiverIDs, id)
+		}
+	}
+	if len(receiverIDs) == 0 {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "At least one receiver is required")
+		return
+	}
+	if len(receiverIDs) > maxForwardReceivers {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("A message can be forwarded to at most %d users at once", maxForwardReceivers))
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var original models.Message
+	err := messagesCollection.FindOne(ctx, bson.M{"_id": messageID, "expiresAt": notExpired(), "$nor": utils.NotSilencedFor(senderID)}).Decode(&original)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	// Only messages the caller could read can be forwarded. Answer 404 rather
+	// than 403 so message IDs from other conversations can't be probed.
+	participant, err := isParticipant(ctx, original, senderID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking conversation", err)
+		return
+	}
+	if !participant {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if original.Deleted || original.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Deleted and system messages can't be forwarded")
+		return
+	}
+
+	// Every receiver must exist, so we don't create messages nobody can read.
+	count, err := db.DB.Collection("users").CountDocuments(ctx, bson.M{"_id": bson.M{"$in": receiverIDs}})
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching receivers", err)
+		return
+	}
+	if int(count) != len(receiverIDs) {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "One or more receivers were not found")
+		return
+	}
+
+	// Blocks apply as for a normal send. One blocked receiver fails the whole
+	// forward, so the caller never has to work out which copies went out.
+	// Copies to a receiver who blocked the sender silently are stored silenced.
+	silenced := make(map[primitive.ObjectID]bool)
+	for _, receiverID := range receiverIDs {
+		blocked, silent, err := utils.SenderBlockStatus(ctx, senderID, receiverID)
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error checking blocks", err)
+			return
+		}
+		if blocked && !silent {
+			utils.RespondError(c, http.StatusForbidden, utils.ErrCodeUserBlocked, "You cannot message one or more of these users")
+			return
+		}
+		silenced[receiverID] = silent
+	}
+
+	// The copy goes through moderation again: the blocklist may have changed
+	// since the original was sent.
+	flagged := false
+	if h.ContentFilter.Matches(original.Text) {
+		if h.ContentFilter.Mode == utils.ContentFilterReject {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeContentBlocked, "Message contains blocked content")
+			return
+		}
+		flagged = true
+	}
+
+	now := time.Now()
+	forwarded := make([]models.Message, len(receiverIDs))
+	documents := make([]interface{}, len(receiverIDs))
+	for i, receiverID := range receiverIDs {
+		// The public IDs are left out on purpose: the asset belongs to the
+		// original message, and copies must never be the ones to clean it up.
+		// A disappearing message's copies disappear with it.
+		message := models.Message{
+			ID:            primitive.NewObjectID(),
+			SenderID:      senderID,
+			ReceiverID:    receiverID,
+			Text:          original.Text,
+			Image:         original.Image,
+			ImageWidth:    original.ImageWidth,
+			ImageHeight:   original.ImageHeight,
+			ThumbnailURL:  original.ThumbnailURL,
+			Audio:         original.Audio,
+			AudioDuration: original.AudioDuration,
+			Priority:      models.PriorityNormal,
+			Forwarded:     true,
+			Status:        models.StatusSent,
+			Flagged:       flagged,
+			Silenced:      silenced[receiverID],
+			ExpiresAt:     original.ExpiresAt,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		message.Signature = h.Signer.Sign(message) // Empty when signing is disabled
+		forwarded[i] = message
+		documents[i] = message
+	}
+
+	if _, err := messagesCollection.InsertMany(ctx, documents); err != nil {
+		utils.RespondInternalError(c, "Error saving forwarded messages", err)
+		return
+	}
+
+	if flagged {
+		logging.FromContext(c).Warn("Forwarded message flagged by content filter", "message_id", original.ID.Hex())
+	}
+
+	metrics.MessagesSent.WithLabelValues("forward").Add(float64(len(forwarded)))
+
+	// Deliver each copy like a normal send.
+	for _, message := range forwarded {
+		if message.Silenced {
+			continue
+		}
+		h.Emitter.EmitNewMessage(message)
+		h.pushUnreadCounts(ctx, message.ReceiverID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"messages": messageResponses(forwarded)})
+}
@@ -3,112 +3,422 @@ package chat
 import (
 	"context"    // For context with MongoDB operations
 	"fmt"        // For formatted error messages
-	//"log"        // For logging errors
 	"net/http"   // For HTTP status codes
+	"strconv"    // For parsing the online filter
 	"time"       // For handling timestamps
 
+	"go-backend/config" // Import config for pagination limits
 	"go-backend/internal/models" // Import models for User and Message structs
 	"go-backend/pkg/db" // Import db to access MongoDB client
+	"go-backend/pkg/logging" // Per-request structured logger
+	"go-backend/pkg/metrics" // Messages sent counter
 	"go-backend/pkg/utils" // Import utils for socket operations AND CloudinaryService
 
 	"github.com/gin-gonic/gin" // Gin context for handling requests
 	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
 	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo" // For aggregation pipelines
 	"go.mongodb.org/mongo-driver/mongo/options" // For MongoDB find options (e.g., sort)
 )
 
 // Struct for SendMessage request body
 type SendMessageRequest struct {
-	Text  string `json:"text,omitempty"`  // Message text, optional
-	Image string `json:"image,omitempty"` // Base64 encoded image, optional
+	Text     string `json:"text,omitempty"`     // Message text, optional
+	Image    string `json:"image,omitempty"`    // Base64 encoded image, optional
+	Priority string `json:"priority,omitempty"` // "normal" (default) or "urgent", optional
+	ReplyTo  string `json:"replyTo,omitempty"`  // ID of an earlier message in the same conversation, optional
+
+	// Voice note, optional: a base64 encoded audio data URI and its length in
+	// seconds as recorded by the client. Can't be combined with Image.
+	Audio         string  `json:"audio,omitempty"`
+	AudioDuration float64 `json:"audioDuration,omitempty"`
+
+	// TTL makes the message disappear this many seconds after it is sent, optional
+	TTL int64 `json:"ttl,omitempty"`
+}
+
+// Struct for EditMessage request body
+type EditMessageRequest struct {
+	Text string `json:"text" binding:"required"` // The new message text
 }
 
+// Struct for GetMessagesBatch request body
+type BatchMessagesRequest struct {
+	UserIDs              []string `json:"userIds" binding:"required"` // The other participant of each conversation
+	LimitPerConversation int      `json:"limitPerConversation"`       // Most recent N messages per conversation, optional
+}
+
+// Caps for GetMessagesBatch, so a single request can't load unbounded history.
+// The per-conversation maximum is MESSAGE_MAX_LIMIT from config.
+const (
+	maxBatchConversations       = 20
+	defaultBatchMessagesPerChat = 20
+)
+
+// defaultMessagesPerPage is how many messages GetMessages returns when no ?limit is given.
+const defaultMessagesPerPage = 50
+
 // ChatHandler struct holds dependencies for chat operations.
 // ADDED: CloudinaryService dependency
 type ChatHandler struct {
+	Config            *config.Config
 	CloudinaryService *utils.CloudinaryService // Add Cloudinary service
+	Emitter           utils.MessageEmitter     // Pushes real-time events (the WebSocket Hub in production)
+	Presence          utils.OnlineUsersSource  // Who is online, for the sidebar's ?online=true filter (the Hub too)
+	ContentFilter     *utils.ContentFilter     // Blocklist check applied to message text
+	Signer            *utils.MessageSigner     // Optional message integrity signing (nil when disabled)
+	Mailer            utils.Mailer             // Emails offline users about @mentions (MENTION_EMAILS)
 }
 
 // NewChatHandler creates a new instance of ChatHandler.
-// MODIFIED: Accepts CloudinaryService
-func NewChatHandler(cldService *utils.CloudinaryService) *ChatHandler { // Changed signature
+// MODIFIED: Accepts CloudinaryService, the MessageEmitter used for real-time delivery, the OnlineUsersSource, the ContentFilter, the MessageSigner and the Mailer
+func NewChatHandler(cfg *config.Config, cldService *utils.CloudinaryService, emitter utils.MessageEmitter, presence utils.OnlineUsersSource, filter *utils.ContentFilter, signer *utils.MessageSigner, mailer utils.Mailer) *ChatHandler { // Changed signature
 	return &ChatHandler{
+		Config:            cfg,
 		CloudinaryService: cldService,
+		Emitter:           emitter,
+		Presence:          presence,
+		ContentFilter:     filter,
+		Signer:            signer,
+		Mailer:            mailer,
+	}
+}
+
+// messageResponse converts a stored message into the JSON shape the frontend
+// expects (see utils.MessageResponse). Used by every handler that returns messages.
+func messageResponse(msg models.Message) utils.MessageResponse {
+	return utils.NewMessageResponse(msg)
+}
+
+// conversationIDOf returns the ID clients use to route a message.
+func conversationIDOf(msg models.Message) string {
+	return utils.MessageConversationID(msg)
+}
+
+// messageResponses converts a slice of messages, preserving order.
+func messageResponses(messages []models.Message) []utils.MessageResponse {
+	return utils.NewMessageResponses(messages)
+}
+
+// windowPassed reports whether more than window has elapsed since sentAt.
+// A zero window never passes (editing or deleting is always allowed).
+func windowPassed(sentAt time.Time, window time.Duration) bool {
+	return window > 0 && time.Since(sentAt) > window
+}
+
+// formatWindow renders a window for error messages: "15 minutes" rather than "15m0s".
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%d hours", window/time.Hour)
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", window/time.Minute)
+	}
+	return window.String()
+}
+
+// blockedIDs returns the users the given user has blocked, never nil so it
+// can be used directly in a $nin filter.
+func blockedIDs(user models.User) []primitive.ObjectID {
+	if user.BlockedUsers == nil {
+		return []primitive.ObjectID{}
+	}
+	return user.BlockedUsers
+}
+
+// contactIDs returns the user's contacts, never nil so it can be used
+// directly in an $in filter.
+func contactIDs(user models.User) []primitive.ObjectID {
+	if user.Contacts == nil {
+		return []primitive.ObjectID{}
 	}
+	return user.Contacts
+}
+
+// sidebarEntry is one row of the sidebar aggregation: the user plus the
+// latest message of our conversation and how many of their messages I haven't seen.
+type sidebarEntry struct {
+	models.User `bson:",inline"`
+	LastMessage *models.Message `bson:"lastMessage,omitempty"` // nil when we've never talked
+	UnreadCount int             `bson:"unreadCount"`
 }
 
-// GetUsersForSidebar retrieves a list of users for the sidebar, excluding the logged-in user.
+// GetUsersForSidebar retrieves the logged-in user's contacts for the sidebar,
+// or every other user with ?all=true. With CONTACTS_REQUIRE_MUTUAL, only
+// contacts who added the user back are listed.
+// Each user comes with a preview of the latest message exchanged with them and
+// an unread count, and users with the most recent activity come first.
+// Supports ?limit (default SIDEBAR_DEFAULT_LIMIT, max SIDEBAR_MAX_LIMIT) and ?page.
 // Mirrors backend/src/controllers/message.controller.js -> getUsersForSidebar
 func (h *ChatHandler) GetUsersForSidebar(c *gin.Context) {
 	// Get the authenticated user from the context (set by AuthMiddleware)
 	userAny, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authenticated user not found in context"})
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
 		return
 	}
 	loggedInUser := userAny.(models.User) // Type assertion to models.User
+	myID := loggedInUser.ID
+
+	limit, ok := utils.ParseLimit(c, h.Config.SidebarDefaultLimit, h.Config.SidebarMaxLimit)
+	if !ok {
+		return
+	}
+	page, ok := utils.ParsePage(c)
+	if !ok {
+		return
+	}
+
+	// Everyone except me, hiding users on either side of a block and banned
+	// users. With ?online=true, only users the Hub currently sees online.
+	idFilter := bson.M{"$ne": myID, "$nin": blockedIDs(loggedInUser)}
+	onlineOnly := false
+	if raw := c.Query("online"); raw != "" {
+		var err error
+		if onlineOnly, err = strconv.ParseBool(raw); err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "online must be true or false")
+			return
+		}
+	}
+	if onlineOnly {
+		idFilter["$in"] = h.Presence.OnlineUserIDs()
+	}
+	match := bson.M{
+		"_id":          idFilter,
+		"blockedUsers": bson.M{"$ne": myID},
+		"bannedAt":     bson.M{"$exists": false},
+	}
+
+	// Contacts only, unless ?all=true.
+	allUsers := false
+	if raw := c.Query("all"); raw != "" {
+		var err error
+		if allUsers, err = strconv.ParseBool(raw); err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "all must be true or false")
+			return
+		}
+	}
+	if !allUsers {
+		// In an $and so it doesn't clash with the online $in on _id.
+		match["$and"] = bson.A{bson.M{"_id": bson.M{"$in": contactIDs(loggedInUser)}}}
+		if h.Config.ContactsRequireMutual {
+			match["contacts"] = myID
+		}
+	}
 
-	var users []models.User // Slice to hold the retrieved users
 	usersCollection := db.DB.Collection("users")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := utils.RequestContext(c)
 	defer cancel()
 
-	// Find all users where _id is not equal to the logged-in user's ID.
-	// The projection (options.Find().SetProjection) is used to exclude the password field.
-	cursor, err := usersCollection.Find(ctx, bson.M{"_id": bson.M{"$ne": loggedInUser.ID}}, options.Find().SetProjection(bson.M{"password": 0}))
+	// A single aggregation over the users collection builds the whole sidebar:
+	//   1. Keep the users matching match: idFilter and, by default, my
+	//      contacts, hiding those who blocked me.
+	//   2. Join the latest 1:1 message between me and each user.
+	//   3. Join the number of their messages to me that I haven't seen
+	//      (deleted or expired messages and system notices never count, like in
+	//      GetUnseenSenders).
+	//   4. Sort by the latest message, newest first. Users I've never talked to
+	//      have no lastActivity and sink to the bottom; _id keeps pages stable.
+	//   5. Page (one extra entry tells whether there is a next page), and drop
+	//      the password hash.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "messages",
+			"let":  bson.M{"otherId": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$or": bson.A{
+					bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$senderId", myID}},
+						bson.M{"$eq": bson.A{"$receiverId", "$$otherId"}},
+					}},
+					bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$senderId", "$$otherId"}},
+						bson.M{"$eq": bson.A{"$receiverId", myID}},
+					}},
+				}}, "$nor": utils.NotSilencedFor(myID), "expiresAt": notExpired()}}},
+				{{Key: "$sort", Value: bson.D{{Key: "_id", Value: -1}}}},
+				{{Key: "$limit", Value: 1}},
+			},
+			"as": "lastMessage",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "messages",
+			"let":  bson.M{"otherId": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"receiverId": myID,
+					"seen":       bson.M{"$ne": true},
+					"system":     bson.M{"$ne": true},
+					"silenced":   bson.M{"$ne": true},
+					"deleted":    bson.M{"$ne": true},
+					"expiresAt":  notExpired(),
+					"$expr":      bson.M{"$eq": bson.A{"$senderId", "$$otherId"}},
+				}}},
+				{{Key: "$count", Value: "count"}},
+			},
+			"as": "unread",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"lastMessage": bson.M{"$arrayElemAt": bson.A{"$lastMessage", 0}},
+			"unreadCount": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$unread.count", 0}}, 0}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"lastActivity": "$lastMessage.createdAt"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "lastActivity", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$skip", Value: int64((page - 1) * limit)}},
+		{{Key: "$limit", Value: int64(limit + 1)}},
+		{{Key: "$project", Value: bson.M{"password": 0, "unread": 0, "lastActivity": 0}}},
+	}
+
+	cursor, err := usersCollection.Aggregate(ctx, pipeline)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Internal server error fetching users: %v", err)})
+		utils.RespondInternalError(c, "Internal server error fetching users", err)
 		return
 	}
 	defer cursor.Close(ctx) // Ensure the cursor is closed after use
 
-	// Iterate through the cursor and decode each document into a models.User struct.
-	if err = cursor.All(ctx, &users); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error decoding users: %v", err)})
+	var entries []sidebarEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		utils.RespondInternalError(c, "Error decoding users", err)
 		return
 	}
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	// Lets the client offer "add contact" on users listed by ?all=true.
+	isContact := make(map[primitive.ObjectID]bool, len(loggedInUser.Contacts))
+	for _, id := range loggedInUser.Contacts {
+		isContact[id] = true
+	}
 
 	// Prepare response data to match frontend expectation (converting ObjectID to hex string)
-	responseUsers := make([]gin.H, len(users))
-	for i, user := range users {
+	responseUsers := make([]gin.H, len(entries))
+	for i, entry := range entries {
+		var lastMessage interface{} // null in JSON when there is no conversation yet
+		if msg := entry.LastMessage; msg != nil {
+			lastMessage = gin.H{
+				"_id":          msg.ID.Hex(),
+				"senderId":     msg.SenderID.Hex(),
+				"text":         msg.Text,
+				"image":        msg.Image,
+				"thumbnailUrl": msg.ThumbnailURL,
+				"audio":        msg.Audio,
+				"deleted":      msg.Deleted,
+				"createdAt":    msg.CreatedAt,
+			}
+		}
+		var lastSeen interface{} // null when hidden or never connected
+		if !entry.HideLastSeen && !entry.LastSeen.IsZero() {
+			lastSeen = entry.LastSeen
+		}
 		responseUsers[i] = gin.H{
-			"_id":        user.ID.Hex(),
-			"fullName":   user.FullName,
-			"email":      user.Email,
-			"profilePic": user.ProfilePic,
-			"createdAt":  user.CreatedAt,
-			"updatedAt":  user.UpdatedAt,
+			"_id":            entry.ID.Hex(),
+			"conversationId": utils.ConversationIDFor(myID, entry.ID),
+			"fullName":       entry.FullName,
+			"bio":            entry.Bio,
+			"email":          entry.Email,
+			"profilePic":     entry.ProfilePic,
+			"thumbnailUrl":   entry.ProfilePicThumbnailURL,
+			"createdAt":      entry.CreatedAt,
+			"updatedAt":      entry.UpdatedAt,
+			"lastMessage":    lastMessage,
+			"unreadCount":    entry.UnreadCount,
+			"lastSeen":       lastSeen,
+			"isContact":      isContact[entry.ID],
 		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":   responseUsers,
+		"page":    page,
+		"limit":   limit,
+		"hasMore": hasMore,
+		"online":  onlineOnly,
+		"all":     allUsers,
+	})
 }
 
-	c.JSON(http.StatusOK, responseUsers)
+// findMessagePage loads one page of the messages matching filter for
+// GetMessages and GetConversationMessages: the newest `limit` messages older
+// than the optional ?before=<messageId> cursor, in chronological order.
+// nextCursor is the `before` value for the next (older) page, or nil once
+// there are no more. Returns false if an error response has already been written.
+func findMessagePage(ctx context.Context, c *gin.Context, filter bson.M, limit int) ([]models.Message, bool, interface{}, bool) {
+	// ObjectIDs increase with insertion time, so "older than the cursor" is
+	// simply a smaller _id. This keeps pages stable even when several messages
+	// share the same createdAt.
+	if before := c.Query("before"); before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid before cursor format")
+			return nil, false, nil, false
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+	filter["expiresAt"] = notExpired()
+
+	// Fetch newest first, plus one extra message to learn whether an older page exists.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	var messages []models.Message // Slice to hold the retrieved messages
+	cursor, err := db.DB.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching messages", err)
+		return nil, false, nil, false
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &messages); err != nil {
+		utils.RespondInternalError(c, "Error decoding messages", err)
+		return nil, false, nil, false
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	// Flip the page back into chronological order for display.
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	var nextCursor interface{} // null in JSON when there are no older messages
+	if hasMore {
+		nextCursor = messages[0].ID.Hex()
+	}
+	return messages, hasMore, nextCursor, true
 }
 
-// GetMessages retrieves messages between the logged-in user and a specific receiver.
+// GetMessages retrieves messages between the logged-in user and a specific receiver,
+// one page at a time, newest page first.
+// Supports ?limit (default 50, max MESSAGE_MAX_LIMIT) and ?before=<messageId>,
+// which returns the page of messages older than that message. Each page is
+// sorted chronologically; `nextCursor` is the `before` value for the next
+// (older) page and is null once `hasMore` is false.
 // Mirrors backend/src/controllers/message.controller.js -> getMessages
 func (h *ChatHandler) GetMessages(c *gin.Context) {
 	// Get receiver ID from URL parameters
-	receiverIDParam := c.Param("id")
-	receiverID, err := primitive.ObjectIDFromHex(receiverIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receiver ID format"})
-		return
-	}
+	receiverID := utils.ObjectIDParam(c, "id")
 
 	// Get the authenticated user from the context
 	userAny, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authenticated user not found in context"})
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
 		return
 	}
 	loggedInUser := userAny.(models.User)
 	myID := loggedInUser.ID
 
-	var messages []models.Message // Slice to hold the retrieved messages
-	messagesCollection := db.DB.Collection("messages")
+	limit, ok := utils.ParseLimit(c, defaultMessagesPerPage, h.Config.MessageMaxLimit)
+	if !ok {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := utils.RequestContext(c)
 	defer cancel()
 
 	// Construct the query using $or to find messages where:
@@ -119,55 +429,47 @@ func (h *ChatHandler) GetMessages(c *gin.Context) {
 			{"senderId": myID, "receiverId": receiverID},
 			{"senderId": receiverID, "receiverId": myID},
 		},
+		"$nor": utils.NotSilencedFor(myID),
 	}
 
-	// Sort messages by createdAt to ensure chronological order
-	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
-
-	cursor, err := messagesCollection.Find(ctx, filter, findOptions)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Internal server error fetching messages: %v", err)})
+	messages, hasMore, nextCursor, ok := findMessagePage(ctx, c, filter, limit)
+	if !ok {
 		return
 	}
-	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &messages); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error decoding messages: %v", err)})
+	response := messageResponses(messages)
+	visible, err := readReceiptsVisible(ctx, loggedInUser, receiverID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		utils.RespondInternalError(c, "Internal server error fetching read receipt settings", err)
 		return
 	}
-
-	// Prepare response data (converting ObjectIDs to hex strings for frontend)
-	responseMessages := make([]gin.H, len(messages))
-	for i, msg := range messages {
-		responseMessages[i] = gin.H{
-			"_id":        msg.ID.Hex(),
-			"senderId":   msg.SenderID.Hex(),
-			"receiverId": msg.ReceiverID.Hex(),
-			"text":       msg.Text,
-			"image":      msg.Image,
-			"createdAt":  msg.CreatedAt,
-			"updatedAt":  msg.UpdatedAt,
+	if !visible {
+		hideReadReceipts(response, messages, myID)
+	}
+	if h.Signer != nil {
+		// Flag any message whose stored content no longer matches its signature.
+		for i, msg := range messages {
+			response[i].Integrity = h.Signer.Status(msg)
 		}
 	}
 
-	c.JSON(http.StatusOK, responseMessages)
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   response,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
 }
 
 // SendMessage handles sending a new message between two users.
 // Mirrors backend/src/controllers/message.controller.js -> sendMessage
 func (h *ChatHandler) SendMessage(c *gin.Context) {
 	// Get receiver ID from URL parameters
-	receiverIDParam := c.Param("id")
-	receiverID, err := primitive.ObjectIDFromHex(receiverIDParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receiver ID format"})
-		return
-	}
+	receiverID := utils.ObjectIDParam(c, "id")
 
 	// Get the authenticated user from the context (sender)
 	userAny, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authenticated user not found in context"})
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
 		return
 	}
 	loggedInUser := userAny.(models.User)
@@ -175,61 +477,800 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 
 	var req SendMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body format"})
+		utils.RespondBindError(c, err, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Blocks are enforced here, server-side, whichever side did the blocking.
+	// A silent block is the exception: the message is stored and the sender
+	// gets the usual 201, but it is marked silenced and never delivered, so the
+	// sender can't tell they were blocked. The tradeoff is that their messages
+	// quietly go nowhere, and we keep them (the blocker never sees them).
+	blocked, silenced, err := utils.SenderBlockStatus(ctx, senderID, receiverID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking blocks", err)
+		return
+	}
+	if blocked && !silenced {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeUserBlocked, "You cannot message this user")
 		return
 	}
 
-	// Ensure at least text or image is provided
-	if req.Text == "" && req.Image == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Message text or image is required"})
+	// Ensure at least text, an image or a voice note is provided
+	if req.Text == "" && req.Image == "" && req.Audio == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Message text, image or audio is required")
 		return
 	}
 
-	var imageUrl string
+	// Validate the priority against the small set we support.
+	switch req.Priority {
+	case "":
+		req.Priority = models.PriorityNormal
+	case models.PriorityNormal, models.PriorityUrgent:
+	default:
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Priority must be \"normal\" or \"urgent\"")
+		return
+	}
+
+	now := time.Now()
+	expiresAt, ok := messageExpiry(c, req, now)
+	if !ok {
+		return
+	}
+
+	// Run the moderation blocklist before doing any work (like uploading the image).
+	flagged := false
+	if h.ContentFilter.Matches(req.Text) {
+		if h.ContentFilter.Mode == utils.ContentFilterReject {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeContentBlocked, "Message contains blocked content")
+			return
+		}
+		flagged = true
+	}
+
+	// A reply must quote a message of this same 1-to-1 chat.
+	replyTo, replyPreview, ok := resolveReplyTo(ctx, c, req.ReplyTo, func(quoted models.Message) bool {
+		return !quoted.IsGroupMessage() &&
+			(quoted.SenderID == senderID && quoted.ReceiverID == receiverID ||
+				quoted.SenderID == receiverID && quoted.ReceiverID == senderID)
+	})
+	if !ok {
+		return
+	}
+
+	var image utils.UploadedImage
 	if req.Image != "" {
 		// INTEGRATED CLOUDINARY: Upload the base64 image to Cloudinary
-		uploadResultURL, err := h.CloudinaryService.UploadImage(req.Image)
+		image, err = h.CloudinaryService.UploadImage(c.Request.Context(), req.Image, h.CloudinaryService.MessageImageUpload)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error uploading image: %v", err)})
+			utils.RespondUploadError(c, utils.ImageUploadStatus(err), "Error uploading image", err)
 			return
 		}
-		imageUrl = uploadResultURL // Use the secure URL from Cloudinary
 	}
-
+	audio, ok := h.uploadVoiceNote(c, req)
+	if !ok {
+		return
+	}
 
 	// Create new message
 	newMessage := models.Message{
-		ID:         primitive.NewObjectID(),
-		SenderID:   senderID,
-		ReceiverID: receiverID,
-		Text:       req.Text,
-		Image:      imageUrl,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:            primitive.NewObjectID(),
+		SenderID:      senderID,
+		ReceiverID:    receiverID,
+		Text:          req.Text,
+		Image:         image.SecureURL, // Use the secure URL from Cloudinary
+		ImagePublicID: image.PublicID,
+		ImageWidth:    image.Width,
+		ImageHeight:   image.Height,
+		ThumbnailURL:  image.ThumbnailURL,
+		Audio:         audio.SecureURL,
+		AudioPublicID: audio.PublicID,
+		AudioDuration: audio.Duration,
+		Priority:      req.Priority,
+		ReplyTo:       replyTo,
+		ReplyPreview:  replyPreview,
+		Status:        models.StatusSent,
+		Flagged:       flagged,
+		Silenced:      silenced,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
-
-	messagesCollection := db.DB.Collection("messages")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	newMessage.Signature = h.Signer.Sign(newMessage) // Empty when signing is disabled
 
 	// Insert message into database
 	_, err = messagesCollection.InsertOne(ctx, newMessage)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error saving message: %v", err)})
+		utils.RespondInternalError(c, "Error saving message", err)
 		return
 	}
 
-	// UNCOMMENTED: Emit the new message via WebSocket for real-time update
-	utils.EmitNewMessage(newMessage)
+	if flagged {
+		logging.FromContext(c).Warn("Message flagged by content filter", "message_id", newMessage.ID.Hex())
+	}
+
+	metrics.MessagesSent.WithLabelValues("direct").Inc()
+
+	if !silenced {
+		// Emit the new message via WebSocket for real-time update
+		h.Emitter.EmitNewMessage(newMessage)
+		// The receiver now has one more unread message from us.
+		h.pushUnreadCounts(ctx, receiverID)
+	}
 
 	// Respond with the newly created message
-	c.JSON(http.StatusCreated, gin.H{
-		"_id":        newMessage.ID.Hex(),
-		"senderId":   newMessage.SenderID.Hex(),
-		"receiverId": newMessage.ReceiverID.Hex(),
-		"text":       newMessage.Text,
-		"image":      newMessage.Image,
-		"createdAt":  newMessage.CreatedAt,
-		"updatedAt":  newMessage.UpdatedAt,
+	c.JSON(http.StatusCreated, messageResponse(newMessage))
+}
+
+// EditMessage replaces the text of a message the logged-in user sent and
+// notifies the receiver with a "messageEdited" event.
+// The previous text is kept in the message's edit history. Image-only and
+// system messages can't be edited, nor can messages older than MESSAGE_EDIT_WINDOW.
+func (h *ChatHandler) EditMessage(c *gin.Context) {
+	// Get message ID from URL parameters
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context (the editor)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Message text is required")
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var message models.Message
+	err := messagesCollection.FindOne(ctx, bson.M{"_id": messageID}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	if message.SenderID != loggedInUser.ID || message.System {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Only the sender can edit a message")
+		return
+	}
+	if message.Deleted {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Deleted messages can't be edited")
+		return
+	}
+	if message.Text == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Image-only messages can't be edited")
+		return
+	}
+	if windowPassed(message.CreatedAt, h.Config.MessageEditWindow) {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeWindowExpired,
+			fmt.Sprintf("Messages can only be edited within %s of sending", formatWindow(h.Config.MessageEditWindow)))
+		return
+	}
+
+	// Edits go through the same moderation as new messages.
+	flagged := message.Flagged
+	matched := h.ContentFilter.Matches(req.Text)
+	if matched {
+		if h.ContentFilter.Mode == utils.ContentFilterReject {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeContentBlocked, "Message contains blocked content")
+			return
+		}
+		flagged = true
+	}
+
+	// In a group, mentions follow the edited text; only users it newly
+	// mentions are notified.
+	var conv models.Conversation
+	var mentionCandidates []models.User
+	var newMentions []primitive.ObjectID
+	if message.IsGroupMessage() {
+		if err := db.DB.Collection("conversations").FindOne(ctx, bson.M{"_id": message.ConversationID}).Decode(&conv); err != nil {
+			utils.RespondInternalError(c, "Internal server error fetching conversation", err)
+			return
+		}
+		if mentionCandidates, err = loadMentionCandidates(ctx, conv); err != nil {
+			utils.RespondInternalError(c, "Internal server error resolving mentions", err)
+			return
+		}
+		mentions := parseMentions(req.Text, mentionCandidates, message.SenderID)
+		alreadyMentioned := make(map[primitive.ObjectID]bool, len(message.Mentions))
+		for _, userID := range message.Mentions {
+			alreadyMentioned[userID] = true
+		}
+		for _, userID := range mentions {
+			if !alreadyMentioned[userID] {
+				newMentions = append(newMentions, userID)
+			}
+		}
+		message.Mentions = mentions
+	}
+
+	now := time.Now()
+	previous := models.MessageEdit{Text: message.Text, EditedAt: now}
+	message.Text = req.Text
+	message.Edited = true
+	message.EditedAt = now
+	message.UpdatedAt = now
+	message.Flagged = flagged
+	message.Signature = h.Signer.Sign(message) // The signature covers the text, so re-sign
+
+	update := bson.M{
+		"$set": bson.M{
+			"text":      message.Text,
+			"edited":    true,
+			"editedAt":  now,
+			"updatedAt": now,
+			"flagged":   flagged,
+			"signature": message.Signature,
+		},
+		"$push": bson.M{"editHistory": previous},
+	}
+	if message.IsGroupMessage() {
+		update["$set"].(bson.M)["mentions"] = message.Mentions
+	}
+	if _, err = messagesCollection.UpdateByID(ctx, message.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error editing message", err)
+		return
+	}
+
+	if matched {
+		logging.FromContext(c).Warn("Message edit flagged by content filter", "message_id", message.ID.Hex())
+	}
+
+	response := messageResponse(message)
+	h.notifyOtherParticipants(ctx, message, "messageEdited", response)
+	if message.IsGroupMessage() {
+		h.notifyMentions(message, conv, newMentions, mentionCandidates)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteMessage soft-deletes a message the logged-in user sent: the text,
+// image and edit history are cleared but the document stays, so GetMessages
+// can return a placeholder in its place. The receiver is notified with a
+// "messageDeleted" event. Deleting an already-deleted message is a no-op;
+// otherwise it must be younger than MESSAGE_DELETE_WINDOW.
+func (h *ChatHandler) DeleteMessage(c *gin.Context) {
+	// Get message ID from URL parameters
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var message models.Message
+	err := messagesCollection.FindOne(ctx, bson.M{"_id": messageID}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	if message.SenderID != loggedInUser.ID || message.System {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Only the sender can delete a message")
+		return
+	}
+
+	if !message.Deleted {
+		if windowPassed(message.CreatedAt, h.Config.MessageDeleteWindow) {
+			utils.RespondError(c, http.StatusForbidden, utils.ErrCodeWindowExpired,
+				fmt.Sprintf("Messages can only be deleted within %s of sending", formatWindow(h.Config.MessageDeleteWindow)))
+			return
+		}
+
+		now := time.Now()
+		message.Text = ""
+		message.Image = ""
+		message.ImagePublicID, message.ImageWidth, message.ImageHeight, message.ThumbnailURL = "", 0, 0, ""
+		message.Audio, message.AudioPublicID, message.AudioDuration = "", "", 0
+		message.Reactions = nil
+		message.Mentions = nil
+		message.Pinned, message.PinnedBy, message.PinnedAt = false, primitive.NilObjectID, time.Time{}
+		message.Deleted = true
+		message.DeletedAt = now
+		message.UpdatedAt = now
+		message.Signature = h.Signer.Sign(message) // Keep the blanked message verifiable
+
+		update := bson.M{
+			"$set": bson.M{
+				"deleted":   true,
+				"deletedAt": now,
+				"updatedAt": now,
+				"signature": message.Signature,
+			},
+			"$unset": bson.M{"text": "", "image": "", "imagePublicId": "", "imageWidth": "", "imageHeight": "", "thumbnailUrl": "",
+				"audio": "", "audioPublicId": "", "audioDuration": "", "editHistory": "", "reactions": "", "mentions": "",
+				"pinned": "", "pinnedBy": "", "pinnedAt": ""},
+		}
+		if _, err = messagesCollection.UpdateByID(ctx, message.ID, update); err != nil {
+			utils.RespondInternalError(c, "Error deleting message", err)
+			return
+		}
+		// The message is gone either way; a failure here only leaves its quote in replies.
+		if err := utils.RedactReplyPreviews(ctx, bson.M{"replyTo": message.ID}); err != nil {
+			logging.FromContext(c).Error("Error redacting reply previews", "message_id", message.ID.Hex(), "error", err)
+		}
+
+		h.notifyOtherParticipants(ctx, message, "messageDeleted", gin.H{
+			"messageId":      message.ID.Hex(),
+			"conversationId": conversationIDOf(message),
+			"deletedAt":      message.DeletedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, messageResponse(message))
+}
+
+// unseenSenderGroup is the shape of one result document produced by the
+// GetUnseenSenders aggregation: one group per sender with unseen messages.
+type unseenSenderGroup struct {
+	SenderID primitive.ObjectID `bson:"_id"`
+	Count    int                `bson:"count"`
+	Latest   models.Message     `bson:"latest"`
+	Sender   models.User        `bson:"sender"`
+}
+
+// GetUnseenSenders returns the distinct users who have sent the logged-in user
+// messages they haven't seen yet, with a count and a preview of the latest one.
+// Used by the client to render grouped notifications ("3 new from Alice").
+// Results are sorted so the sender with the most recent unseen message comes first.
+func (h *ChatHandler) GetUnseenSenders(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	messagesCollection := db.DB.Collection("messages")
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// A single aggregation does all the work:
+	//   1. Keep messages sent to me that are not marked seen. Messages without a
+	//      `seen` field at all count as unseen. Deleted or expired messages and
+	//      system notices never count.
+	//   2. Sort newest first so $first in the group picks the latest message.
+	//   3. Group by sender, counting messages and keeping the latest one.
+	//   4. Order the groups by their latest message.
+	//   5. Join the sender's user document (the password is never returned below).
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"receiverId": loggedInUser.ID, "seen": bson.M{"$ne": true}, "system": bson.M{"$ne": true}, "silenced": bson.M{"$ne": true}, "deleted": bson.M{"$ne": true}, "expiresAt": notExpired()}}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$senderId",
+			"count":  bson.M{"$sum": 1},
+			"latest": bson.M{"$first": "$$ROOT"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "latest.createdAt", Value: -1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "sender",
+		}}},
+		{{Key: "$unwind", Value: "$sender"}}, // Drops groups whose sender no longer exists
+	}
+
+	cursor, err := messagesCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching unseen senders", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var groups []unseenSenderGroup
+	if err = cursor.All(ctx, &groups); err != nil {
+		utils.RespondInternalError(c, "Error decoding unseen senders", err)
+		return
+	}
+
+	response := make([]gin.H, len(groups))
+	for i, group := range groups {
+		response[i] = gin.H{
+			"sender": gin.H{
+				"_id":        group.Sender.ID.Hex(),
+				"fullName":   group.Sender.FullName,
+				"email":      group.Sender.Email,
+				"profilePic": group.Sender.ProfilePic,
+			},
+			"conversationId": utils.ConversationIDFor(loggedInUser.ID, group.SenderID),
+			"count":          group.Count,
+			"latestMessage": gin.H{
+				"_id":       group.Latest.ID.Hex(),
+				"text":      group.Latest.Text,
+				"image":     group.Latest.Image,
+				"createdAt": group.Latest.CreatedAt,
+			},
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MarkMessageSeen marks a single message as seen by its receiver and notifies
+// the sender with a targeted "messageSeen" event, enabling per-message read indicators.
+// Only the receiver of the message may mark it seen.
+func (h *ChatHandler) MarkMessageSeen(c *gin.Context) {
+	// Get message ID from URL parameters
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context (the reader)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// A silenced message is hidden from its receiver, so it can't be marked
+	// seen either: that would tell the blocked sender it was read.
+	var message models.Message
+	err := messagesCollection.FindOne(ctx, bson.M{"_id": messageID, "$nor": utils.NotSilencedFor(loggedInUser.ID)}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	if message.ReceiverID != loggedInUser.ID {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Only the receiver can mark a message as seen")
+		return
+	}
+
+	// Marking an already-seen message is a no-op; don't re-notify the sender.
+	if !message.Seen {
+		message.Seen = true
+		message.SeenAt = time.Now()
+
+		update := bson.M{"$set": bson.M{"seen": true, "seenAt": message.SeenAt, "status": models.StatusSeen, "updatedAt": message.SeenAt}}
+		if _, err = messagesCollection.UpdateByID(ctx, message.ID, update); err != nil {
+			utils.RespondInternalError(c, "Error marking message as seen", err)
+			return
+		}
+
+		// Only tell the sender if both sides share read receipts.
+		visible, err := readReceiptsVisible(ctx, loggedInUser, message.SenderID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			logging.FromContext(c).Error("Error loading read receipt settings", "other_user_id", message.SenderID.Hex(), "error", err)
+		}
+		if visible {
+			h.Emitter.SendToUser(message.SenderID, "messageSeen", gin.H{
+				"messageId": message.ID.Hex(),
+				"seenBy":    loggedInUser.ID.Hex(),
+				"seenAt":    message.SeenAt,
+			})
+		}
+		// Keep the reader's badges on their other devices in sync.
+		h.pushUnreadCounts(ctx, loggedInUser.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":    message.ID.Hex(),
+		"status": message.DeliveryStatus(),
+		"seen":   message.Seen,
+		"seenAt": message.SeenAt,
+	})
+}
+
+// MarkConversationSeen marks every unseen message the given user sent to the
+// logged-in user as seen, and notifies that sender with a "messagesSeen" event
+// listing the affected message IDs. The URL's :id is the other user's ID.
+func (h *ChatHandler) MarkConversationSeen(c *gin.Context) {
+	// Get the sender's ID from URL parameters
+	senderID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context (the reader)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Collect the IDs first so the sender can be told exactly which messages changed.
+	filter := bson.M{
+		"senderId":   senderID,
+		"receiverId": loggedInUser.ID,
+		"seen":       bson.M{"$ne": true}, // Also matches messages stored before read receipts existed
+		"silenced":   bson.M{"$ne": true}, // The sender must never see these as read
+	}
+	cursor, err := messagesCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching messages", err)
+		return
+	}
+	var unseen []models.Message
+	if err = cursor.All(ctx, &unseen); err != nil {
+		utils.RespondInternalError(c, "Error decoding messages", err)
+		return
+	}
+
+	seenAt := time.Now()
+	ids := make([]primitive.ObjectID, len(unseen))
+	messageIDs := make([]string, len(unseen))
+	for i, msg := range unseen {
+		ids[i] = msg.ID
+		messageIDs[i] = msg.ID.Hex()
+	}
+
+	if len(ids) > 0 {
+		update := bson.M{"$set": bson.M{"seen": true, "seenAt": seenAt, "status": models.StatusSeen, "updatedAt": seenAt}}
+		if _, err = messagesCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update); err != nil {
+			utils.RespondInternalError(c, "Error marking messages as seen", err)
+			return
+		}
+
+		// Only tell the sender if both sides share read receipts.
+		visible, err := readReceiptsVisible(ctx, loggedInUser, senderID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			logging.FromContext(c).Error("Error loading read receipt settings", "other_user_id", senderID.Hex(), "error", err)
+		}
+		if visible {
+			h.Emitter.SendToUser(senderID, "messagesSeen", gin.H{
+				"messageIds": messageIDs,
+				"seenBy":     loggedInUser.ID.Hex(),
+				"seenAt":     seenAt,
+			})
+		}
+		// Keep the reader's badges on their other devices in sync.
+		h.pushUnreadCounts(ctx, loggedInUser.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messageIds": messageIDs,
+		"seenAt":     seenAt,
 	})
 }
+
+// GetMessagesBatch returns the most recent messages for several conversations at
+// once, keyed by the other user's ID, so clients can prefetch chats on cold start.
+// Runs one indexed, limited query per conversation; both the number of
+// conversations and the messages per conversation are capped.
+func (h *ChatHandler) GetMessagesBatch(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+	myID := loggedInUser.ID
+
+	var req BatchMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+
+	// Parse and de-duplicate the requested conversations.
+	var otherIDs []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for _, idHex := range req.UserIDs {
+		id, err := primitive.ObjectIDFromHex(idHex)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, fmt.Sprintf("Invalid user ID format: %s", idHex))
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			otherIDs = append(otherIDs, id)
+		}
+	}
+	if len(otherIDs) > maxBatchConversations {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("At most %d conversations can be fetched at once", maxBatchConversations))
+		return
+	}
+
+	limit := req.LimitPerConversation
+	if limit <= 0 {
+		limit = defaultBatchMessagesPerChat
+	}
+	if limit > h.Config.MessageMaxLimit {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("limitPerConversation must be at most %d", h.Config.MessageMaxLimit))
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Newest first so the limit keeps the most recent messages; reversed below.
+	findOptions := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+
+	response := make(gin.H, len(otherIDs))
+	for _, otherID := range otherIDs {
+		filter := bson.M{
+			"$or": []bson.M{
+				{"senderId": myID, "receiverId": otherID},
+				{"senderId": otherID, "receiverId": myID},
+			},
+			"$nor":      utils.NotSilencedFor(myID),
+			"expiresAt": notExpired(),
+		}
+
+		cursor, err := messagesCollection.Find(ctx, filter, findOptions)
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error fetching messages", err)
+			return
+		}
+		var messages []models.Message
+		err = cursor.All(ctx, &messages) // All closes the cursor
+		if err != nil {
+			utils.RespondInternalError(c, "Error decoding messages", err)
+			return
+		}
+
+		// Return each conversation in chronological order, like GetMessages.
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+		conversation := messageResponses(messages)
+		visible, err := readReceiptsVisible(ctx, loggedInUser, otherID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			utils.RespondInternalError(c, "Internal server error fetching read receipt settings", err)
+			return
+		}
+		if !visible {
+			hideReadReceipts(conversation, messages, myID)
+		}
+		response[otherID.Hex()] = conversation
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyConversation (admin) re-checks the signature of every message between
+// two users and reports the ones that were modified or are unsigned.
+// Query params: userA and userB, the two participants' IDs.
+func (h *ChatHandler) VerifyConversation(c *gin.Context) {
+	if h.Signer == nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeFeatureDisabled, "Message signing is not enabled")
+		return
+	}
+
+	userA, errA := primitive.ObjectIDFromHex(c.Query("userA"))
+	userB, errB := primitive.ObjectIDFromHex(c.Query("userB"))
+	if errA != nil || errB != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid userA or userB ID format")
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	ctx, cancel := db.ScanContextFrom(c.Request.Context()) // Full conversation scan
+	defer cancel()
+
+	filter := bson.M{
+		"$or": []bson.M{
+			{"senderId": userA, "receiverId": userB},
+			{"senderId": userB, "receiverId": userA},
+		},
+	}
+	cursor, err := messagesCollection.Find(ctx, filter)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching messages", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	checked := 0
+	tampered := []string{}
+	unsigned := []string{}
+	for cursor.Next(ctx) {
+		var msg models.Message
+		if err := cursor.Decode(&msg); err != nil {
+			utils.RespondInternalError(c, "Error decoding message", err)
+			return
+		}
+		checked++
+		switch h.Signer.Status(msg) {
+		case utils.IntegrityTampered:
+			tampered = append(tampered, msg.ID.Hex())
+		case utils.IntegrityUnsigned:
+			unsigned = append(unsigned, msg.ID.Hex())
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		utils.RespondInternalError(c, "Error reading messages", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checked":  checked,
+		"tampered": tampered,
+		"unsigned": unsigned,
+	})
+}
+
+// GetMessage returns a single message by ID, for notification deep-links and
+// report review. Only the sender or receiver may fetch it.
+func (h *ChatHandler) GetMessage(c *gin.Context) {
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var message models.Message
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": messageID, "expiresAt": notExpired(), "$nor": utils.NotSilencedFor(loggedInUser.ID)}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	participant, err := isParticipant(ctx, message, loggedInUser.ID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching conversation", err)
+		return
+	}
+	if !participant {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "You are not a participant in this conversation")
+		return
+	}
+
+	response := messageResponse(message)
+	if message.SenderID == loggedInUser.ID && !message.IsGroupMessage() {
+		visible, err := readReceiptsVisible(ctx, loggedInUser, message.ReceiverID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			utils.RespondInternalError(c, "Internal server error fetching read receipt settings", err)
+			return
+		}
+		if !visible {
+			hideReadReceipts([]utils.MessageResponse{response}, []models.Message{message}, loggedInUser.ID)
+		}
+	}
+	if h.Signer != nil {
+		response.Integrity = h.Signer.Status(message)
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"      // For context with MongoDB operations
+	"fmt"          // For the notification email
+	"log/slog"     // For logging failed emails
+	"strings"      // For matching names in the text
+	"unicode"      // For word boundaries around a mention
+	"unicode/utf8" // For decoding the runes around a mention
+
+	"go-backend/internal/models" // Import models for the User, Message and Conversation structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+
+	"github.com/gin-gonic/gin"                   // For the event payload
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projections
+)
+
+// loadMentionCandidates returns the participants of a group that can be
+// mentioned in it, with the fields mentions are matched against.
+func loadMentionCandidates(ctx context.Context, conv models.Conversation) ([]models.User, error) {
+	cursor, err := db.DB.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": conv.Participants}},
+		options.Find().SetProjection(bson.M{"fullName": 1, "email": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// mentionHandles returns what can follow "@" to mention user: their full name,
+// or the part of their email before the "@" as a one-word username.
+func mentionHandles(user models.User) []string {
+	handles := []string{user.FullName}
+	if local, _, found := strings.Cut(user.Email, "@"); found && local != "" {
+		handles = append(handles, local)
+	}
+	return handles
+}
+
+// isWordRune reports whether r continues a word, so "@Ann" doesn't match in
+// "@Anna" or inside "bob@example.com".
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// parseMentions resolves the "@name" mentions in text against the group's
+// participants, case-insensitively, preferring the longest handle that
+// matches (so "@Ann Lee" beats "@Ann"). Only participants can be mentioned;
+// anything else after "@" is plain text. The sender never mentions themselves.
+// The result is in order of first mention, without duplicates.
+func parseMentions(text string, candidates []models.User, senderID primitive.ObjectID) []primitive.ObjectID {
+	var mentions []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for i := 0; i < len(text); i++ {
+		if text[i] != '@' {
+			continue
+		}
+		if before, _ := utf8.DecodeLastRuneInString(text[:i]); i > 0 && isWordRune(before) {
+			continue // An email address, not a mention
+		}
+		rest := text[i+1:]
+		var best primitive.ObjectID
+		bestLength := 0
+		for _, user := range candidates {
+			for _, handle := range mentionHandles(user) {
+				if len(handle) <= bestLength || len(handle) > len(rest) || !strings.EqualFold(rest[:len(handle)], handle) {
+					continue
+				}
+				if after, _ := utf8.DecodeRuneInString(rest[len(handle):]); len(rest) > len(handle) && isWordRune(after) {
+					continue
+				}
+				best, bestLength = user.ID, len(handle)
+			}
+		}
+		if bestLength == 0 {
+			continue
+		}
+		i += bestLength
+		if best != senderID && !seen[best] {
+			seen[best] = true
+			mentions = append(mentions, best)
+		}
+	}
+	return mentions
+}
+
+// notifyMentions sends a "mention" event to each mentioned user, whether or
+// not they have the group open. With MENTION_EMAILS, mentioned users who are
+// offline are also emailed, in the background.
+func (h *ChatHandler) notifyMentions(message models.Message, conv models.Conversation, mentioned []primitive.ObjectID, candidates []models.User) {
+	if len(mentioned) == 0 {
+		return
+	}
+	h.Emitter.SendToUsers(mentioned, "mention", gin.H{
+		"conversationId":   conv.ID.Hex(),
+		"conversationName": conv.Name,
+		"message":          messageResponse(message),
+	})
+
+	if !h.Config.MentionEmails || h.Mailer == nil {
+		return
+	}
+	online := make(map[primitive.ObjectID]bool)
+	for _, userID := range h.Presence.OnlineUserIDs() {
+		online[userID] = true
+	}
+	users := make(map[primitive.ObjectID]models.User, len(candidates))
+	for _, user := range candidates {
+		users[user.ID] = user
+	}
+	sender := users[message.SenderID]
+	for _, userID := range mentioned {
+		user, ok := users[userID]
+		if !ok || online[userID] || user.Email == "" {
+			continue
+		}
+		subject := fmt.Sprintf("%s mentioned you in %s", sender.FullName, conv.Name)
+		body := fmt.Sprintf("%s mentioned you in %s:\n\n%s\n\nOpen the chat: %s", sender.FullName, conv.Name, message.Text, h.Config.AppBaseURL)
+		go func(to string) {
+			if err := h.Mailer.Send(to, subject, body); err != nil {
+				slog.Error("Error sending mention email", "message_id", message.ID.Hex(), "error", err)
+			}
+		}(user.Email)
+	}
+}
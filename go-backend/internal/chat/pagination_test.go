@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-backend/internal/models"
+	"go-backend/pkg/db/dbtest"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestFindMessagePageWhileInserting pages back through a conversation while new
+// messages keep arriving, and checks every original message shows up exactly
+// once, newest page first, with no newer messages leaking into older pages.
+func TestFindMessagePageWhileInserting(t *testing.T) {
+	database := dbtest.Connect(t)
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	insert := func(text string) primitive.ObjectID {
+		t.Helper()
+		// Every message shares a createdAt, which is why paging is by _id.
+		msg := models.Message{ID: primitive.NewObjectID(), SenderID: alice, ReceiverID: bob, Text: text, CreatedAt: time.Unix(1700000000, 0)}
+		if _, err := database.Collection("messages").InsertOne(ctx, msg); err != nil {
+			t.Fatalf("inserting message: %v", err)
+		}
+		return msg.ID
+	}
+
+	const total, limit = 23, 5
+	var original []primitive.ObjectID
+	for i := 0; i < total; i++ {
+		original = append(original, insert(fmt.Sprintf("original %d", i)))
+	}
+
+	seen := map[primitive.ObjectID]int{}
+	var order []primitive.ObjectID // Oldest last, as the pages are walked
+	before := ""
+	for page := 0; ; page++ {
+		if page > total {
+			t.Fatal("pagination did not terminate")
+		}
+		target := "/messages?limit=5"
+		if before != "" {
+			target += "&before=" + before
+		}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", target, nil)
+		filter := bson.M{"$or": []bson.M{
+			{"senderId": alice, "receiverId": bob},
+			{"senderId": bob, "receiverId": alice},
+		}}
+
+		messages, hasMore, nextCursor, ok := findMessagePage(ctx, c, filter, limit)
+		if !ok {
+			t.Fatalf("page %d: findMessagePage failed", page)
+		}
+		// Pages are chronological, so walk each one backwards.
+		for i := len(messages) - 1; i >= 0; i-- {
+			seen[messages[i].ID]++
+			order = append(order, messages[i].ID)
+		}
+
+		// A new message arrives between page requests.
+		insert(fmt.Sprintf("arrived after page %d", page))
+
+		if !hasMore {
+			if nextCursor != nil {
+				t.Errorf("page %d: nextCursor = %v with hasMore false", page, nextCursor)
+			}
+			break
+		}
+		before = nextCursor.(string)
+	}
+
+	// The first page is read before anything new arrives, so the walk should be
+	// exactly the originals, newest to oldest.
+	if len(order) != total {
+		t.Fatalf("got %d messages across pages, want %d", len(order), total)
+	}
+	for i, id := range order {
+		if want := original[total-1-i]; id != want {
+			t.Errorf("message %d = %s, want %s", i, id.Hex(), want.Hex())
+		}
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("message %s returned %d times", id.Hex(), n)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package chat
+
+import (
+	"context"  // For context with MongoDB operations
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps and the query timeout
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For sorting pinned messages
+)
+
+// sameConversation matches the messages of msg's conversation: its group, or
+// both directions of its 1-to-1 chat.
+func sameConversation(msg models.Message) bson.M {
+	if msg.IsGroupMessage() {
+		return bson.M{"conversationId": msg.ConversationID}
+	}
+	return bson.M{
+		"$or": []bson.M{
+			{"senderId": msg.SenderID, "receiverId": msg.ReceiverID},
+			{"senderId": msg.ReceiverID, "receiverId": msg.SenderID},
+		},
+	}
+}
+
+// findPinnableMessage loads the :id message for a pin change and checks the
+// caller is a participant of its conversation.
+// Returns false if a response has already been written.
+func findPinnableMessage(ctx context.Context, c *gin.Context, userID primitive.ObjectID) (models.Message, bool) {
+	var message models.Message
+	messageID := utils.ObjectIDParam(c, "id")
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": messageID, "expiresAt": notExpired()}).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return message, false
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return message, false
+	}
+
+	participant, err := isParticipant(ctx, message, userID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking conversation", err)
+		return message, false
+	}
+	if !participant {
+		// Same answer as a missing message, so IDs can't be probed.
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return message, false
+	}
+	return message, true
+}
+
+// PinMessage pins a message to the top of its conversation. Any participant
+// can pin, up to MAX_PINNED_MESSAGES per conversation; pinning a message that
+// is already pinned is a no-op. Every participant gets a "messagePin" event.
+func (h *ChatHandler) PinMessage(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	message, ok := findPinnableMessage(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+	if message.Deleted || message.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot pin this message")
+		return
+	}
+	if message.Pinned {
+		c.JSON(http.StatusOK, messageResponse(message))
+		return
+	}
+
+	messagesCollection := db.DB.Collection("messages")
+	filter := sameConversation(message)
+	filter["pinned"] = true
+	filter["expiresAt"] = notExpired()
+	count, err := messagesCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error counting pinned messages", err)
+		return
+	}
+	if int(count) >= h.Config.MaxPinnedMessages {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation,
+			fmt.Sprintf("At most %d messages can be pinned in a conversation; unpin one first", h.Config.MaxPinnedMessages))
+		return
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"pinned": true, "pinnedBy": loggedInUser.ID, "pinnedAt": now, "updatedAt": now}}
+	if _, err := messagesCollection.UpdateByID(ctx, message.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error pinning message", err)
+		return
+	}
+	message.Pinned, message.PinnedBy, message.PinnedAt = true, loggedInUser.ID, now
+
+	h.notifyPinChange(ctx, message, "pinned", loggedInUser.ID)
+	c.JSON(http.StatusOK, messageResponse(message))
+}
+
+// UnpinMessage unpins a message. Any participant can unpin, whoever pinned it;
+// unpinning a message that isn't pinned is a no-op.
+// Every participant gets a "messagePin" event.
+func (h *ChatHandler) UnpinMessage(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	message, ok := findPinnableMessage(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+	if !message.Pinned {
+		c.JSON(http.StatusOK, messageResponse(message))
+		return
+	}
+
+	update := bson.M{
+		"$unset": bson.M{"pinned": "", "pinnedBy": "", "pinnedAt": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	}
+	if _, err := db.DB.Collection("messages").UpdateByID(ctx, message.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error unpinning message", err)
+		return
+	}
+	message.Pinned, message.PinnedBy, message.PinnedAt = false, primitive.NilObjectID, time.Time{}
+
+	h.notifyPinChange(ctx, message, "unpinned", loggedInUser.ID)
+	c.JSON(http.StatusOK, messageResponse(message))
+}
+
+// notifyPinChange sends a "messagePin" event to every participant of the
+// message's conversation, including the user who changed the pin so their
+// other devices stay in sync.
+func (h *ChatHandler) notifyPinChange(ctx context.Context, message models.Message, action string, userID primitive.ObjectID) {
+	h.notifyParticipantsExcept(ctx, message, primitive.NilObjectID, "messagePin", gin.H{
+		"messageId":      message.ID.Hex(),
+		"conversationId": conversationIDOf(message),
+		"action":         action, // "pinned" or "unpinned"
+		"userId":         userID.Hex(),
+		"message":        messageResponse(message),
+	})
+}
+
+// GetPinnedMessages returns the pinned messages of the 1-to-1 chat with the
+// user in the URL, most recently pinned first.
+func (h *ChatHandler) GetPinnedMessages(c *gin.Context) {
+	otherID := utils.ObjectIDParam(c, "id")
+
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	h.respondWithPinned(ctx, c, sameConversation(models.Message{SenderID: loggedInUser.ID, ReceiverID: otherID}))
+}
+
+// GetConversationPinnedMessages returns the pinned messages of a group
+// conversation, most recently pinned first.
+func (h *ChatHandler) GetConversationPinnedMessages(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	conv, ok := findConversation(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+
+	h.respondWithPinned(ctx, c, bson.M{"conversationId": conv.ID})
+}
+
+// respondWithPinned responds with the pinned messages matching filter (a
+// conversation), most recently pinned first.
+func (h *ChatHandler) respondWithPinned(ctx context.Context, c *gin.Context, filter bson.M) {
+	filter["pinned"] = true
+	filter["expiresAt"] = notExpired()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "pinnedAt", Value: -1}})
+	cursor, err := db.DB.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching pinned messages", err)
+		return
+	}
+	var messages []models.Message
+	if err = cursor.All(ctx, &messages); err != nil {
+		utils.RespondInternalError(c, "Error decoding pinned messages", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messageResponses(messages)})
+}
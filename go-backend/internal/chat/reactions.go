@@ -0,0 +1,207 @@
+package chat
+
+import (
+	"context"  // For context with MongoDB operations
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For returning the updated document
+)
+
+// ReactRequest is the body of POST /api/messages/:id/react.
+type ReactRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// allowedReaction reports whether emoji may be used as a reaction: any single
+// emoji when custom reactions are on, otherwise only the configured allowlist.
+func (h *ChatHandler) allowedReaction(emoji string) bool {
+	if h.Config.AllowCustomReactions {
+		return isSingleEmoji(emoji)
+	}
+	for _, allowed := range utils.SplitCommaList(h.Config.ReactionAllowlist) {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// findReactableMessage loads the :id message for a reaction change and checks
+// the caller may react to it. Returns false if a response has already been written.
+func findReactableMessage(ctx context.Context, c *gin.Context, userID primitive.ObjectID) (models.Message, bool) {
+	var message models.Message
+	messageID := utils.ObjectIDParam(c, "id")
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": messageID}).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return message, false
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return message, false
+	}
+
+	participant, err := isParticipant(ctx, message, userID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking conversation", err)
+		return message, false
+	}
+	if !participant {
+		// Same answer as a missing message, so IDs can't be probed.
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return message, false
+	}
+	if message.Deleted || message.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot react to this message")
+		return message, false
+	}
+	return message, true
+}
+
+// updateReactions applies update to the message's reactions, bumping its
+// updatedAt so /api/messages/sync picks the change up.
+func updateReactions(ctx context.Context, messageID primitive.ObjectID, update bson.M) error {
+	update["$set"] = bson.M{"updatedAt": time.Now()}
+	_, err := db.DB.Collection("messages").UpdateByID(ctx, messageID, update)
+	return err
+}
+
+// ReactToMessage sets the logged-in user's reaction on a message. Each user has
+// at most one reaction per message: reacting with a different emoji replaces
+// it, and reacting again with the same emoji removes it (a toggle).
+// Other participants get a "messageReaction" event.
+func (h *ChatHandler) ReactToMessage(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	var req ReactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A single emoji is required")
+		return
+	}
+	if !h.allowedReaction(req.Emoji) {
+		if h.Config.AllowCustomReactions {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "A single emoji is required")
+		} else {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "That emoji is not allowed as a reaction")
+		}
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	message, ok := findReactableMessage(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+
+	// Count the distinct emoji other users have left, since the caller's own
+	// reaction is about to be replaced.
+	toggledOff := false
+	distinct := map[string]bool{}
+	for _, reaction := range message.Reactions {
+		if reaction.UserID == loggedInUser.ID {
+			toggledOff = reaction.Emoji == req.Emoji
+			continue
+		}
+		distinct[reaction.Emoji] = true
+	}
+	if !toggledOff && !distinct[req.Emoji] && len(distinct) >= h.Config.MaxDistinctReactions {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation,
+			fmt.Sprintf("A message can have at most %d different reactions", h.Config.MaxDistinctReactions))
+		return
+	}
+
+	// Drop any existing reaction by this user first; then, unless this was a
+	// toggle-off, add the new one.
+	if err := updateReactions(ctx, message.ID, bson.M{"$pull": bson.M{"reactions": bson.M{"userId": loggedInUser.ID}}}); err != nil {
+		utils.RespondInternalError(c, "Error updating reaction", err)
+		return
+	}
+	action := "removed"
+	if !toggledOff {
+		action = "added"
+		reaction := models.Reaction{UserID: loggedInUser.ID, Emoji: req.Emoji, CreatedAt: time.Now()}
+		if err := updateReactions(ctx, message.ID, bson.M{"$push": bson.M{"reactions": reaction}}); err != nil {
+			utils.RespondInternalError(c, "Error updating reaction", err)
+			return
+		}
+	}
+
+	h.respondWithReactions(ctx, c, message, loggedInUser.ID, req.Emoji, action)
+}
+
+// RemoveReaction removes the logged-in user's reaction from a message, if any.
+// Other participants get a "messageReaction" event.
+func (h *ChatHandler) RemoveReaction(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	message, ok := findReactableMessage(ctx, c, loggedInUser.ID)
+	if !ok {
+		return
+	}
+
+	var emoji string
+	for _, reaction := range message.Reactions {
+		if reaction.UserID == loggedInUser.ID {
+			emoji = reaction.Emoji
+		}
+	}
+	if emoji != "" {
+		if err := updateReactions(ctx, message.ID, bson.M{"$pull": bson.M{"reactions": bson.M{"userId": loggedInUser.ID}}}); err != nil {
+			utils.RespondInternalError(c, "Error removing reaction", err)
+			return
+		}
+	}
+
+	h.respondWithReactions(ctx, c, message, loggedInUser.ID, emoji, "removed")
+}
+
+// respondWithReactions reloads the message's reactions, notifies the other
+// participants with a "messageReaction" event and responds with the summary.
+// emoji is "" when nothing changed, in which case nobody is notified.
+func (h *ChatHandler) respondWithReactions(ctx context.Context, c *gin.Context, message models.Message, userID primitive.ObjectID, emoji, action string) {
+	var updated models.Message
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": message.ID},
+		options.FindOne().SetProjection(bson.M{"reactions": 1})).Decode(&updated)
+	if err != nil {
+		utils.RespondInternalError(c, "Error fetching reactions", err)
+		return
+	}
+
+	response := gin.H{
+		"messageId":      message.ID.Hex(),
+		"conversationId": conversationIDOf(message),
+		"userId":         userID.Hex(),
+		"emoji":          emoji,
+		"action":         action, // "added" or "removed"
+		"reactions":      utils.ReactionSummaries(updated.Reactions),
+	}
+	if emoji != "" {
+		h.notifyParticipantsExcept(ctx, message, userID, "messageReaction", response)
+	}
+	c.JSON(http.StatusOK, response)
+}
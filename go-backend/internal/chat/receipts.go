@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"context" // For context with MongoDB operations
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the message response type
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projections
+)
+
+// Read receipts are symmetric: if either participant has turned off
+// sendReadReceipts, neither of them learns when the other read a message.
+// Messages are still marked seen in the database (unread counts need that),
+// but the seen status of messages *sent by* the caller is withheld.
+
+// readReceiptsEnabledFor loads a user's sendReadReceipts preference.
+func readReceiptsEnabledFor(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"sendReadReceipts": 1})).Decode(&user)
+	if err != nil {
+		return false, err
+	}
+	return user.ReadReceiptsEnabled(), nil
+}
+
+// readReceiptsVisible reports whether read receipts flow between me and other.
+func readReceiptsVisible(ctx context.Context, me models.User, otherID primitive.ObjectID) (bool, error) {
+	if !me.ReadReceiptsEnabled() {
+		return false, nil
+	}
+	return readReceiptsEnabledFor(ctx, otherID)
+}
+
+// hideReadReceipts blanks the seen status of the messages in a response that
+// were sent by myID. A seen message still shows as delivered: delivery isn't a
+// read receipt. response[i] must correspond to messages[i].
+func hideReadReceipts(response []utils.MessageResponse, messages []models.Message, myID primitive.ObjectID) {
+	for i, msg := range messages {
+		if msg.SenderID == myID {
+			response[i].Seen = false
+			response[i].SeenAt = utils.Timestamp{}
+			if msg.Seen {
+				response[i].Status = models.StatusDelivered
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"context"  // For context with MongoDB operations
+	"net/http" // For HTTP status codes
+
+	"go-backend/internal/models" // Import models for the Message struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+)
+
+// maxReplyPreviewRunes bounds the quoted text copied into a reply's preview.
+const maxReplyPreviewRunes = 200
+
+// resolveReplyTo validates the optional replyTo of a message being sent: it
+// must be the ID of a message, neither deleted, expired nor a system notice,
+// that belongs to the same conversation (inConversation decides). On success
+// it returns the ID and a preview snapshot to store on the reply, or nils when
+// replyTo is empty.
+// Returns false if a response has already been written.
+func resolveReplyTo(ctx context.Context, c *gin.Context, replyTo string, inConversation func(models.Message) bool) (*primitive.ObjectID, *models.ReplyPreview, bool) {
+	if replyTo == "" {
+		return nil, nil, true
+	}
+	replyToID, err := primitive.ObjectIDFromHex(replyTo)
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid replyTo message ID format")
+		return nil, nil, false
+	}
+
+	var quoted models.Message
+	err = db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": replyToID, "expiresAt": notExpired()}).Decode(&quoted)
+	if err != nil && err != mongo.ErrNoDocuments {
+		utils.RespondInternalError(c, "Internal server error fetching replied-to message", err)
+		return nil, nil, false
+	}
+	// A message from another conversation gets the same answer as a missing
+	// one, so IDs can't be probed.
+	if err == mongo.ErrNoDocuments || !inConversation(quoted) {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "The replied-to message is not in this conversation")
+		return nil, nil, false
+	}
+	if quoted.Deleted {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot reply to a deleted message")
+		return nil, nil, false
+	}
+	if quoted.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot reply to a system message")
+		return nil, nil, false
+	}
+
+	// A disappearing message's text isn't copied: the preview would outlive
+	// it. Clients show the quote from the original while it exists.
+	text := []rune(quoted.Text)
+	if !quoted.ExpiresAt.IsZero() {
+		text = nil
+	}
+	if len(text) > maxReplyPreviewRunes {
+		text = append(text[:maxReplyPreviewRunes], '…')
+	}
+	return &replyToID, &models.ReplyPreview{
+		SenderID: quoted.SenderID,
+		Text:     string(text),
+		HasImage: quoted.Image != "",
+		HasAudio: quoted.Audio != "",
+	}, true
+}
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"net/http"     // For HTTP status codes
+	"strings"      // For trimming the details
+	"time"         // For the report timestamp
+	"unicode/utf8" // For counting characters of the details
+
+	"go-backend/internal/models" // Import models for User, Message and Report structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/logging"     // Request-scoped logger
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"          // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"  // For MongoDB queries
+	"go.mongodb.org/mongo-driver/mongo" // For mongo.ErrNoDocuments
+)
+
+// ReportMessageRequest is the body of POST /api/messages/:id/report.
+type ReportMessageRequest struct {
+	Reason  string `json:"reason" binding:"required"` // One of the models.ReportReason constants
+	Details string `json:"details"`                   // Optional explanation for the moderators
+}
+
+// maxReportDetailsLength caps the details of a report, in characters.
+const maxReportDetailsLength = 500
+
+// ReportMessage files a report against a message the logged-in user received,
+// for admins to review. Each user can report a message once; a second report
+// is refused with 409 ALREADY_REPORTED.
+func (h *ChatHandler) ReportMessage(c *gin.Context) {
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context (the reporter)
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	var req ReportMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBindError(c, err, utils.ErrCodeValidation, "reason is required")
+		return
+	}
+	if !models.ValidReportReason(req.Reason) {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "reason must be one of spam, harassment, hate, violence, sexual or other")
+		return
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "details must be at most 500 characters")
+		return
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var message models.Message
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": messageID, "expiresAt": notExpired()}).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	participant, err := isParticipant(ctx, message, loggedInUser.ID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking conversation", err)
+		return
+	}
+	if !participant {
+		// Same answer as a missing message, so IDs can't be probed.
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if message.SenderID == loggedInUser.ID {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot report your own message")
+		return
+	}
+	if message.Deleted || message.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot report this message")
+		return
+	}
+
+	report := models.Report{
+		ReporterID:   loggedInUser.ID,
+		MessageID:    message.ID,
+		SenderID:     message.SenderID,
+		Reason:       req.Reason,
+		Details:      details,
+		MessageText:  message.Text,
+		MessageImage: message.Image,
+		MessageAudio: message.Audio,
+		CreatedAt:    time.Now(),
+	}
+	// The unique (reporterId, messageId) index turns a repeated report, even
+	// a concurrent one, into a duplicate key error.
+	result, err := db.DB.Collection("reports").InsertOne(ctx, report)
+	if mongo.IsDuplicateKeyError(err) {
+		utils.RespondError(c, http.StatusConflict, utils.ErrCodeAlreadyReported, "You have already reported this message")
+		return
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Error saving report", err)
+		return
+	}
+
+	logging.FromContext(c).Info("Message reported", "message_id", message.ID.Hex(), "reason", req.Reason)
+	c.JSON(http.StatusCreated, gin.H{
+		"_id":       result.InsertedID,
+		"messageId": message.ID.Hex(),
+		"reason":    report.Reason,
+		"createdAt": report.CreatedAt,
+	})
+}
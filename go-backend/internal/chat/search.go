@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"regexp"   // For escaping the search term
+	"strings"  // For trimming the search term
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For pagination query params
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For MongoDB find options (e.g., sort)
+)
+
+// Search paging and input bounds. The maximum page size is SEARCH_MAX_LIMIT from config.
+const (
+	defaultSearchResults = 20
+	maxSearchQueryLength = 100
+)
+
+// SearchMessages finds the messages between the logged-in user and the user
+// in :id whose text contains ?q (case-insensitive), newest first.
+// Image-only and deleted messages have no text and never match.
+// Supports ?limit (default 20, max SEARCH_MAX_LIMIT) and ?before=<messageId>;
+// `nextCursor` is the `before` value for the next (older) page of results.
+// No matches is an empty list, not an error.
+func (h *ChatHandler) SearchMessages(c *gin.Context) {
+	otherID := utils.ObjectIDParam(c, "id")
+
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+	myID := loggedInUser.ID
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "q is required")
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("q must be at most %d characters", maxSearchQueryLength))
+		return
+	}
+
+	limit, ok := utils.ParseLimit(c, defaultSearchResults, h.Config.SearchMaxLimit)
+	if !ok {
+		return
+	}
+
+	// The term is matched literally: QuoteMeta stops users from sending regex
+	// syntax (and expensive patterns) to the database.
+	filter := bson.M{
+		"$or": []bson.M{
+			{"senderId": myID, "receiverId": otherID},
+			{"senderId": otherID, "receiverId": myID},
+		},
+		"text":      bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"},
+		"$nor":      utils.NotSilencedFor(myID),
+		"expiresAt": notExpired(),
+	}
+	if before := c.Query("before"); before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid before cursor format")
+			return
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Newest first, plus one extra result to learn whether there is another page.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	var messages []models.Message
+	cursor, err := db.DB.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error searching messages", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &messages); err != nil {
+		utils.RespondInternalError(c, "Error decoding messages", err)
+		return
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	var nextCursor interface{} // null in JSON when there are no more results
+	if hasMore {
+		nextCursor = messages[len(messages)-1].ID.Hex()
+	}
+
+	response := messageResponses(messages)
+	visible, err := readReceiptsVisible(ctx, loggedInUser, otherID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		utils.RespondInternalError(c, "Internal server error fetching read receipt settings", err)
+		return
+	}
+	if !visible {
+		hideReadReceipts(response, messages, myID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":      query,
+		"messages":   response,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-backend/config"
+	"go-backend/internal/models"
+	"go-backend/pkg/db/dbtest"
+	"go-backend/pkg/utils"
+	"go-backend/pkg/utils/sockettest"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sendMessageContext builds the context SendMessage sees behind AuthMiddleware:
+// the sender as "user" and the receiver as the :id parameter.
+func sendMessageContext(sender models.User, receiverID primitive.ObjectID, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("POST", "/api/messages/send/"+receiverID.Hex(), strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: receiverID.Hex()}}
+	utils.ValidateObjectIDParam("id", "receiver")(c) // As the route does
+	c.Set("user", sender)
+	return c, recorder
+}
+
+func TestSendMessageRejectsMalformedBody(t *testing.T) {
+	emitter := &sockettest.FakeEmitter{}
+	h := &ChatHandler{Config: &config.Config{}, Emitter: emitter}
+	sender := models.User{ID: primitive.NewObjectID()}
+
+	c, recorder := sendMessageContext(sender, primitive.NewObjectID(), "not json")
+	h.SendMessage(c)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	if events := emitter.Events(); len(events) != 0 {
+		t.Errorf("emitted %d events for a rejected message, want none", len(events))
+	}
+}
+
+func TestSendMessageDeliversToReceiver(t *testing.T) {
+	database := dbtest.Connect(t)
+	emitter := &sockettest.FakeEmitter{}
+	h := &ChatHandler{Config: &config.Config{}, Emitter: emitter}
+	sender := models.User{ID: primitive.NewObjectID()}
+	receiverID := primitive.NewObjectID()
+
+	c, recorder := sendMessageContext(sender, receiverID, `{"text":"hello"}`)
+	h.SendMessage(c)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body %s", recorder.Code, http.StatusCreated, recorder.Body)
+	}
+
+	messages := emitter.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("emitted %d newMessage events, want 1", len(messages))
+	}
+	if got := messages[0]; got.Text != "hello" || got.SenderID != sender.ID.Hex() || got.ReceiverID != receiverID.Hex() {
+		t.Errorf("newMessage payload = %+v", got)
+	}
+
+	// Every event goes to the receiver only: the message, then their unread counts.
+	events, sentTo := emitter.Events(), emitter.SentTo()
+	if len(events) != len(sentTo) {
+		t.Fatalf("%d events but %d recipient lists", len(events), len(sentTo))
+	}
+	wantEvents := []string{"newMessage", "unreadCounts"}
+	if len(events) != len(wantEvents) {
+		t.Fatalf("emitted %d events, want %v", len(events), wantEvents)
+	}
+	for i, event := range events {
+		if event.Event != wantEvents[i] {
+			t.Errorf("event %d = %q, want %q", i, event.Event, wantEvents[i])
+		}
+		if len(sentTo[i]) != 1 || sentTo[i][0] != receiverID {
+			t.Errorf("event %q sent to %v, want only the receiver", event.Event, sentTo[i])
+		}
+	}
+
+	stored, err := database.Collection("messages").CountDocuments(context.Background(),
+		bson.M{"senderId": sender.ID, "receiverId": receiverID, "text": "hello"})
+	if err != nil {
+		t.Fatalf("counting stored messages: %v", err)
+	}
+	if stored != 1 {
+		t.Errorf("stored %d messages, want 1", stored)
+	}
+}
@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps and the query timeout
+
+	"go-backend/internal/models" // Import models for User, Message and StarredMessage structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For the upsert and sort options
+)
+
+// StarMessage bookmarks a message for the logged-in user. Stars are private
+// and idempotent: starring a message twice keeps the original star.
+func (h *ChatHandler) StarMessage(c *gin.Context) {
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var message models.Message
+	err := db.DB.Collection("messages").FindOne(ctx, bson.M{"_id": messageID, "expiresAt": notExpired()}).Decode(&message)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching message", err)
+		return
+	}
+
+	participant, err := isParticipant(ctx, message, loggedInUser.ID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking conversation", err)
+		return
+	}
+	if !participant {
+		// Same answer as a missing message, so IDs can't be probed.
+		utils.RespondError(c, http.StatusNotFound, utils.ErrCodeMessageNotFound, "Message not found")
+		return
+	}
+	if message.Deleted || message.System {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot star this message")
+		return
+	}
+
+	// Upsert on the unique (userId, messageId) pair: a repeated star matches
+	// the existing document and $setOnInsert leaves its time alone.
+	filter := bson.M{"userId": loggedInUser.ID, "messageId": message.ID}
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var star models.StarredMessage
+	err = db.DB.Collection("starred_messages").FindOneAndUpdate(ctx, filter, update, opts).Decode(&star)
+	if mongo.IsDuplicateKeyError(err) {
+		// Two concurrent stars raced to insert; the other one won, read it back.
+		err = db.DB.Collection("starred_messages").FindOne(ctx, filter).Decode(&star)
+	}
+	if err != nil {
+		utils.RespondInternalError(c, "Error starring message", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messageId": message.ID.Hex(),
+		"starred":   true,
+		"starredAt": star.CreatedAt,
+	})
+}
+
+// UnstarMessage removes the logged-in user's star from a message.
+// Unstarring a message that isn't starred (or no longer exists) is a no-op.
+func (h *ChatHandler) UnstarMessage(c *gin.Context) {
+	messageID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	_, err := db.DB.Collection("starred_messages").DeleteOne(ctx, bson.M{"userId": loggedInUser.ID, "messageId": messageID})
+	if err != nil {
+		utils.RespondInternalError(c, "Error unstarring message", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messageId": messageID.Hex(),
+		"starred":   false,
+	})
+}
+
+// starredMessageResponse is a message in GetStarredMessages: the usual fields plus starredAt.
+type starredMessageResponse struct {
+	utils.MessageResponse
+	StarredAt utils.Timestamp `json:"starredAt"`
+}
+
+// GetStarredMessages lists the logged-in user's starred messages across all
+// conversations, most recently starred first. Each message carries `starredAt`.
+// Supports ?limit (default 50, max MESSAGE_MAX_LIMIT) and ?before=<cursor>,
+// where the cursor is the `nextCursor` of the previous page.
+// Messages from group conversations the user has since left are skipped.
+func (h *ChatHandler) GetStarredMessages(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	limit, ok := utils.ParseLimit(c, defaultMessagesPerPage, h.Config.MessageMaxLimit)
+	if !ok {
+		return
+	}
+
+	// Stars are paginated by their own _id, which increases with star time.
+	filter := bson.M{"userId": loggedInUser.ID}
+	if before := c.Query("before"); before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid before cursor format")
+			return
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1)) // One extra to know whether there are more
+	cursor, err := db.DB.Collection("starred_messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching starred messages", err)
+		return
+	}
+	var stars []models.StarredMessage
+	if err = cursor.All(ctx, &stars); err != nil {
+		utils.RespondInternalError(c, "Error decoding starred messages", err)
+		return
+	}
+
+	hasMore := len(stars) > limit
+	if hasMore {
+		stars = stars[:limit]
+	}
+	var nextCursor interface{} // null on the last page
+	if hasMore {
+		nextCursor = stars[len(stars)-1].ID.Hex()
+	}
+
+	messageIDs := make([]primitive.ObjectID, len(stars))
+	for i, star := range stars {
+		messageIDs[i] = star.MessageID
+	}
+	cursor, err = db.DB.Collection("messages").Find(ctx, bson.M{"_id": bson.M{"$in": messageIDs}, "expiresAt": notExpired()})
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching messages", err)
+		return
+	}
+	var messages []models.Message
+	if err = cursor.All(ctx, &messages); err != nil {
+		utils.RespondInternalError(c, "Error decoding messages", err)
+		return
+	}
+	byID := make(map[primitive.ObjectID]models.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+
+	// Keep the star order. Stars of messages that no longer exist (deleted
+	// accounts, disappearing messages), or that the user can't see any more,
+	// are left out.
+	response := []starredMessageResponse{}
+	for _, star := range stars {
+		msg, found := byID[star.MessageID]
+		if !found {
+			continue
+		}
+		participant, err := isParticipant(ctx, msg, loggedInUser.ID)
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error checking conversation", err)
+			return
+		}
+		if !participant {
+			continue
+		}
+		response = append(response, starredMessageResponse{
+			MessageResponse: messageResponse(msg),
+			StarredAt:       utils.Timestamp(star.CreatedAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   response,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
+}
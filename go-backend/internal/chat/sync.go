@@ -0,0 +1,187 @@
+package chat
+
+import (
+	"net/http" // For HTTP status codes
+	"strconv"  // For parsing sync cursors
+	"strings"  // For splitting sync cursors
+	"time"     // For timestamps and the query timeout
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For error responses and the message response type
+
+	"github.com/gin-gonic/gin"                   // Gin framework
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For find options
+)
+
+// Sync lets offline-first clients catch up on every conversation at once.
+// Messages are returned in order of updatedAt, which every change to a message
+// (edit, delete, reaction, receipt, pin) bumps, so a changed message comes back
+// again and the client simply replaces its copy. Deleted messages come back
+// as tombstones (deleted: true, content removed). Expired disappearing
+// messages are never returned; clients drop them by their own expiresAt.
+
+// defaultSyncPageSize is how many messages SyncMessages returns when no ?limit is given.
+const defaultSyncPageSize = 100
+
+// syncPosition is where a sync page ends: the updatedAt and _id of its last
+// message. updatedAt alone isn't unique, so _id breaks ties.
+type syncPosition struct {
+	updatedAt time.Time
+	id        primitive.ObjectID
+}
+
+// String encodes the position as a cursor: "<updatedAt in Unix ms>_<hex _id>".
+// MongoDB stores dates with millisecond precision, so nothing is lost.
+func (p syncPosition) String() string {
+	return strconv.FormatInt(p.updatedAt.UnixMilli(), 10) + "_" + p.id.Hex()
+}
+
+// parseSyncCursor decodes a cursor produced by syncPosition.String.
+func parseSyncCursor(cursor string) (syncPosition, bool) {
+	millis, idHex, found := strings.Cut(cursor, "_")
+	if !found {
+		return syncPosition{}, false
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return syncPosition{}, false
+	}
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return syncPosition{}, false
+	}
+	return syncPosition{updatedAt: time.UnixMilli(ms), id: id}, true
+}
+
+// SyncMessages returns every message involving the logged-in user (1-to-1 chats
+// and their current groups) created or changed after a point in time, oldest
+// change first, one page at a time.
+//
+// Start with ?since=<RFC 3339 timestamp> (omit it for a full sync), then pass
+// the returned `nextCursor` as ?cursor= until `hasMore` is false. Keep the
+// last nextCursor for the next sync; it is null only when nothing matched, in
+// which case the previous since/cursor is still the right starting point.
+// Supports ?limit (default 100 or MESSAGE_MAX_LIMIT if lower, max MESSAGE_MAX_LIMIT).
+func (h *ChatHandler) SyncMessages(c *gin.Context) {
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+	myID := loggedInUser.ID
+
+	// The default is capped too, in case MESSAGE_MAX_LIMIT is set below it.
+	limit, ok := utils.ParseLimit(c, min(defaultSyncPageSize, h.Config.MessageMaxLimit), h.Config.MessageMaxLimit)
+	if !ok {
+		return
+	}
+
+	// Where to resume: strictly after the cursor's message, or strictly after since.
+	var position bson.M
+	var nextCursor interface{} // null in JSON until a message is returned
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, ok := parseSyncCursor(raw)
+		if !ok {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "Invalid sync cursor")
+			return
+		}
+		position = bson.M{"$or": []bson.M{
+			{"updatedAt": bson.M{"$gt": cursor.updatedAt}},
+			{"updatedAt": cursor.updatedAt, "_id": bson.M{"$gt": cursor.id}},
+		}}
+		nextCursor = raw
+	} else if raw := c.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "since must be an RFC 3339 timestamp, e.g. 2024-05-01T12:00:00.000Z")
+			return
+		}
+		position = bson.M{"updatedAt": bson.M{"$gt": since}}
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Groups the user currently belongs to; messages of groups they left aren't synced.
+	groupIDs, err := db.DB.Collection("conversations").Distinct(ctx, "_id", bson.M{"participants": myID})
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching conversations", err)
+		return
+	}
+
+	involved := bson.M{"$or": []bson.M{
+		{"senderId": myID, "conversationId": bson.M{"$exists": false}},
+		{"receiverId": myID},
+		{"conversationId": bson.M{"$in": groupIDs}},
+	}}
+	clauses := []bson.M{involved}
+	if position != nil {
+		clauses = append(clauses, position)
+	}
+	filter := bson.M{"$and": clauses, "$nor": utils.NotSilencedFor(myID), "expiresAt": notExpired()}
+
+	// Fetch one extra message to learn whether another page exists.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := db.DB.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching messages", err)
+		return
+	}
+	var messages []models.Message
+	if err = cursor.All(ctx, &messages); err != nil { // All closes the cursor
+		utils.RespondInternalError(c, "Error decoding messages", err)
+		return
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		nextCursor = syncPosition{updatedAt: last.UpdatedAt, id: last.ID}.String()
+	}
+
+	response := messageResponses(messages)
+
+	// Read receipts are decided per 1-to-1 peer, so look each one up only once.
+	visible := map[primitive.ObjectID]bool{}
+	for i, msg := range messages {
+		if msg.IsGroupMessage() || msg.SenderID != myID {
+			continue // Only the caller's own 1-to-1 messages carry receipts
+		}
+		receiptsVisible, seen := visible[msg.ReceiverID]
+		if !seen {
+			receiptsVisible, err = readReceiptsVisible(ctx, loggedInUser, msg.ReceiverID)
+			if err != nil && err != mongo.ErrNoDocuments {
+				utils.RespondInternalError(c, "Internal server error fetching read receipt settings", err)
+				return
+			}
+			visible[msg.ReceiverID] = receiptsVisible
+		}
+		if !receiptsVisible {
+			hideReadReceipts(response[i:i+1], messages[i:i+1], myID)
+		}
+	}
+	if h.Signer != nil {
+		// Flag any message whose stored content no longer matches its signature.
+		for i, msg := range messages {
+			response[i].Integrity = h.Signer.Status(msg)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   response,
+		"hasMore":    hasMore,
+		"nextCursor": nextCursor,
+	})
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"context"  // For context with MongoDB operations
+	"log/slog" // For logging errors
+	"net/http" // For HTTP status codes
+
+	"go-backend/internal/models" // Import models for User and Message structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For aggregation pipelines
+)
+
+// unreadCounts returns, for each user who has sent userID 1:1 messages that
+// userID hasn't seen yet, the number of such messages, keyed by the sender's
+// hex ID. Deleted or expired messages and system notices never count. Senders with nothing unread are absent.
+func unreadCounts(ctx context.Context, userID primitive.ObjectID) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"receiverId": userID, "seen": bson.M{"$ne": true}, "system": bson.M{"$ne": true}, "silenced": bson.M{"$ne": true}, "deleted": bson.M{"$ne": true}, "expiresAt": notExpired()}}},
+		{{Key: "$group", Value: bson.M{"_id": "$senderId", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := db.DB.Collection("messages").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		SenderID primitive.ObjectID `bson:"_id"`
+		Count    int                `bson:"count"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(groups))
+	for _, group := range groups {
+		counts[group.SenderID.Hex()] = group.Count
+	}
+	return counts, nil
+}
+
+// pushUnreadCounts sends userID's current unread counts to all of their
+// connected devices as an "unreadCounts" event. Failures are only logged:
+// the counts are a convenience and the client can always refetch them.
+func (h *ChatHandler) pushUnreadCounts(ctx context.Context, userID primitive.ObjectID) {
+	counts, err := unreadCounts(ctx, userID)
+	if err != nil {
+		slog.Error("Error computing unread counts", "user_id", userID.Hex(), "error", err)
+		return
+	}
+	h.Emitter.SendToUser(userID, "unreadCounts", counts)
+}
+
+// GetUnreadCounts returns a map of otherUserId -> number of messages that user
+// sent me which I haven't seen yet. The same map is pushed over WebSocket as
+// an "unreadCounts" event whenever it changes.
+func (h *ChatHandler) GetUnreadCounts(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	counts, err := unreadCounts(ctx, loggedInUser.ID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error fetching unread counts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
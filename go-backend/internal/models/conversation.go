@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Conversation represents a group chat. 1-to-1 chats have no Conversation
+// document: their messages are addressed by SenderID/ReceiverID alone.
+type Conversation struct {
+	// ID is the MongoDB document's primary key, stored on each message as ConversationID.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// Participants are the members of the conversation, admins included.
+	Participants []primitive.ObjectID `bson:"participants"`
+
+	// IsGroup is true for group conversations (currently the only kind stored).
+	IsGroup bool `bson:"isGroup"`
+
+	// Name is the group's display name.
+	Name string `bson:"name"`
+
+	// AdminIDs are the participants allowed to manage the group. The creator is the first admin.
+	AdminIDs []primitive.ObjectID `bson:"adminIds"`
+
+	// CreatedAt is when the group was created.
+	CreatedAt time.Time `bson:"createdAt"`
+
+	// UpdatedAt is bumped on every new message, so lists can show the most active groups first.
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// HasParticipant reports whether userID is a member of the conversation.
+func (c Conversation) HasParticipant(userID primitive.ObjectID) bool {
+	for _, participantID := range c.Participants {
+		if participantID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailVerification is a pending email address confirmation, stored in the
+// "email_verifications" collection. Like PasswordReset, only a hash of the
+// emailed token is kept. A TTL index removes it once ExpiresAt has passed.
+type EmailVerification struct {
+	// ID is the MongoDB document's primary key.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// UserID is the account whose email the token confirms.
+	UserID primitive.ObjectID `bson:"userId"`
+
+	// TokenHash is the hex-encoded SHA-256 of the emailed token.
+	TokenHash string `bson:"tokenHash"`
+
+	// CreatedAt is when the token was issued.
+	CreatedAt time.Time `bson:"createdAt"`
+
+	// ExpiresAt is when the token stops being accepted.
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
@@ -6,7 +6,30 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Message represents the structure of a message document in MongoDB
+// Message priorities accepted by SendMessage.
+const (
+	PriorityNormal = "normal"
+	PriorityUrgent = "urgent"
+)
+
+// Delivery statuses of 1-to-1 messages, stored in Message.Status. Each implies
+// the previous ones: sent (stored), delivered (reached one of the receiver's
+// devices), seen (read by the receiver).
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusSeen      = "seen"
+)
+
+// System message types, stored in Message.SystemType.
+const (
+	SystemTypeMemberJoined = "member_joined" // Members were added to a group
+	SystemTypeMemberLeft   = "member_left"   // A member left a group
+)
+
+// Message represents the structure of a message document in MongoDB.
+// A message belongs either to a 1-to-1 chat (ReceiverID set) or to a group
+// Conversation (ConversationID set); IsGroupMessage tells them apart.
 type Message struct {
 	// ID is the MongoDB document's primary key.
 	ID primitive.ObjectID `bson:"_id,omitempty"`
@@ -17,7 +40,12 @@ type Message struct {
 	// `bson:"senderId"`: Maps this field to the "senderId" in MongoDB.
 	SenderID primitive.ObjectID `bson:"senderId"`
 
-	// ReceiverID refers to the User ID of the receiver.
+	// ConversationID refers to the group Conversation the message was sent to.
+	// Zero for 1-to-1 messages, which use ReceiverID instead.
+	// `bson:"conversationId,omitempty"`: Maps to "conversationId" in MongoDB.
+	ConversationID primitive.ObjectID `bson:"conversationId,omitempty"`
+
+	// ReceiverID refers to the User ID of the receiver. Zero for group messages.
 	// `bson:"receiverId"`: Maps to "receiverId" in MongoDB.
 	ReceiverID primitive.ObjectID `bson:"receiverId"`
 
@@ -29,9 +57,190 @@ type Message struct {
 	// `bson:"image,omitempty"`: Maps to "image". `omitempty` is used as it can be empty.
 	Image string `bson:"image,omitempty"`
 
+	// Cloudinary metadata of Image: the public ID (to delete the asset) and its
+	// pixel dimensions (so clients can lay the image out before it loads).
+	// Empty/zero for text-only messages and for images sent before this was stored.
+	ImagePublicID string `bson:"imagePublicId,omitempty"`
+	ImageWidth    int    `bson:"imageWidth,omitempty"`
+	ImageHeight   int    `bson:"imageHeight,omitempty"`
+
+	// ThumbnailURL is a small version of Image for lists and chat bubbles;
+	// clients load Image itself only when it is opened. Empty for images sent
+	// before thumbnails existed or with THUMBNAIL_SIZE=0.
+	ThumbnailURL string `bson:"thumbnailUrl,omitempty"`
+
+	// Audio is the URL of a voice note. Optional; never set together with Image.
+	// AudioPublicID is its Cloudinary public ID and AudioDuration its length in
+	// seconds, so clients can show it before loading the file.
+	Audio         string  `bson:"audio,omitempty"`
+	AudioPublicID string  `bson:"audioPublicId,omitempty"`
+	AudioDuration float64 `bson:"audioDuration,omitempty"`
+
+	// Priority is "normal" or "urgent". Urgent messages are highlighted by
+	// clients. Older messages have no priority stored and are treated as normal.
+	// `bson:"priority,omitempty"`: Maps to "priority" in MongoDB.
+	Priority string `bson:"priority,omitempty"`
+
+	// System marks an in-chat notice generated by the server ("X left the
+	// group", ...) rather than written by a user. SenderID is the user the
+	// notice is about, but clients must not attribute the text to them.
+	// System messages don't count as unread and can't be replied to.
+	System bool `bson:"system,omitempty"`
+
+	// SystemType says which kind of notice a system message is (see SystemType* constants).
+	SystemType string `bson:"systemType,omitempty"`
+
+	// Forwarded marks a copy of another message made through
+	// POST /api/messages/:id/forward, so clients can label it.
+	Forwarded bool `bson:"forwarded,omitempty"`
+
+	// Flagged marks messages that matched the content filter in "flag" mode,
+	// so moderators can review them. Never returned to clients.
+	Flagged bool `bson:"flagged,omitempty"`
+
+	// Signature is an HMAC of the message content, set at insert when
+	// MESSAGE_SIGNING_KEY is configured, used to detect out-of-band edits.
+	Signature string `bson:"signature,omitempty"`
+
+	// Status is the delivery status of a 1-to-1 message (see Status* constants).
+	// Messages stored before statuses existed have none; use DeliveryStatus()
+	// rather than reading it directly. Not tracked for group messages.
+	// `bson:"status,omitempty"`: Maps to "status" in MongoDB.
+	Status string `bson:"status,omitempty"`
+
+	// DeliveredAt records when the message first reached one of the receiver's
+	// connected devices. Zero until delivered.
+	DeliveredAt time.Time `bson:"deliveredAt,omitempty"`
+
+	// Seen is set once the receiver has read the message (read receipts).
+	// Messages stored before read receipts existed have no `seen` field and count as unseen.
+	Seen bool `bson:"seen"`
+
+	// SeenAt records when the receiver read the message. Zero until seen.
+	SeenAt time.Time `bson:"seenAt,omitempty"`
+
+	// Edited is set once the sender has changed the text after sending, so
+	// clients can render an "edited" label.
+	Edited bool `bson:"edited,omitempty"`
+
+	// EditedAt records the most recent edit. Zero if never edited.
+	EditedAt time.Time `bson:"editedAt,omitempty"`
+
+	// EditHistory keeps the previous versions of the text, oldest first.
+	// Never returned to clients.
+	EditHistory []MessageEdit `bson:"editHistory,omitempty"`
+
+	// ReplyTo is the message this one replies to, if any. It is always in the
+	// same conversation.
+	// `bson:"replyTo,omitempty"`: Maps to "replyTo" in MongoDB.
+	ReplyTo *primitive.ObjectID `bson:"replyTo,omitempty"`
+
+	// ReplyPreview is a snapshot of the replied-to message taken when the reply
+	// was sent, so clients can render the quote without another lookup. Later
+	// edits to the original don't change it, but deleting it redacts the preview
+	// (utils.RedactReplyPreviews).
+	// `bson:"replyPreview,omitempty"`: Maps to "replyPreview" in MongoDB.
+	ReplyPreview *ReplyPreview `bson:"replyPreview,omitempty"`
+
+	// Mentions are the group participants @mentioned in Text, resolved when
+	// the message is sent (or edited), so clients can render them as links.
+	// Always empty for 1-to-1 messages.
+	// `bson:"mentions,omitempty"`: Maps to "mentions" in MongoDB.
+	Mentions []primitive.ObjectID `bson:"mentions,omitempty"`
+
+	// Reactions are the emoji reactions on the message, at most one per user,
+	// in the order they were added.
+	// `bson:"reactions,omitempty"`: Maps to "reactions" in MongoDB.
+	Reactions []Reaction `bson:"reactions,omitempty"`
+
+	// Deleted marks a message its sender removed. The document is kept (with
+	// Text and Image blanked) so the conversation order is preserved and
+	// clients can render a "message deleted" placeholder.
+	Deleted bool `bson:"deleted,omitempty"`
+
+	// DeletedAt records when the message was deleted. Zero if not deleted.
+	DeletedAt time.Time `bson:"deletedAt,omitempty"`
+
+	// Pinned marks a message pinned to the top of its conversation by one of
+	// its participants. Pins are shared: everyone in the conversation sees them.
+	Pinned bool `bson:"pinned,omitempty"`
+
+	// PinnedBy is the participant who pinned the message. Zero if not pinned.
+	PinnedBy primitive.ObjectID `bson:"pinnedBy,omitempty"`
+
+	// PinnedAt records when the message was pinned. Zero if not pinned.
+	PinnedAt time.Time `bson:"pinnedAt,omitempty"`
+
+	// Silenced marks a message sent to a user who silently blocked the sender.
+	// It is hidden from the receiver everywhere (see utils.NotSilencedFor) and
+	// never leaves the server as such: the sender must not learn of the block.
+	Silenced bool `bson:"silenced,omitempty"`
+
+	// ExpiresAt makes a disappearing message: MongoDB's TTL index on this
+	// field removes the document once it has passed. Zero for messages that
+	// don't disappear.
+	ExpiresAt time.Time `bson:"expiresAt,omitempty"`
+
 	// CreatedAt field, automatically added by Mongoose `timestamps: true`.
 	CreatedAt time.Time `bson:"createdAt"`
 
 	// UpdatedAt field, automatically added by Mongoose `timestamps: true`.
 	UpdatedAt time.Time `bson:"updatedAt"`
 }
+
+// ReplyPreview is the quoted part of a replied-to message.
+type ReplyPreview struct {
+	SenderID primitive.ObjectID `bson:"senderId"`
+	Text     string             `bson:"text,omitempty"` // Truncated
+	HasImage bool               `bson:"hasImage,omitempty"`
+	HasAudio bool               `bson:"hasAudio,omitempty"`
+	Deleted  bool               `bson:"deleted,omitempty"` // The quoted message was deleted; its content was removed
+}
+
+// Reaction is one user's emoji reaction to a message.
+type Reaction struct {
+	UserID    primitive.ObjectID `bson:"userId"`
+	Emoji     string             `bson:"emoji"`
+	CreatedAt time.Time          `bson:"createdAt"`
+}
+
+// MessageEdit is one previous version of an edited message's text.
+type MessageEdit struct {
+	Text     string    `bson:"text"`
+	EditedAt time.Time `bson:"editedAt"` // When this version was replaced
+}
+
+// NewSystemMessage builds (but doesn't store) a server-generated notice in the
+// group conversation conversationID, about the user subjectID.
+func NewSystemMessage(conversationID, subjectID primitive.ObjectID, systemType, text string) Message {
+	now := time.Now()
+	return Message{
+		ID:             primitive.NewObjectID(),
+		SenderID:       subjectID,
+		ConversationID: conversationID,
+		Text:           text,
+		System:         true,
+		SystemType:     systemType,
+		Seen:           true, // Nobody needs to "read" a notice
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// DeliveryStatus returns the message's delivery status, deriving it from
+// Seen and DeliveredAt for messages stored before statuses existed.
+func (m Message) DeliveryStatus() string {
+	switch {
+	case m.Seen:
+		return StatusSeen
+	case !m.DeliveredAt.IsZero():
+		return StatusDelivered
+	default:
+		return StatusSent
+	}
+}
+
+// IsGroupMessage reports whether the message was sent to a group Conversation.
+func (m Message) IsGroupMessage() bool {
+	return !m.ConversationID.IsZero()
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset is a pending password reset request, stored in the
+// "password_resets" collection. Only a hash of the emailed token is kept, so a
+// database leak doesn't expose usable tokens. A token is single-use: UsedAt is
+// set when it is redeemed.
+type PasswordReset struct {
+	// ID is the MongoDB document's primary key.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// UserID is the account whose password the token can reset.
+	UserID primitive.ObjectID `bson:"userId"`
+
+	// TokenHash is the hex-encoded SHA-256 of the emailed token.
+	TokenHash string `bson:"tokenHash"`
+
+	// CreatedAt is when the reset was requested.
+	CreatedAt time.Time `bson:"createdAt"`
+
+	// ExpiresAt is when the token stops being accepted.
+	ExpiresAt time.Time `bson:"expiresAt"`
+
+	// UsedAt is when the token was redeemed. Zero while unused.
+	UsedAt time.Time `bson:"usedAt,omitempty"`
+}
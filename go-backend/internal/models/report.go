@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Report reasons a user can pick when reporting a message.
+const (
+	ReportReasonSpam       = "spam"
+	ReportReasonHarassment = "harassment"
+	ReportReasonHate       = "hate"
+	ReportReasonViolence   = "violence"
+	ReportReasonSexual     = "sexual"
+	ReportReasonOther      = "other"
+)
+
+// ValidReportReason reports whether reason is one of the ReportReason constants.
+func ValidReportReason(reason string) bool {
+	switch reason {
+	case ReportReasonSpam, ReportReasonHarassment, ReportReasonHate,
+		ReportReasonViolence, ReportReasonSexual, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// Report is a user's report of an abusive message, stored in the "reports"
+// collection for admins to review. A user reports a message at most once
+// (enforced by a unique index on reporterId + messageId).
+type Report struct {
+	// ID is the MongoDB document's primary key. It also orders the report
+	// list, newest report first.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// ReporterID is the user who filed the report.
+	ReporterID primitive.ObjectID `bson:"reporterId"`
+
+	// MessageID is the reported message.
+	MessageID primitive.ObjectID `bson:"messageId"`
+
+	// SenderID is the author of the reported message, so reports can be
+	// listed per user.
+	SenderID primitive.ObjectID `bson:"senderId"`
+
+	// Reason is one of the ReportReason constants; Details is the reporter's
+	// optional explanation.
+	Reason  string `bson:"reason"`
+	Details string `bson:"details,omitempty"`
+
+	// MessageText, MessageImage and MessageAudio snapshot the message as it
+	// was reported, so moderators still see it after it is edited or deleted.
+	MessageText  string `bson:"messageText,omitempty"`
+	MessageImage string `bson:"messageImage,omitempty"`
+	MessageAudio string `bson:"messageAudio,omitempty"`
+
+	// CreatedAt is when the report was filed.
+	CreatedAt time.Time `bson:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session represents a login that can be extended with a refresh token.
+// Only a hash of the refresh token is stored, so a database leak doesn't hand
+// out live tokens. Deleting the document revokes the refresh token.
+type Session struct {
+	// ID is the MongoDB document's primary key, also embedded in the refresh token.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// UserID is the user this session belongs to.
+	UserID primitive.ObjectID `bson:"userId"`
+
+	// TokenHash is the hex-encoded SHA-256 of the refresh token.
+	TokenHash string `bson:"tokenHash"`
+
+	// CreatedAt is when the user logged in.
+	CreatedAt time.Time `bson:"createdAt"`
+
+	// ExpiresAt is when the refresh token stops being accepted.
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
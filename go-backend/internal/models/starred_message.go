@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StarredMessage is a message a user bookmarked, stored in the
+// "starred_messages" collection. Stars are private: only the user who starred
+// a message ever sees them. A user stars a message at most once (enforced by
+// a unique index on userId + messageId).
+type StarredMessage struct {
+	// ID is the MongoDB document's primary key. It also orders the starred
+	// list, newest star first.
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+
+	// UserID is the user who starred the message.
+	UserID primitive.ObjectID `bson:"userId"`
+
+	// MessageID is the starred message.
+	MessageID primitive.ObjectID `bson:"messageId"`
+
+	// CreatedAt is when the message was starred.
+	CreatedAt time.Time `bson:"createdAt"`
+}
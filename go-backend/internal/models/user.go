@@ -6,6 +6,9 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive" // Required for `primitive.ObjectID` to handle MongoDB's unique identifiers
 )
 
+// MaxBioRunes is the longest bio accepted, in characters.
+const MaxBioRunes = 160
+
 // User represents the structure of a user document in MongoDB.
 // Each field is defined with its Go type and a `bson` tag.
 // The `bson` tag tells the MongoDB Go driver how to map the Go struct field
@@ -28,16 +31,93 @@ type User struct {
 	// `bson:"fullName"`: Maps to "fullName" in MongoDB.
 	FullName string `bson:"fullName"`
 
+	// Bio is the user's short "about" text, shown on their profile and in the
+	// sidebar. At most MaxBioRunes characters; empty when not set.
+	// `bson:"bio,omitempty"`: Maps to "bio" in MongoDB.
+	Bio string `bson:"bio,omitempty"`
+
 	// Password field, required and minlength 6 in your Mongoose schema.
 	// This field will store the hashed password.
 	// `bson:"password"`: Maps to "password" in MongoDB.
 	Password string `bson:"password"`
 
+	// EmailVerified is set once the user has followed the link emailed at
+	// signup (see auth.VerifyEmail). Accounts created before verification
+	// existed have no field and count as unverified.
+	// `bson:"emailVerified"`: Maps to "emailVerified" in MongoDB; missing means false.
+	EmailVerified bool `bson:"emailVerified"`
+
 	// ProfilePic field, optional with a default empty string in Mongoose.
 	// `bson:"profilePic,omitempty"`: Maps to "profilePic". `omitempty` is used
 	//   because it's an optional field and might be an empty string.
 	ProfilePic string `bson:"profilePic,omitempty"`
 
+	// ProfilePicPublicID is the Cloudinary public ID of ProfilePic, used to
+	// delete the old asset when the picture changes. Empty for external URLs.
+	// `bson:"profilePicPublicId,omitempty"`: Maps to "profilePicPublicId" in MongoDB.
+	ProfilePicPublicID string `bson:"profilePicPublicId,omitempty"`
+
+	// ProfilePicThumbnailURL is a small version of ProfilePic for sidebars and
+	// other lists, returned as "thumbnailUrl". Empty for external URLs and
+	// pictures uploaded before thumbnails existed; clients use ProfilePic then.
+	// `bson:"profilePicThumbnailUrl,omitempty"`: Maps to "profilePicThumbnailUrl" in MongoDB.
+	ProfilePicThumbnailURL string `bson:"profilePicThumbnailUrl,omitempty"`
+
+	// IsAdmin grants access to the /api/admin routes. There is no API to set it;
+	// promote a user directly in the database.
+	// `bson:"isAdmin"`: Maps to "isAdmin" in MongoDB; missing means false.
+	IsAdmin bool `bson:"isAdmin"`
+
+	// BannedAt is when an admin banned the user (see admin.BanUser); zero if
+	// they aren't banned. A banned user can't log in and their tokens are
+	// rejected, but the account is kept, so the email can't simply sign up again.
+	// `bson:"bannedAt,omitempty"`: Maps to "bannedAt" in MongoDB.
+	BannedAt time.Time `bson:"bannedAt,omitempty"`
+
+	// BannedBy is the admin who banned the user.
+	// `bson:"bannedBy,omitempty"`: Maps to "bannedBy" in MongoDB.
+	BannedBy primitive.ObjectID `bson:"bannedBy,omitempty"`
+
+	// SendReadReceipts is the user's read-receipt preference. A pointer so that
+	// users created before the setting existed (no field in MongoDB) default to enabled.
+	// Use ReadReceiptsEnabled() rather than reading it directly.
+	// `bson:"sendReadReceipts,omitempty"`: Maps to "sendReadReceipts" in MongoDB.
+	SendReadReceipts *bool `bson:"sendReadReceipts,omitempty"`
+
+	// BlockedUsers lists the users this user has blocked. A block works both
+	// ways: neither side can message the other, and each is hidden from the
+	// other's sidebar. Use utils.BlockExists to check a pair of users.
+	// `bson:"blockedUsers,omitempty"`: Maps to "blockedUsers" in MongoDB.
+	BlockedUsers []primitive.ObjectID `bson:"blockedUsers,omitempty"`
+
+	// SilentBlocks is the subset of BlockedUsers blocked silently: their
+	// 1-to-1 messages to this user still look sent to them, but are stored
+	// without ever being delivered (see ChatHandler.SendMessage).
+	// `bson:"silentBlocks,omitempty"`: Maps to "silentBlocks" in MongoDB.
+	SilentBlocks []primitive.ObjectID `bson:"silentBlocks,omitempty"`
+
+	// Contacts lists the users this user has added as contacts. Unlike a
+	// block, adding a contact is one-sided: the other user isn't asked or told.
+	// The sidebar only shows contacts unless the client asks for everyone.
+	// `bson:"contacts,omitempty"`: Maps to "contacts" in MongoDB.
+	Contacts []primitive.ObjectID `bson:"contacts,omitempty"`
+
+	// LastSeen is when the user's last WebSocket connection closed. Zero for
+	// users who have never connected. Written by the Hub (see utils.RecordLastSeen).
+	// `bson:"lastSeen,omitempty"`: Maps to "lastSeen" in MongoDB.
+	LastSeen time.Time `bson:"lastSeen,omitempty"`
+
+	// HideLastSeen is the user's privacy toggle: when true, LastSeen is never
+	// shown to other users.
+	// `bson:"hideLastSeen"`: Maps to "hideLastSeen" in MongoDB; missing means false.
+	HideLastSeen bool `bson:"hideLastSeen"`
+
+	// TokenVersion is copied into every access token issued to the user.
+	// POST /api/auth/logout-all increments it, so AuthMiddleware rejects every
+	// token issued before then. Missing means 0, the version of older tokens.
+	// `bson:"tokenVersion,omitempty"`: Maps to "tokenVersion" in MongoDB.
+	TokenVersion int `bson:"tokenVersion,omitempty"`
+
 	// CreatedAt field, automatically added by Mongoose `timestamps: true`.
 	// `time.Time` is the Go type for timestamps.
 	// `bson:"createdAt"`: Maps to "createdAt" in MongoDB.
@@ -46,4 +126,15 @@ type User struct {
 	// UpdatedAt field, automatically added by Mongoose `timestamps: true`.
 	// `bson:"updatedAt"`: Maps to "updatedAt" in MongoDB.
 	UpdatedAt time.Time `bson:"updatedAt"`
-}
\ No newline at end of file
+}
+
+// IsBanned reports whether an admin has banned the user.
+func (u User) IsBanned() bool {
+	return !u.BannedAt.IsZero()
+}
+
+// ReadReceiptsEnabled reports whether the user shares (and sees) read receipts.
+// Defaults to true when the preference was never set.
+func (u User) ReadReceiptsEnabled() bool {
+	return u.SendReadReceipts == nil || *u.SendReadReceipts
+}
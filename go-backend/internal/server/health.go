@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"  // For the MongoDB ping timeout
+	"net/http" // For HTTP status codes
+	"time"     // For timeouts
+
+	"go-backend/pkg/db"    // Import db to ping MongoDB
+	"go-backend/pkg/utils" // Import utils for the WebSocket Hub
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/mongo/readpref" // For pinging the primary
+)
+
+// Readiness check timeouts. Probes typically time out after a few seconds, so
+// the checks must answer well within that.
+const (
+	readyMongoTimeout = 2 * time.Second
+	readyHubTimeout   = time.Second
+)
+
+// Health is the liveness probe: it answers as long as the process can serve
+// HTTP, without touching any dependency (a MongoDB outage shouldn't get the
+// container restarted).
+func (s *Server) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready is the readiness probe: 200 when MongoDB answers a ping and the
+// WebSocket Hub's event loop is responsive, 503 otherwise. Cloudinary is only
+// reported (whether credentials are configured), since image uploads failing
+// shouldn't take the whole instance out of rotation.
+func (s *Server) Ready(hub *utils.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ready := true
+
+		mongoStatus := "ok"
+		ctx, cancel := context.WithTimeout(context.Background(), readyMongoTimeout)
+		defer cancel()
+		if db.Client == nil {
+			mongoStatus = "not connected"
+			ready = false
+		} else if err := db.Client.Ping(ctx, readpref.Primary()); err != nil {
+			mongoStatus = "unreachable"
+			ready = false
+		}
+
+		hubStatus := "ok"
+		if !hub.Responsive(readyHubTimeout) {
+			hubStatus = "unresponsive"
+			ready = false
+		}
+
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "not ready", http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{
+			"status":  status,
+			"mongodb": mongoStatus,
+			"websocket": gin.H{
+				"status":      hubStatus,
+				"onlineUsers": hub.OnlineCount(),
+			},
+			"cloudinaryConfigured": s.Config.CloudinaryCloudName != "" && s.Config.CloudinaryAPIKey != "" && s.Config.CloudinaryAPISecret != "",
+		})
+	}
+}
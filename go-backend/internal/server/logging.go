@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/rand"  // For generating request IDs
+	"encoding/hex" // For encoding request IDs
+	"log/slog"     // Structured logging
+	"time"         // For request latency
+
+	"go-backend/pkg/logging" // Per-request logger helpers
+
+	"github.com/gin-gonic/gin" // Gin context for handling HTTP requests and responses
+)
+
+// requestIDHeader carries the request ID in both directions: a proxy may set it
+// on the way in, and it is always echoed back so clients can quote it in bug reports.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming request IDs so clients can't bloat the logs.
+const maxRequestIDLength = 64
+
+// RequestLogger gives every request an ID and a logger carrying it (see
+// logging.FromContext), then writes one access log line per request with the
+// route, status, latency and, for authenticated requests, the user ID.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+		c.Set("requestId", requestID)
+		logging.Set(c, logger.With("request_id", requestID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		// The request logger already carries request_id and, once AuthMiddleware
+		// ran, user_id.
+		logging.FromContext(c).Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"route", route,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// validRequestID accepts IDs of letters, digits, '-' and '_' up to maxRequestIDLength.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns 16 random hex characters.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"      // For formatted error messages
+	"net"      // For parsing and matching networks
+	"net/http" // For HTTP status codes
+	"strconv"  // For the status code label
+	"strings"  // For telling IPs from CIDRs
+	"time"     // For measuring latency
+
+	"go-backend/pkg/metrics" // The latency histogram
+	"go-backend/pkg/utils"   // For the standard error response
+
+	"github.com/gin-gonic/gin" // The Gin web framework
+)
+
+// MetricsMiddleware records every request's latency in the
+// http_request_duration_seconds histogram. Routes are labelled by their
+// template (c.FullPath), so IDs in paths don't create new series. WebSocket
+// upgrades are skipped: their "latency" is the lifetime of the connection.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// parseNetworks parses a comma-separated list of IPs and CIDRs. A bare IP
+// matches only itself.
+func parseNetworks(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range utils.SplitCommaList(list) {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// RestrictToNetworks refuses requests whose client IP (see TRUSTED_PROXIES)
+// isn't in one of the networks with a 403. An empty list allows everyone.
+func RestrictToNetworks(networks []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(networks) == 0 {
+			c.Next()
+			return
+		}
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeForbidden, "Forbidden")
+		c.Abort()
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"          // For buffering the response body before deciding whether to compress it
+	"compress/flate" // For deflate encoding
+	"compress/gzip"  // For gzip encoding
+	"fmt"            // For formatted error messages
+	"io"             // For the common writer interface shared by gzip and flate
+	"net/http"       // For HTTP status codes
+	"strconv"        // For parsing q-values in Accept-Encoding
+	"strings"        // For header parsing
+
+	"go-backend/pkg/utils" // For the standard error response
+
+	"github.com/gin-gonic/gin" // The Gin web framework
+)
+
+// compressWriter buffers everything the handler writes so the middleware can
+// decide, once the full body size is known, whether to compress it.
+// The status code and headers still go through the wrapped gin.ResponseWriter,
+// which only records them until the body is actually written.
+type compressWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// CompressionMiddleware gzip/deflate-encodes API responses whose body is at least
+// minBytes long, honoring the client's Accept-Encoding header.
+// It is meant for the JSON API group only: WebSocket upgrades are skipped, and
+// responses that already carry a Content-Encoding or an already-compressed
+// content type (images, archives, ...) are passed through untouched.
+func CompressionMiddleware(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		// Swap in the buffering writer for the rest of the chain.
+		original := c.Writer
+		cw := &compressWriter{ResponseWriter: original}
+		c.Writer = cw
+		c.Next()
+		c.Writer = original
+
+		body := cw.buf.Bytes()
+		header := original.Header()
+		header.Add("Vary", "Accept-Encoding")
+
+		status := original.Status()
+		if len(body) < minBytes ||
+			status == http.StatusNoContent || status == http.StatusNotModified ||
+			header.Get("Content-Encoding") != "" ||
+			isCompressedContentType(header.Get("Content-Type")) {
+			original.Write(body)
+			return
+		}
+
+		header.Set("Content-Encoding", encoding)
+		header.Del("Content-Length") // The compressed length differs from anything set by the handler
+
+		var zw io.WriteCloser
+		if encoding == "gzip" {
+			zw = gzip.NewWriter(original)
+		} else {
+			// flate.NewWriter only errors on an invalid level, which DefaultCompression is not.
+			zw, _ = flate.NewWriter(original, flate.DefaultCompression)
+		}
+		zw.Write(body)
+		zw.Close()
+	}
+}
+
+// negotiateEncoding picks "gzip" or "deflate" from an Accept-Encoding header,
+// preferring gzip, and returns "" when neither is acceptable (absent or q=0).
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		enabled := true
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				enabled = err == nil && q > 0
+			}
+		}
+		accepted[name] = enabled
+	}
+
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	}
+	return ""
+}
+
+// isCompressedContentType reports whether a body of this type is already
+// compressed, in which case compressing it again only wastes CPU.
+func isCompressedContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range []string{"image/", "video/", "audio/", "application/zip", "application/gzip", "application/x-gzip"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// BodyLimitMiddleware caps the request body at maxBytes (0 = no limit), so an
+// oversized base64 upload is refused before it is read into memory. Bodies
+// that declare a larger Content-Length get a 413 right away; for the others
+// (chunked uploads) reading stops at the limit and the handler's JSON binding
+// fails with an *http.MaxBytesError, which utils.RespondBindError turns into a 413.
+func BodyLimitMiddleware(maxBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > int64(maxBytes) {
+			utils.RespondError(c, http.StatusRequestEntityTooLarge, utils.ErrCodePayloadTooLarge,
+				fmt.Sprintf("Request body must be at most %d bytes", maxBytes))
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(maxBytes))
+		c.Next()
+	}
+}
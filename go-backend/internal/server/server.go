@@ -1,14 +1,21 @@
 package server
 
 import (
+	"context"  // For the shutdown deadline
+	"errors"   // For recognizing http.ErrServerClosed
 	"fmt"      // For formatted output (e.g., server start message)
-	"log"      // For logging errors
-	//"net/http" // For HTTP status codes and constants (e.g., http.StatusUnauthorized)
+	"log"      // For fatal configuration errors
+	"log/slog" // Structured logging
+	"net/http" // For the underlying HTTP server
+	"os"       // For exiting when the server fails
 	"time"     // For time-related operations (e.g., MaxAge duration)
 
 	"go-backend/config" // Import your config package for application settings
+	"go-backend/internal/admin" // Import admin package for admin-only handlers
 	"go-backend/internal/auth" // Import auth package for handlers and middleware
 	"go-backend/internal/chat" // Import chat package for handlers
+	"go-backend/internal/users" // Import users package for blocking and other per-user handlers
+	"go-backend/pkg/metrics" // Prometheus metrics handler
 	"go-backend/pkg/utils" // Import utils for CloudinaryService and Hub
 
 	"github.com/gin-contrib/cors" // Gin middleware for CORS
@@ -20,11 +27,15 @@ import (
 type Server struct {
 	Engine *gin.Engine
 	Config *config.Config
+	Logger *slog.Logger // Base logger; each request gets a child tagged with its request ID
+
+	// httpServer serves Engine; kept so Shutdown can drain in-flight requests.
+	httpServer *http.Server
 }
 
 // NewServer creates and initializes a new Gin server instance.
 // It sets up the Gin mode (release/debug) and returns a pointer to the Server struct.
-func NewServer(cfg *config.Config) *Server {
+func NewServer(cfg *config.Config, logger *slog.Logger) *Server {
 	// Set Gin mode based on NodeEnv from config.
 	// In production, Gin runs in release mode, which disables debug output.
 	if cfg.NodeEnv == "production" {
@@ -33,25 +44,52 @@ func NewServer(cfg *config.Config) *Server {
 		gin.SetMode(gin.DebugMode)
 	}
 
-	// Initialize the Gin default engine.
-	// Default includes Logger and Recovery middleware.
-	engine := gin.Default()
+	// Initialize the Gin engine with Recovery, plus our structured request
+	// logging in place of Gin's plain-text Logger and the latency histogram.
+	engine := gin.New()
+	engine.Use(gin.Recovery(), RequestLogger(logger), MetricsMiddleware())
+
+	// Only honor X-Forwarded-For from configured proxies; otherwise anyone could
+	// spoof c.ClientIP() and sidestep per-IP limits. No proxies configured means
+	// the TCP peer address is used.
+	if err := engine.SetTrustedProxies(utils.SplitCommaList(cfg.TrustedProxies)); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Fail fast on a bad cookie SameSite mode rather than at the first login.
+	if _, err := utils.ParseSameSite(cfg.CookieSameSite); err != nil {
+		log.Fatalf("Invalid COOKIE_SAMESITE: %v", err)
+	}
+
+	port := cfg.Port
+	if port == "" {
+		port = "5000" // Default port if not set in config
+	}
 
 	return &Server{
 		Engine: engine,
 		Config: cfg,
+		Logger: logger,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf(":%s", port),
+			Handler: engine,
+		},
 	}
 }
 
 // SetupRoutes configures all API endpoints and applies middleware.
 // MODIFIED: Accepts the WebSocket Hub instance.
 func (s *Server) SetupRoutes(hub *utils.Hub) {
+	// The same origin list drives CORS, the WebSocket upgrader (see NewHub) and
+	// RequireOrigin, so they can't drift apart.
+	allowedOrigins := utils.SplitCommaList(s.Config.AllowedOrigins)
+
 	// Configure CORS middleware.
 	s.Engine.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
+		AllowOriginFunc:  func(origin string) bool { return utils.OriginAllowed(allowedOrigins, origin) },
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", requestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -60,42 +98,162 @@ func (s *Server) SetupRoutes(hub *utils.Hub) {
 	cloudinaryService := utils.NewCloudinaryService(s.Config)
 
 	// Initialize authentication and chat handlers.
-	authHandler := auth.NewAuthHandler(s.Config, cloudinaryService)
-	chatHandler := chat.NewChatHandler(cloudinaryService)
+	mailer := utils.NewLogMailer()
+	authHandler := auth.NewAuthHandler(s.Config, cloudinaryService, mailer, hub)
+	contentFilter := utils.NewContentFilter(s.Config)
+	messageSigner := utils.NewMessageSigner(s.Config)
+	adminHandler := admin.NewAdminHandler(hub)
+	userHandler := users.NewUserHandler(s.Config)
+	chatHandler := chat.NewChatHandler(s.Config, cloudinaryService, hub, hub, contentFilter, messageSigner, mailer)
+
+	// Email availability checks are cheap to abuse for account enumeration,
+	// so they get a tight per-IP budget.
+	emailCheckLimiter := auth.NewRateLimiter(10, time.Minute)
+	// Login failures lock out the client IP and the targeted email with
+	// exponential backoff; signups are capped per IP.
+	loginThrottle := auth.NewLoginThrottle(s.Config.LoginMaxFailures, s.Config.LoginFailureWindow)
+	signupLimiter := auth.NewRateLimiter(s.Config.SignupRateLimit, s.Config.SignupRateWindow)
+	// Password reset and email verification requests send email, so they get
+	// an even tighter budget.
+	passwordResetLimiter := auth.NewRateLimiter(5, time.Minute)
+	// Routes that accept base64 uploads refuse huge bodies before reading them.
+	bodyLimit := BodyLimitMiddleware(s.Config.MaxRequestBodyBytes)
 
 	// Group API routes under "/api".
 	api := s.Engine.Group("/api")
+	// Compress large JSON responses (e.g. long conversation histories).
+	// Applied to the API group only, so the WebSocket route and static files are unaffected.
+	if s.Config.CompressionEnabled {
+		api.Use(CompressionMiddleware(s.Config.CompressionMinBytes))
+	}
 	{
 		// Authentication Routes (no protection needed for signup/login)
 		authRoutes := api.Group("/auth")
 		{
-			authRoutes.POST("/signup", authHandler.Signup)
-			authRoutes.POST("/login", authHandler.Login)
+			authRoutes.POST("/signup", auth.RateLimitMiddleware(signupLimiter), authHandler.Signup)
+			authRoutes.POST("/login", auth.LoginThrottleMiddleware(loginThrottle), authHandler.Login)
 			authRoutes.POST("/logout", authHandler.Logout)
+			authRoutes.POST("/refresh", authHandler.Refresh)
+			authRoutes.POST("/forgot-password", auth.RateLimitMiddleware(passwordResetLimiter), authHandler.ForgotPassword)
+			authRoutes.POST("/reset-password", auth.RateLimitMiddleware(passwordResetLimiter), authHandler.ResetPassword)
+			authRoutes.GET("/verify-email", auth.RateLimitMiddleware(passwordResetLimiter), authHandler.VerifyEmail)
+			authRoutes.POST("/resend-verification", auth.RateLimitMiddleware(passwordResetLimiter), authHandler.ResendVerification)
+			authRoutes.GET("/email-available",
+				auth.RequireOrigin(allowedOrigins...),
+				auth.RateLimitMiddleware(emailCheckLimiter),
+				authHandler.EmailAvailable)
 
 			// Protected Auth Routes (require authentication middleware)
 			protectedAuthRoutes := authRoutes.Group("/")
 			protectedAuthRoutes.Use(auth.AuthMiddleware(s.Config))
 			{
-				protectedAuthRoutes.PUT("/update-profile", authHandler.UpdateProfile)
+				protectedAuthRoutes.PUT("/update-profile", bodyLimit, authHandler.UpdateProfile)
+				protectedAuthRoutes.PATCH("/profile", bodyLimit, authHandler.UpdateAccount)
 				protectedAuthRoutes.GET("/check", authHandler.CheckAuth)
+				protectedAuthRoutes.PUT("/preferences", authHandler.UpdatePreferences)
+				protectedAuthRoutes.PUT("/change-password", authHandler.ChangePassword)
+				protectedAuthRoutes.POST("/logout-all", authHandler.LogoutAll)
+				protectedAuthRoutes.DELETE("/account", authHandler.DeleteAccount)
 			}
 		}
 
+		// Group Conversation Routes (all protected)
+		conversationRoutes := api.Group("/conversations")
+		conversationRoutes.Use(auth.AuthMiddleware(s.Config))
+		{
+			conversationRoutes.POST("", chatHandler.CreateGroup)
+			conversationRoutes.GET("", chatHandler.GetConversations)
+			conversationRoutes.GET("/:id/messages", chatHandler.GetConversationMessages)
+			conversationRoutes.GET("/:id/pinned", chatHandler.GetConversationPinnedMessages)
+			conversationRoutes.POST("/:id/messages", bodyLimit, chatHandler.SendConversationMessage)
+		}
+
+		// User Routes (all protected)
+		userRoutes := api.Group("/users")
+		userRoutes.Use(auth.AuthMiddleware(s.Config))
+		{
+			userRoutes.GET("/search", userHandler.SearchUsers)
+			userRoutes.GET("/:id", userHandler.GetUserProfile)
+			userRoutes.POST("/:id/block", userHandler.BlockUser)
+			userRoutes.POST("/:id/unblock", userHandler.UnblockUser)
+		}
+
+		// Contact Routes (all protected)
+		contactRoutes := api.Group("/contacts")
+		contactRoutes.Use(auth.AuthMiddleware(s.Config))
+		contactIDParam := utils.ValidateObjectIDParam("id", "user")
+		{
+			contactRoutes.POST("/:id", contactIDParam, userHandler.AddContact)
+			contactRoutes.DELETE("/:id", contactIDParam, userHandler.RemoveContact)
+		}
+
 		// Message Routes (all protected)
 		messageRoutes := api.Group("/messages")
 		messageRoutes.Use(auth.AuthMiddleware(s.Config))
+		// Parse :id once, before the handler, so a malformed ID always gets the
+		// same 400. What :id refers to depends on the route.
+		messageIDParam := utils.ValidateObjectIDParam("id", "message")
+		userIDParam := utils.ValidateObjectIDParam("id", "user")
+		receiverIDParam := utils.ValidateObjectIDParam("id", "receiver")
 		{
 			messageRoutes.GET("/users", chatHandler.GetUsersForSidebar)
-			messageRoutes.GET("/:id", chatHandler.GetMessages)
-			messageRoutes.POST("/send/:id", chatHandler.SendMessage)
+			messageRoutes.GET("/unseen-senders", chatHandler.GetUnseenSenders)
+			messageRoutes.GET("/unread-counts", chatHandler.GetUnreadCounts)
+			messageRoutes.GET("/starred", chatHandler.GetStarredMessages)
+			messageRoutes.GET("/sync", chatHandler.SyncMessages)
+			messageRoutes.POST("/batch", chatHandler.GetMessagesBatch)
+			messageRoutes.GET("/message/:id", messageIDParam, chatHandler.GetMessage)
+			messageRoutes.GET("/:id", receiverIDParam, chatHandler.GetMessages)
+			messageRoutes.GET("/:id/search", userIDParam, chatHandler.SearchMessages)
+			messageRoutes.POST("/send/:id", receiverIDParam, bodyLimit, chatHandler.SendMessage)
+			messageRoutes.PUT("/:id", messageIDParam, chatHandler.EditMessage)
+			messageRoutes.DELETE("/:id", messageIDParam, chatHandler.DeleteMessage)
+			messageRoutes.POST("/:id/forward", messageIDParam, chatHandler.ForwardMessage)
+			messageRoutes.POST("/:id/seen", userIDParam, chatHandler.MarkConversationSeen)
+			messageRoutes.POST("/:id/seen-single", messageIDParam, chatHandler.MarkMessageSeen)
+			messageRoutes.POST("/:id/react", messageIDParam, chatHandler.ReactToMessage)
+			messageRoutes.DELETE("/:id/react", messageIDParam, chatHandler.RemoveReaction)
+			messageRoutes.POST("/:id/star", messageIDParam, chatHandler.StarMessage)
+			messageRoutes.DELETE("/:id/star", messageIDParam, chatHandler.UnstarMessage)
+			messageRoutes.POST("/:id/report", messageIDParam, chatHandler.ReportMessage)
+			messageRoutes.POST("/:id/pin", messageIDParam, chatHandler.PinMessage)
+			messageRoutes.DELETE("/:id/pin", messageIDParam, chatHandler.UnpinMessage)
+			messageRoutes.GET("/:id/pinned", userIDParam, chatHandler.GetPinnedMessages)
+		}
+
+		// Admin Routes (authenticated users with isAdmin set)
+		adminRoutes := api.Group("/admin")
+		adminRoutes.Use(auth.AuthMiddleware(s.Config), auth.AdminMiddleware())
+		{
+			targetUserIDParam := utils.ValidateObjectIDParam("id", "user")
+			adminRoutes.GET("/stats", adminHandler.GetStats)
+			adminRoutes.GET("/users", adminHandler.ListUsers)
+			adminRoutes.DELETE("/users/:id", targetUserIDParam, adminHandler.BanUser)
+			adminRoutes.POST("/users/:id/unban", targetUserIDParam, adminHandler.UnbanUser)
+			adminRoutes.GET("/reports", adminHandler.ListReports)
+			adminRoutes.GET("/messages/verify", chatHandler.VerifyConversation)
 		}
 	}
 
 	// WebSocket Route
 	// This route will handle upgrading the HTTP connection to a WebSocket.
-	// It uses the AuthMiddleware to ensure only authenticated users can establish a WebSocket connection.
-	s.Engine.GET("/ws", auth.AuthMiddleware(s.Config), func(c *gin.Context) {
+	// It uses the WebSocketAuthMiddleware to ensure only authenticated users can establish a
+	// WebSocket connection (like AuthMiddleware, plus a ?token= fallback).
+	// Liveness/readiness probes for container orchestration. Unauthenticated,
+	// and outside /api so compression and auth never apply.
+	s.Engine.GET("/health", s.Health)
+	s.Engine.GET("/ready", s.Ready(hub))
+
+	// Prometheus metrics, also outside /api, limited to the scrapers' networks.
+	if s.Config.MetricsEnabled {
+		metricsNetworks, err := parseNetworks(s.Config.MetricsAllowedNetworks)
+		if err != nil {
+			log.Fatalf("Invalid METRICS_ALLOWED_NETWORKS: %v", err)
+		}
+		s.Engine.GET("/metrics", RestrictToNetworks(metricsNetworks), gin.WrapH(metrics.Handler()))
+	}
+
+	s.Engine.GET("/ws", auth.WebSocketAuthMiddleware(s.Config), func(c *gin.Context) {
 		utils.WebSocketHandler(c, hub) // Pass the hub to the WebSocket handler
 	})
 
@@ -109,12 +267,18 @@ func (s *Server) SetupRoutes(hub *utils.Hub) {
 	}
 }
 
-// Run starts the Gin HTTP server.
+// Run starts the HTTP server and blocks until it fails or Shutdown is called.
 func (s *Server) Run() {
-	port := s.Config.Port
-	if port == "" {
-		port = "5000" // Default port if not set in config
+	s.Logger.Info("Server is running", "addr", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.Logger.Error("Server stopped", "error", err)
+		os.Exit(1)
 	}
-	log.Printf("Server is running on PORT: %s", port)
-	log.Fatal(s.Engine.Run(fmt.Sprintf(":%s", port)))
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests
+// to finish, or for ctx to be done. WebSocket connections are hijacked from
+// the HTTP server and are not waited for; close them with Hub.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
 }
\ No newline at end of file
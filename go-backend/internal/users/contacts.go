@@ -0,0 +1,117 @@
+package users
+
+import (
+	"net/http" // For HTTP status codes
+	"time"     // For timestamps and the query timeout
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response and block checks
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+)
+
+// AddContact adds the user in the URL to the logged-in user's contacts.
+// Adding someone twice is not an error. The response says whether they have
+// added us back ("mutual"), which matters when CONTACTS_REQUIRE_MUTUAL is set.
+func (h *UserHandler) AddContact(c *gin.Context) {
+	targetID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	if targetID == loggedInUser.ID {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot add yourself as a contact")
+		return
+	}
+
+	usersCollection := db.DB.Collection("users")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// Make sure the target exists, so contact lists don't collect dangling IDs.
+	var target models.User
+	if err := usersCollection.FindOne(ctx, bson.M{"_id": targetID}).Decode(&target); err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "User not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching user", err)
+		return
+	}
+
+	// A blocked user would be hidden from the sidebar anyway; say so instead.
+	blocked, err := utils.BlockExists(ctx, loggedInUser.ID, targetID)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error checking blocks", err)
+		return
+	}
+	if blocked {
+		utils.RespondError(c, http.StatusForbidden, utils.ErrCodeUserBlocked, "You cannot add this user as a contact")
+		return
+	}
+
+	update := bson.M{
+		"$addToSet": bson.M{"contacts": targetID},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}
+	if _, err := usersCollection.UpdateByID(ctx, loggedInUser.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error updating contact list", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":  targetID.Hex(),
+		"contact": true,
+		"mutual":  hasContact(target, loggedInUser.ID),
+	})
+}
+
+// RemoveContact removes the user in the URL from the logged-in user's contacts.
+// Removing someone who isn't a contact is a no-op.
+func (h *UserHandler) RemoveContact(c *gin.Context) {
+	targetID := utils.ObjectIDParam(c, "id")
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	update := bson.M{
+		"$pull": bson.M{"contacts": targetID},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	if _, err := db.DB.Collection("users").UpdateByID(ctx, loggedInUser.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error updating contact list", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":  targetID.Hex(),
+		"contact": false,
+	})
+}
+
+// hasContact reports whether user has added id as a contact.
+func hasContact(user models.User, id primitive.ObjectID) bool {
+	for _, contact := range user.Contacts {
+		if contact == id {
+			return true
+		}
+	}
+	return false
+}
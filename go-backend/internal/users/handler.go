@@ -0,0 +1,112 @@
+package users
+
+import (
+	"io"       // For telling an empty body apart from a malformed one
+	"net/http" // For HTTP status codes
+	"time"     // For handling timestamps
+
+	"go-backend/config"          // Import config for application settings
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For the standard error response
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// UserHandler holds dependencies for operations on other users (profiles, blocking, ...).
+// All of its routes are mounted behind AuthMiddleware.
+type UserHandler struct {
+	Config *config.Config
+}
+
+// NewUserHandler creates a new instance of UserHandler.
+func NewUserHandler(cfg *config.Config) *UserHandler {
+	return &UserHandler{Config: cfg}
+}
+
+// BlockRequest is the optional body of POST /api/users/:id/block.
+type BlockRequest struct {
+	// Silent hides the block from the blocked user: their messages look sent
+	// but are never delivered, instead of being refused with a 403.
+	Silent bool `json:"silent"`
+}
+
+// BlockUser adds the user in the URL to the logged-in user's block list.
+// Blocking is idempotent: blocking someone twice is not an error, and blocking
+// again with a different `silent` just switches the kind of block.
+func (h *UserHandler) BlockUser(c *gin.Context) {
+	var req BlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		utils.RespondBindError(c, err, utils.ErrCodeInvalidRequestBody, "Invalid request body format")
+		return
+	}
+	h.setBlocked(c, true, req.Silent)
+}
+
+// UnblockUser removes the user in the URL from the logged-in user's block list.
+// Unblocking someone who isn't blocked is a no-op.
+func (h *UserHandler) UnblockUser(c *gin.Context) {
+	h.setBlocked(c, false, false)
+}
+
+// setBlocked implements BlockUser and UnblockUser.
+func (h *UserHandler) setBlocked(c *gin.Context, blocked, silent bool) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	if targetID == loggedInUser.ID {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "You cannot block yourself")
+		return
+	}
+
+	usersCollection := db.DB.Collection("users")
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var update bson.M
+	if blocked {
+		// Make sure the target exists, so block lists don't collect dangling IDs.
+		count, err := usersCollection.CountDocuments(ctx, bson.M{"_id": targetID})
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error fetching user", err)
+			return
+		}
+		if count == 0 {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "User not found")
+			return
+		}
+		update = bson.M{"$addToSet": bson.M{"blockedUsers": targetID}}
+		if silent {
+			update["$addToSet"].(bson.M)["silentBlocks"] = targetID
+		} else {
+			update["$pull"] = bson.M{"silentBlocks": targetID}
+		}
+	} else {
+		update = bson.M{"$pull": bson.M{"blockedUsers": targetID, "silentBlocks": targetID}}
+	}
+	update["$set"] = bson.M{"updatedAt": time.Now()}
+
+	if _, err := usersCollection.UpdateByID(ctx, loggedInUser.ID, update); err != nil {
+		utils.RespondInternalError(c, "Error updating block list", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":  targetID.Hex(),
+		"blocked": blocked,
+		"silent":  silent,
+	})
+}
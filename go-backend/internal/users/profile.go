@@ -0,0 +1,90 @@
+package users
+
+import (
+	"net/http" // For HTTP status codes
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For BlockExists
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projections
+)
+
+// publicProfileProjection selects the fields GetUserProfile needs; the
+// password hash and private settings never leave the database.
+var publicProfileProjection = bson.M{
+	"fullName":               1,
+	"bio":                    1,
+	"email":                  1,
+	"profilePic":             1,
+	"profilePicThumbnailUrl": 1,
+	"createdAt":              1,
+	"lastSeen":               1,
+	"hideLastSeen":           1,
+}
+
+// GetUserProfile returns the public profile of the user in the URL, so a chat
+// can show who it's with without loading the whole sidebar.
+// Users who have blocked each other get a 404, as they're hidden from each other's sidebar too.
+func (h *UserHandler) GetUserProfile(c *gin.Context) {
+	targetID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid user ID format")
+		return
+	}
+
+	// Get the authenticated user from the context
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	var user models.User
+	err = db.DB.Collection("users").FindOne(ctx, bson.M{"_id": targetID},
+		options.FindOne().SetProjection(publicProfileProjection)).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "User not found")
+			return
+		}
+		utils.RespondInternalError(c, "Internal server error fetching user", err)
+		return
+	}
+
+	if targetID != loggedInUser.ID {
+		blocked, err := utils.BlockExists(ctx, loggedInUser.ID, targetID)
+		if err != nil {
+			utils.RespondInternalError(c, "Internal server error checking blocks", err)
+			return
+		}
+		if blocked {
+			utils.RespondError(c, http.StatusNotFound, utils.ErrCodeUserNotFound, "User not found")
+			return
+		}
+	}
+
+	var lastSeen interface{} // null when hidden or never connected
+	if !user.HideLastSeen && !user.LastSeen.IsZero() {
+		lastSeen = user.LastSeen
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":          user.ID.Hex(),
+		"fullName":     user.FullName,
+		"bio":          user.Bio,
+		"email":        user.Email,
+		"profilePic":   user.ProfilePic,
+		"thumbnailUrl": user.ProfilePicThumbnailURL,
+		"createdAt":    user.CreatedAt,
+		"lastSeen":     lastSeen,
+	})
+}
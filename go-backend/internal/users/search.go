@@ -0,0 +1,117 @@
+package users
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"regexp"   // For escaping the search term
+	"strings"  // For trimming the search term
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+	"go-backend/pkg/utils"       // For pagination query params
+
+	"github.com/gin-gonic/gin"                   // Gin context for handling requests
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For MongoDB find options (e.g., sort)
+)
+
+// User search paging and input bounds. The maximum page size is SEARCH_MAX_LIMIT from config.
+const (
+	defaultUserSearchResults = 20
+	maxUserSearchQueryLength = 100
+)
+
+// SearchUsers finds users whose name or email contains ?q (case-insensitive),
+// for a "find people" box. The logged-in user and users on either side of a
+// block are excluded. Results are sorted by name and paged with ?limit
+// (default 20, max SEARCH_MAX_LIMIT) and ?page; no matches is an empty list.
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	userAny, exists := c.Get("user")
+	if !exists {
+		utils.RespondError(c, http.StatusInternalServerError, utils.ErrCodeInternal, "Authenticated user not found in context")
+		return
+	}
+	loggedInUser := userAny.(models.User)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, "q is required")
+		return
+	}
+	if len(query) > maxUserSearchQueryLength {
+		utils.RespondError(c, http.StatusBadRequest, utils.ErrCodeValidation, fmt.Sprintf("q must be at most %d characters", maxUserSearchQueryLength))
+		return
+	}
+
+	limit, ok := utils.ParseLimit(c, defaultUserSearchResults, h.Config.SearchMaxLimit)
+	if !ok {
+		return
+	}
+	page, ok := utils.ParsePage(c)
+	if !ok {
+		return
+	}
+
+	blocked := loggedInUser.BlockedUsers
+	if blocked == nil {
+		blocked = []primitive.ObjectID{} // $nin needs an array
+	}
+
+	// The term is matched literally: QuoteMeta stops users from sending regex
+	// syntax (and expensive patterns) to the database.
+	pattern := bson.M{"$regex": regexp.QuoteMeta(query), "$options": "i"}
+	filter := bson.M{
+		"_id":          bson.M{"$ne": loggedInUser.ID, "$nin": blocked},
+		"blockedUsers": bson.M{"$ne": loggedInUser.ID},
+		"bannedAt":     bson.M{"$exists": false},
+		"$or": []bson.M{
+			{"fullName": pattern},
+			{"email": pattern},
+		},
+	}
+
+	ctx, cancel := utils.RequestContext(c)
+	defer cancel()
+
+	// One extra result tells us whether there is another page.
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "fullName", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit + 1)).
+		SetProjection(bson.M{"fullName": 1, "email": 1, "profilePic": 1, "profilePicThumbnailUrl": 1})
+
+	cursor, err := db.DB.Collection("users").Find(ctx, filter, findOptions)
+	if err != nil {
+		utils.RespondInternalError(c, "Internal server error searching users", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		utils.RespondInternalError(c, "Error decoding users", err)
+		return
+	}
+
+	hasMore := len(users) > limit
+	if hasMore {
+		users = users[:limit]
+	}
+	response := make([]gin.H, len(users))
+	for i, user := range users {
+		response[i] = gin.H{
+			"_id":          user.ID.Hex(),
+			"fullName":     user.FullName,
+			"email":        user.Email,
+			"profilePic":   user.ProfilePic,
+			"thumbnailUrl": user.ProfilePicThumbnailURL,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":   response,
+		"page":    page,
+		"hasMore": hasMore,
+	})
+}
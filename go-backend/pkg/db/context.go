@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context" // For operation deadlines
+	"time"    // For the default timeout
+)
+
+// operationTimeout bounds each database operation. ConnectDB sets it from
+// DB_TIMEOUT; the default only applies before that (e.g. in tools).
+var operationTimeout = 5 * time.Second
+
+// scanTimeout bounds operations that read a whole conversation rather than a
+// page of it. ConnectDB sets it from DB_SCAN_TIMEOUT.
+var scanTimeout = 30 * time.Second
+
+// Context returns a context for database work that isn't tied to a request,
+// such as WebSocket events and background jobs, with the configured timeout.
+func Context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), operationTimeout)
+}
+
+// ContextFrom is like Context, but also ends when parent does. Handlers use it
+// (through utils.RequestContext) so that queries stop once the client is gone.
+func ContextFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, operationTimeout)
+}
+
+// ScanContextFrom is like ContextFrom, with the longer DB_SCAN_TIMEOUT
+// deadline for full-conversation scans.
+func ScanContextFrom(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, scanTimeout)
+}
@@ -0,0 +1,54 @@
+// Package dbtest provides a MongoDB harness for integration tests. Tests that
+// call Connect are skipped unless MONGO_URI points at a running server.
+package dbtest
+
+import (
+	"context" // For connection and cleanup deadlines
+	"fmt"     // For naming the throwaway database
+	"os"      // For reading MONGO_URI
+	"testing" // For skipping and cleanup
+	"time"    // For timeouts
+
+	"go-backend/pkg/db" // The package-level Client and DB the handlers use
+
+	"go.mongodb.org/mongo-driver/mongo"         // The MongoDB driver
+	"go.mongodb.org/mongo-driver/mongo/options" // For applying the URI
+)
+
+// Connect points db.Client and db.DB at a fresh database on the server in
+// MONGO_URI, and drops it (restoring the previous globals) when the test ends.
+// The test is skipped when MONGO_URI is unset.
+func Connect(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI not set; skipping MongoDB integration test")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to MongoDB: %v", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		t.Fatalf("pinging MongoDB: %v", err)
+	}
+
+	// One database per test, so parallel packages don't see each other's data.
+	database := client.Database(fmt.Sprintf("chat-test-%d", time.Now().UnixNano()))
+	prevClient, prevDB := db.Client, db.DB
+	db.Client, db.DB = client, database
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := database.Drop(ctx); err != nil {
+			t.Logf("dropping test database: %v", err)
+		}
+		client.Disconnect(ctx)
+		db.Client, db.DB = prevClient, prevDB
+	})
+	return database
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context" // For the migration timeout
+	"fmt"     // For reporting collisions
+	"strings" // For normalizing and listing emails
+	"time"    // For the timeout
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projecting the email
+)
+
+// NormalizeEmails lowercases (and trims) stored emails that predate email
+// normalization. Logins look users up by the normalized email and the
+// email_unique index is case-sensitive, so such accounts could neither log in
+// nor stop a second signup with the same address. Call it once after
+// ConnectDB, before EnsureIndexes.
+//
+// If two accounts would end up with the same email, nothing is changed and an
+// error listing them is returned: which account keeps the address is for an
+// operator to decide.
+func NormalizeEmails() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	users := DB.Collection("users")
+	cursor, err := users.Find(ctx,
+		bson.M{"email": bson.M{"$regex": `[A-Z]|^\s|\s$`}},
+		options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return fmt.Errorf("finding emails to normalize: %w", err)
+	}
+	var stale []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Email string             `bson:"email"`
+	}
+	if err := cursor.All(ctx, &stale); err != nil {
+		return fmt.Errorf("decoding emails to normalize: %w", err)
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// Check every collision before touching anything: against accounts that
+	// are already normalized, and between the stale accounts themselves.
+	claimed := make(map[string]string, len(stale)) // Normalized email -> original
+	var collisions []string
+	for _, user := range stale {
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		if other, ok := claimed[email]; ok {
+			collisions = append(collisions, fmt.Sprintf("%q and %q", other, user.Email))
+			continue
+		}
+		claimed[email] = user.Email
+		count, err := users.CountDocuments(ctx, bson.M{"email": email, "_id": bson.M{"$ne": user.ID}})
+		if err != nil {
+			return fmt.Errorf("checking email %q: %w", user.Email, err)
+		}
+		if count > 0 {
+			collisions = append(collisions, fmt.Sprintf("%q and %q", email, user.Email))
+		}
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("cannot normalize emails, these accounts differ only by case or whitespace: %s",
+			strings.Join(collisions, "; "))
+	}
+
+	for _, user := range stale {
+		email := strings.ToLower(strings.TrimSpace(user.Email))
+		if _, err := users.UpdateByID(ctx, user.ID, bson.M{"$set": bson.M{"email": email}}); err != nil {
+			return fmt.Errorf("normalizing email %q: %w", user.Email, err)
+		}
+	}
+	return nil
+}
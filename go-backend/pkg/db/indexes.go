@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context" // For the index creation timeout
+	"fmt"     // For wrapping errors with the collection name
+	"time"    // For the timeout
+
+	"go.mongodb.org/mongo-driver/bson"          // For index key documents
+	"go.mongodb.org/mongo-driver/mongo"         // For index models
+	"go.mongodb.org/mongo-driver/mongo/options" // For index options
+)
+
+// indexes lists the indexes the application relies on, per collection.
+// CreateMany is a no-op for indexes that already exist with the same definition,
+// so this is safe to run on every startup.
+var indexes = map[string][]mongo.IndexModel{
+	"users": {
+		// Login/signup look users up by email. Unique, so the database (not a
+		// racy existence check) is what prevents duplicate accounts.
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+	},
+	"messages": {
+		// One index per direction of a 1-to-1 conversation: GetMessages' $or
+		// uses both, and receiverId-first also serves unread/unseen lookups.
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "receiverId", Value: 1}, {Key: "createdAt", Value: 1}}, Options: options.Index().SetName("sender_receiver_createdAt")},
+		{Keys: bson.D{{Key: "receiverId", Value: 1}, {Key: "senderId", Value: 1}, {Key: "createdAt", Value: 1}}, Options: options.Index().SetName("receiver_sender_createdAt")},
+		// Group conversation history, paginated by _id.
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("conversation_id").SetSparse(true)},
+		// /api/messages/sync: one index per branch of its $or, each ordered by updatedAt.
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "updatedAt", Value: 1}}, Options: options.Index().SetName("senderId_updatedAt")},
+		{Keys: bson.D{{Key: "receiverId", Value: 1}, {Key: "updatedAt", Value: 1}}, Options: options.Index().SetName("receiverId_updatedAt")},
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "updatedAt", Value: 1}}, Options: options.Index().SetName("conversationId_updatedAt").SetSparse(true)},
+		// Let MongoDB delete disappearing messages once they expire. Messages
+		// without expiresAt are never touched.
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0)},
+	},
+	"conversations": {
+		{Keys: bson.D{{Key: "participants", Value: 1}, {Key: "updatedAt", Value: -1}}, Options: options.Index().SetName("participants_updatedAt")},
+	},
+	"starred_messages": {
+		// One star per user and message; StarMessage relies on it to stay idempotent.
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "messageId", Value: 1}}, Options: options.Index().SetName("userId_messageId_unique").SetUnique(true)},
+		// The starred list, newest star first, paginated by _id.
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("userId_id")},
+	},
+	"reports": {
+		// One report per user and message; ReportMessage relies on it to refuse duplicates.
+		{Keys: bson.D{{Key: "reporterId", Value: 1}, {Key: "messageId", Value: 1}}, Options: options.Index().SetName("reporterId_messageId_unique").SetUnique(true)},
+		// The admin report list filtered by reported user, newest first.
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("senderId_id")},
+	},
+	"sessions": {
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetName("userId")},
+		// Let MongoDB delete sessions once their refresh token has expired.
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0)},
+	},
+	"password_resets": {
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetName("tokenHash")},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0)},
+	},
+	"email_verifications": {
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetName("tokenHash")},
+		{Keys: bson.D{{Key: "userId", Value: 1}}, Options: options.Index().SetName("userId")},
+		// Let MongoDB delete verification tokens once they have expired.
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetName("expiresAt_ttl").SetExpireAfterSeconds(0)},
+	},
+}
+
+// EnsureIndexes creates any missing indexes. Call it once after ConnectDB.
+// It fails if an index can't be built, e.g. the unique email index when the
+// users collection already contains duplicate emails.
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for collection, models := range indexes {
+		if _, err := DB.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("creating indexes on %s: %w", collection, err)
+		}
+	}
+	return nil
+}
@@ -2,17 +2,26 @@ package db
 
 import (
 	"context" // For managing request-scoped values, cancellation signals, and deadlines
-	"fmt"     // For formatted I/O (like printing to console)
-	"log"     // For logging messages, especially errors
+	"fmt"      // For wrapping connection errors
+	"log"      // For fatal configuration errors
+	"log/slog" // Structured logging
+	"strconv" // For parsing numeric write concerns (e.g. "2")
 	"time"    // For specifying timeouts
 
 	"go-backend/config" // Import your config package. IMPORTANT: Replace "chat-app-backend" with your actual Go module name from go.mod
 
 	"go.mongodb.org/mongo-driver/mongo"          // The main MongoDB driver package
 	"go.mongodb.org/mongo-driver/mongo/options"  // For setting client options
+	"go.mongodb.org/mongo-driver/mongo/readconcern"  // For configurable read concern levels
 	"go.mongodb.org/mongo-driver/mongo/readpref" // For pinging the database
+	"go.mongodb.org/mongo-driver/mongo/writeconcern" // For configurable write acknowledgement
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring" // For reading the database name from the URI
 )
 
+// defaultDatabaseName is used when neither MONGODB_DB_NAME nor the URI names a
+// database. It was the hardcoded name before the name became configurable.
+const defaultDatabaseName = "chat-db"
+
 // Global variables to hold the MongoDB client and database instance.
 // These will be initialized once and then used throughout the application.
 var(
@@ -20,49 +29,99 @@ var(
 	DB *mongo.Database
 )
 
+// maxConnectRetryDelay caps the exponential backoff between connection attempts.
+const maxConnectRetryDelay = 30 * time.Second
+
 // ConnectDB establishes a connection to MongoDB.
 // It takes a pointer to your application's Config struct, which contains the MongoDB URI.
-func ConnectDB(cfg *config.Config){
+// A failed attempt is retried with exponential backoff (starting at
+// MONGODB_CONNECT_RETRY_DELAY) up to MONGODB_CONNECT_MAX_ATTEMPTS times, so a
+// database that starts at the same time as the app doesn't kill it; the last
+// error is returned once the attempts run out.
+func ConnectDB(cfg *config.Config) error{
+	operationTimeout = cfg.DBTimeout
+	scanTimeout = cfg.DBScanTimeout
+	attempts := cfg.MongoDBConnectMaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := cfg.MongoDBConnectRetryDelay
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = connectOnce(cfg); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		slog.Warn("MongoDB connection failed; retrying", "attempt", attempt, "max_attempts", attempts, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxConnectRetryDelay {
+			delay = maxConnectRetryDelay
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// connectOnce makes a single connection attempt and, on success, sets Client and DB.
+func connectOnce(cfg *config.Config) error{
 	// 1. Create a new context with a timeout for the connection attempt.
 	//    It's good practice to set a reasonable timeout for network operations.
 	//    Example: 10 seconds.
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel() // Ensure the context is cancelled when ConnectDB exits
+	defer cancel() // Ensure the context is cancelled when connectOnce exits
 
 	// 2. Create a new MongoDB client instance.
 	//    Use `options.Client().ApplyURI()` to specify the connection string from your config.
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoDBURI))
+	clientOptions := options.Client().ApplyURI(cfg.MongoDBURI)
+
+	//    Optionally override the read/write concerns. Left empty, the driver defaults
+	//    (or whatever the URI specifies, e.g. `w=majority`) apply.
+	//    Note the tradeoff: `majority` write concern makes every insert wait for a
+	//    majority of replica set members to acknowledge it, which adds latency to
+	//    each SendMessage, but an acknowledged message then survives a primary failover.
+	if cfg.MongoDBWriteConcern != "" {
+		clientOptions.SetWriteConcern(parseWriteConcern(cfg.MongoDBWriteConcern))
+	}
+	if cfg.MongoDBReadConcern != "" {
+		clientOptions.SetReadConcern(parseReadConcern(cfg.MongoDBReadConcern))
+	}
+
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil{
-		// If connection fails, log a fatal error and exit the application.
-		log.Fatalf("MongoDB connection error: %v", err)
+		// Usually an invalid URI; the caller decides whether to retry.
+		return fmt.Errorf("MongoDB connection error: %w", err)
 	}
 
 	// 3. Ping the primary database to verify the connection is alive and working.
 	//    This helps catch issues even if `Connect` didn't return an error immediately.
 	err = client.Ping(ctx , readpref.Primary())
 	if err != nil{
-		// If ping fails, log a fatal error and exit the application.
-		log.Fatalf("MongoDB ping error: %v", err)
+		// Release the client's background connections before the next attempt.
+		client.Disconnect(context.Background())
+		return fmt.Errorf("MongoDB ping error: %w", err)
 	}
 
 	// 4. If connection and ping are successful, assign the client and the desired database
 	//    to the global variables. 
 	Client = client
-	DB = client.Database("chat-db") // Make sure "chat-db" matches your database name
+	DB = client.Database(databaseName(cfg))
 
-	fmt.Println("MongoDB connected successfully!")
+	slog.Info("MongoDB connected", "database", DB.Name())
+	return nil
 }
 
 // DisconnectDB closes the MongoDB connection gracefully.
 // This function should be called when your application is shutting down.
 func DisconnectDB(){
 	// 1. Create a new context for the disconnection with a timeout.
-	ctx, cancel :=context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel :=Context()
 	defer cancel() // ensure the context is cancelled
 
 	// 2. Check if the client is not nil before attempting to disconnect.
 	if Client == nil{
-		log.Println("MongoDB client is already nil, nothing to disconnect.")
+		slog.Warn("MongoDB client is already nil, nothing to disconnect")
 		return
 	}
 
@@ -70,8 +129,48 @@ func DisconnectDB(){
 	err := Client.Disconnect(ctx)
 	if err != nil{
 		// Log the error but don't fatally exit, as this is part of a graceful shutdown.
-		log.Printf("Error disconnecting from MongoDB: %v", err)
+		slog.Error("Error disconnecting from MongoDB", "error", err)
 		return
 	}
-	fmt.Println("MongoDB disconnected successfully.")
-}
\ No newline at end of file
+	slog.Info("MongoDB disconnected")
+}
+
+// databaseName picks the database to use: MONGODB_DB_NAME if set, else the
+// database in the URI's path (mongodb://host/<name>), else defaultDatabaseName.
+func databaseName(cfg *config.Config) string {
+	if cfg.MongoDBName != "" {
+		return cfg.MongoDBName
+	}
+	uri, err := connstring.Parse(cfg.MongoDBURI)
+	if err == nil && uri.Database != "" {
+		return uri.Database
+	}
+	return defaultDatabaseName
+}
+
+// parseWriteConcern converts the MONGODB_WRITE_CONCERN value into a driver write concern.
+// "majority" waits for a majority of members, a number waits for that many members,
+// and anything else is treated as a custom tag set name.
+func parseWriteConcern(value string) *writeconcern.WriteConcern {
+	if value == "majority" {
+		return writeconcern.Majority()
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 {
+			log.Fatalf("Invalid MONGODB_WRITE_CONCERN %q: must not be negative", value)
+		}
+		return &writeconcern.WriteConcern{W: n}
+	}
+	return writeconcern.Custom(value)
+}
+
+// parseReadConcern converts the MONGODB_READ_CONCERN value into a driver read concern.
+// Unknown levels are rejected at startup rather than failing on the first query.
+func parseReadConcern(value string) *readconcern.ReadConcern {
+	switch value {
+	case "local", "available", "majority", "linearizable", "snapshot":
+		return &readconcern.ReadConcern{Level: value}
+	}
+	log.Fatalf("Invalid MONGODB_READ_CONCERN %q: expected local, available, majority, linearizable or snapshot", value)
+	return nil
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"log"      // For fatal configuration errors
+	"log/slog" // Structured logging
+	"os"       // Logs go to stdout
+	"strings"  // For parsing LOG_LEVEL
+
+	"go-backend/config" // Import config for the log level
+
+	"github.com/gin-gonic/gin" // Gin context carries the per-request logger
+)
+
+// contextKey is the Gin context key under which the per-request logger is stored.
+const contextKey = "logger"
+
+// New creates the application's JSON logger at the configured LOG_LEVEL
+// ("debug", "info", "warn" or "error"). An unknown level is a fatal configuration error.
+func New(cfg *config.Config) *slog.Logger {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(cfg.LogLevel))); err != nil {
+		log.Fatalf("Invalid LOG_LEVEL %q: expected debug, info, warn or error", cfg.LogLevel)
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// FromContext returns the logger of the current request, which carries its
+// request ID (and user ID once authenticated). Outside a request, or before
+// the request logging middleware ran, it returns slog.Default().
+func FromContext(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get(contextKey); ok {
+		return logger.(*slog.Logger)
+	}
+	return slog.Default()
+}
+
+// Set stores logger as the current request's logger.
+func Set(c *gin.Context, logger *slog.Logger) {
+	c.Set(contextKey, logger)
+}
+
+// With adds attributes to the current request's logger, so every later log
+// line of the request includes them (e.g. the user ID after authentication).
+func With(c *gin.Context, args ...any) {
+	Set(c, FromContext(c).With(args...))
+}
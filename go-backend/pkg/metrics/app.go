@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"          // Metric types
+	"github.com/prometheus/client_golang/prometheus/promauto" // Registers metrics as they are created
+)
+
+// The application's metrics. Label values must come from small fixed sets
+// (routes as templates, never raw paths or IDs) to keep series counts bounded.
+var (
+	// HTTPRequestDuration is the latency of HTTP requests, labelled with the
+	// method, the route template (e.g. /api/messages/:id) and the status code.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// MessagesSent counts stored messages by kind: direct (1-to-1), group or forward.
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_messages_sent_total",
+		Help: "Messages sent, by kind (direct, group, forward).",
+	}, []string{"kind"})
+
+	// LoginAttempts counts logins by result: success or failure (bad credentials).
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "chat_login_attempts_total",
+		Help: "Login attempts, by result (success, failure).",
+	}, []string{"result"})
+
+	// WebSocketConnections is the number of open WebSocket connections on this instance.
+	WebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_websocket_connections",
+		Help: "Open WebSocket connections on this instance.",
+	})
+
+	// WebSocketUsers is the number of users with at least one open WebSocket
+	// connection on this instance (the size of the Hub's clients map).
+	WebSocketUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_websocket_connected_users",
+		Help: "Users with at least one open WebSocket connection on this instance.",
+	})
+)
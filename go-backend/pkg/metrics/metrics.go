@@ -0,0 +1,16 @@
+// Package metrics holds the application's Prometheus metrics and serves them
+// for scraping. The metrics are registered with the Prometheus client's
+// default registry when the package is initialized, alongside its Go runtime
+// and process collectors, and are safe for concurrent use.
+package metrics
+
+import (
+	"net/http" // For the /metrics handler
+
+	"github.com/prometheus/client_golang/prometheus/promhttp" // Serves the default registry
+)
+
+// Handler serves every registered metric in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
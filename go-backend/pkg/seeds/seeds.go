@@ -1,76 +1,84 @@
+// Package seeds fills a development database with a fixed set of users.
+// Run it with `go run ./cmd/seed`.
 package seeds
 
 import (
-	"context" // For context with MongoDB operations
-	//"fmt"     // For formatted output - REMOVED: Not used in this file
-	"log"     // For logging messages
-	"time"    // For timestamps
+	"context"       // For context with MongoDB operations
+	"encoding/json" // For users files
+	"fmt"           // For generated seed users and errors
+	"log"           // For logging messages
+	"os"            // For reading users files
+	"strings"       // For normalizing emails from users files
+	"time"          // For timestamps
 
-	"go-backend/config" // Import config for MongoDB URI
 	"go-backend/internal/models" // Import models for User struct
-	"go-backend/pkg/db" // Import db for MongoDB connection
+	"go-backend/pkg/db"          // Import db for MongoDB connection
 
-	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
 	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
-	"go.mongodb.org/mongo-driver/mongo" // For MongoDB client operations
-	"golang.org/x/crypto/bcrypt" // For password hashing
+	"go.mongodb.org/mongo-driver/mongo"          // For MongoDB client operations
+	"golang.org/x/crypto/bcrypt"                 // For password hashing
 )
 
+// DefaultPassword is the password of seed users that don't set their own,
+// unless Options.Password overrides it.
+const DefaultPassword = "123456"
+
+// minPasswordLength matches the `min=6` rule of SignupRequest, so seeded
+// users could also have been created through the API.
+const minPasswordLength = 6
+
+// SeedUser is one user to be inserted by SeedDatabase. It is also the shape
+// of each entry in a users file (see LoadUsers).
+type SeedUser struct {
+	Email      string `json:"email"`
+	FullName   string `json:"fullName"`
+	Password   string `json:"password,omitempty"` // Empty means the run's default password
+	ProfilePic string `json:"profilePic,omitempty"`
+}
+
 // SeedUsers defines the initial user data to be inserted.
 // This mirrors the `seedUsers` array in your Node.js `user.seed.js`.
-var SeedUsers = []struct {
-	Email      string
-	FullName   string
-	Password   string
-	ProfilePic string
-}{
+var SeedUsers = []SeedUser{
 	// Female Users
 	{
 		Email:      "emma.thompson@example.com",
 		FullName:   "Emma Thompson",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/1.jpg",
 	},
 	{
 		Email:      "olivia.miller@example.com",
 		FullName:   "Olivia Miller",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/2.jpg",
 	},
 	{
 		Email:      "sophia.davis@example.com",
 		FullName:   "Sophia Davis",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/3.jpg",
 	},
 	{
 		Email:      "ava.wilson@example.com",
 		FullName:   "Ava Wilson",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/4.jpg",
 	},
 	{
 		Email:      "isabella.brown@example.com",
 		FullName:   "Isabella Brown",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/5.jpg",
 	},
 	{
 		Email:      "mia.johnson@example.com",
 		FullName:   "Mia Johnson",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/6.jpg",
 	},
 	{
 		Email:      "charlotte.williams@example.com",
 		FullName:   "Charlotte Williams",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/7.jpg",
 	},
 	{
 		Email:      "amelia.garcia@example.com",
 		FullName:   "Amelia Garcia",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/women/8.jpg",
 	},
 
@@ -78,115 +86,230 @@ var SeedUsers = []struct {
 	{
 		Email:      "james.anderson@example.com",
 		FullName:   "James Anderson",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/1.jpg",
 	},
 	{
 		Email:      "william.clark@example.com",
 		FullName:   "William Clark",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/2.jpg",
 	},
 	{
 		Email:      "benjamin.taylor@example.com",
 		FullName:   "Benjamin Taylor",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/3.jpg",
 	},
 	{
 		Email:      "lucas.moore@example.com",
 		FullName:   "Lucas Moore",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/4.jpg",
 	},
 	{
 		Email:      "henry.jackson@example.com",
 		FullName:   "Henry Jackson",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/5.jpg",
 	},
 	{
 		Email:      "alexander.martin@example.com",
 		FullName:   "Alexander Martin",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/6.jpg",
 	},
 	{
 		Email:      "daniel.rodriguez@example.com",
 		FullName:   "Daniel Rodriguez",
-		Password:   "123456",
 		ProfilePic: "https://randomuser.me/api/portraits/men/7.jpg",
 	},
 }
 
-// SeedDatabase connects to MongoDB and inserts the predefined users.
-// This function mirrors the `seedDatabase` function in your Node.js `user.seed.js`.
-func SeedDatabase() {
-	// Load configuration (needed for MongoDB URI)
-	cfg := config.LoadConfig()
-	if cfg == nil {
-		log.Fatal("Failed to load configuration for seeding.")
+// Options tune a SeedDatabase run.
+type Options struct {
+	// Users is the list to seed, e.g. from LoadUsers. nil means SeedUsers.
+	Users []SeedUser
+	// Count is how many users to seed: 0 seeds the whole list, fewer seeds
+	// the first Count of them, and more adds generated users after the list.
+	Count int
+	// Password is given to users without their own. Empty means DefaultPassword.
+	Password string
+	// Wipe deletes the users about to be seeded before inserting them again,
+	// so a run starts from a known state. Other users are never touched.
+	Wipe bool
+}
+
+// LoadUsers reads seed users from a JSON file holding an array of SeedUser
+// objects, e.g. [{"email": "a@example.com", "fullName": "A"}]. Emails are
+// normalized like signup does, and every entry is checked up front so a bad
+// file fails before anything is written.
+func LoadUsers(path string) ([]SeedUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var users []SeedUser
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("%s contains no users", path)
 	}
 
-	// Connect to MongoDB
-	db.ConnectDB(cfg)
-	defer db.DisconnectDB() // Ensure disconnection on exit
+	seen := make(map[string]bool, len(users))
+	for i := range users {
+		user := &users[i]
+		user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+		user.FullName = strings.TrimSpace(user.FullName)
+		switch {
+		case user.Email == "":
+			return nil, fmt.Errorf("%s: user %d has no email", path, i+1)
+		case user.FullName == "":
+			return nil, fmt.Errorf("%s: user %s has no fullName", path, user.Email)
+		case seen[user.Email]:
+			return nil, fmt.Errorf("%s: email %s appears more than once", path, user.Email)
+		case user.Password != "" && len(user.Password) < minPasswordLength:
+			return nil, fmt.Errorf("%s: password of %s is shorter than %d characters", path, user.Email, minPasswordLength)
+		}
+		seen[user.Email] = true
+	}
+	return users, nil
+}
 
+// usersFor returns the users a run seeds: base trimmed or extended to count.
+func usersFor(base []SeedUser, count int) []SeedUser {
+	if count <= 0 {
+		return base
+	}
+	if count <= len(base) {
+		return base[:count]
+	}
+	users := append([]SeedUser(nil), base...)
+	for i := len(base) + 1; i <= count; i++ {
+		users = append(users, SeedUser{
+			Email:    fmt.Sprintf("seed.user%d@example.com", i),
+			FullName: fmt.Sprintf("Seed User %d", i),
+		})
+	}
+	return users
+}
+
+// seedResult is what happened to one seed user.
+type seedResult int
+
+const (
+	seedInserted seedResult = iota
+	seedSkipped             // Email already taken
+	seedFailed              // Logged by seedOne
+)
+
+// SeedDatabase inserts the seed users into the connected database (see
+// db.ConnectDB). Users whose email already exists are skipped, unless
+// opts.Wipe deleted them first.
+// This function mirrors the `seedDatabase` function in your Node.js `user.seed.js`.
+func SeedDatabase(opts Options) error {
+	password := opts.Password
+	if password == "" {
+		password = DefaultPassword
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("default password must be at least %d characters", minPasswordLength)
+	}
+
+	base := opts.Users
+	if base == nil {
+		base = SeedUsers
+	}
+	users := usersFor(base, opts.Count)
 	usersCollection := db.DB.Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
 
-	log.Println("Starting database seeding...")
+	log.Printf("Starting database seeding (%d users)...", len(users))
 
-	// Iterate through the seed users and insert them
-	for _, seedUser := range SeedUsers {
-		// Check if user already exists by email to prevent duplicates
-		var existingUser models.User
-		err := usersCollection.FindOne(ctx, bson.M{"email": seedUser.Email}).Decode(&existingUser)
-		if err == nil {
-			log.Printf("User with email %s already exists, skipping.", seedUser.Email)
-			continue // Skip if user already exists
+	if opts.Wipe {
+		emails := make([]string, len(users))
+		for i, seedUser := range users {
+			emails[i] = seedUser.Email
 		}
-		if err != mongo.ErrNoDocuments {
-			log.Printf("Error checking for existing user %s: %v", seedUser.Email, err)
-			continue // Log error and continue to next user
-		}
-
-		// Hash the password
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(seedUser.Password), bcrypt.DefaultCost)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, err := usersCollection.DeleteMany(ctx, bson.M{"email": bson.M{"$in": emails}})
+		cancel()
 		if err != nil {
-			log.Printf("Error hashing password for %s: %v", seedUser.Email, err)
-			continue // Log error and continue to next user
+			return fmt.Errorf("wiping existing seed users: %w", err)
 		}
+		log.Printf("Deleted %d existing seed users.", result.DeletedCount)
+	}
+
+	// bcrypt is deliberately slow, and most users share the default password,
+	// so each distinct password is hashed only once per run.
+	hashes := map[string][]byte{}
 
-		// Create a new User model instance
-		newUser := models.User{
-			ID:         primitive.NewObjectID(),
-			FullName:   seedUser.FullName,
-			Email:      seedUser.Email,
-			Password:   string(hashedPassword), // Store hashed password as string
-			ProfilePic: seedUser.ProfilePic,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
+	// Iterate through the seed users and insert them
+	var inserted, skipped, failed int
+	for _, seedUser := range users {
+		if seedUser.Password == "" {
+			seedUser.Password = password
 		}
+		switch seedOne(usersCollection, seedUser, hashes) {
+		case seedInserted:
+			inserted++
+		case seedSkipped:
+			skipped++
+		default:
+			failed++
+		}
+	}
+
+	log.Printf("Database seeding completed: %d inserted, %d skipped (already existed), %d failed.", inserted, skipped, failed)
+	return nil
+}
+
+// seedOne inserts a single seed user unless their email is already taken.
+// Errors are logged and the user skipped, so one bad entry doesn't stop the run.
+// hashes caches password hashes across calls.
+func seedOne(usersCollection *mongo.Collection, seedUser SeedUser, hashes map[string][]byte) seedResult {
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	// Check if user already exists by email to prevent duplicates
+	var existingUser models.User
+	err := usersCollection.FindOne(ctx, bson.M{"email": seedUser.Email}).Decode(&existingUser)
+	if err == nil {
+		log.Printf("User with email %s already exists, skipping.", seedUser.Email)
+		return seedSkipped // Skip if user already exists
+	}
+	if err != mongo.ErrNoDocuments {
+		log.Printf("Error checking for existing user %s: %v", seedUser.Email, err)
+		return seedFailed
+	}
 
-		// Insert the new user into the database
-		_, err = usersCollection.InsertOne(ctx, newUser)
+	// Hash the password
+	hashedPassword, ok := hashes[seedUser.Password]
+	if !ok {
+		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(seedUser.Password), bcrypt.DefaultCost)
 		if err != nil {
-			log.Printf("Error inserting user %s: %v", seedUser.Email, err)
-			continue // Log error and continue to next user
+			log.Printf("Error hashing password for %s: %v", seedUser.Email, err)
+			return seedFailed
 		}
-		log.Printf("Successfully seeded user: %s", newUser.Email)
+		hashes[seedUser.Password] = hashedPassword
 	}
 
-	log.Println("Database seeding completed.")
-}
+	// Create a new User model instance
+	now := time.Now()
+	newUser := models.User{
+		ID:            primitive.NewObjectID(),
+		FullName:      seedUser.FullName,
+		Email:         seedUser.Email,
+		Password:      string(hashedPassword), // Store hashed password as string
+		ProfilePic:    seedUser.ProfilePic,
+		EmailVerified: true, // Seed users can log in even with REQUIRE_EMAIL_VERIFICATION
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
 
-// main function for standalone execution of seeding.
-// This is typically run once via `go run pkg/seeds/seeds.go`.
-func init() {
-    // This `init` function will run automatically when this package is imported.
-    // However, for a standalone seeding script, you'd typically call SeedDatabase
-    // from a `main` function if this were its own executable.
-    // For our structure, we'll create a separate `cmd/seed/main.go` for execution.
+	// Insert the new user into the database
+	if _, err := usersCollection.InsertOne(ctx, newUser); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			log.Printf("User with email %s already exists, skipping.", seedUser.Email)
+			return seedSkipped // Created between the check and the insert
+		}
+		log.Printf("Error inserting user %s: %v", seedUser.Email, err)
+		return seedFailed
+	}
+	log.Printf("Successfully seeded user: %s", newUser.Email)
+	return seedInserted
 }
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/base64" // For decoding the audio payload
+	"errors"          // For the sentinel validation errors
+	"fmt"             // For formatted error messages
+	"net/http"        // For sniffing the decoded content type and status codes
+	"strings"         // For parsing the data URI
+)
+
+// Audio validation errors returned by UploadAudio. Handlers map them to a
+// status code with AudioUploadStatus.
+var (
+	ErrAudioTooLarge        = errors.New("audio is too large")
+	ErrAudioTooLong         = errors.New("audio is too long")
+	ErrUnsupportedAudioType = errors.New("unsupported audio type")
+	ErrInvalidAudio         = errors.New("invalid audio data")
+)
+
+// allowedAudioTypes maps the MIME types accepted for voice notes to the types
+// http.DetectContentType reports for their content. MP3 and AAC streams
+// without a container have no signature it knows, so for those an unknown
+// ("application/octet-stream") result is accepted too.
+var allowedAudioTypes = map[string][]string{
+	"audio/webm": {"video/webm"}, // Browsers' MediaRecorder default
+	"audio/ogg":  {"application/ogg"},
+	"audio/mpeg": {"audio/mpeg", "application/octet-stream"},
+	"audio/mp4":  {"video/mp4", "audio/mp4"}, // Safari's MediaRecorder
+	"audio/aac":  {"application/octet-stream"},
+	"audio/wav":  {"audio/wave"},
+}
+
+// validateAudioDataURI checks a "data:<mime>;base64,<data>" voice note like
+// validateImageDataURI checks images: allowed declared type (codec parameters
+// such as ";codecs=opus" are ignored), decodable payload no larger than
+// maxBytes (0 = no limit), and content that matches the declared type.
+func validateAudioDataURI(dataURI string, maxBytes int) error {
+	header, data, found := strings.Cut(dataURI, ",")
+	if !found || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return fmt.Errorf("%w: expected a base64 data URI", ErrInvalidAudio)
+	}
+	mimeType, _, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64"), ";")
+	mimeType = strings.ToLower(mimeType)
+	sniffable, ok := allowedAudioTypes[mimeType]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAudioType, mimeType)
+	}
+
+	// Reject oversized payloads before decoding, so we never allocate for them.
+	if maxBytes > 0 && base64.StdEncoding.DecodedLen(len(data)) > maxBytes+2 {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrAudioTooLarge, maxBytes)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAudio, err)
+	}
+	if maxBytes > 0 && len(decoded) > maxBytes {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrAudioTooLarge, maxBytes)
+	}
+
+	sniffed := http.DetectContentType(decoded)
+	for _, allowed := range sniffable {
+		if sniffed == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: content is %q", ErrUnsupportedAudioType, sniffed)
+}
+
+// AudioUploadStatus returns the HTTP status for an UploadAudio error:
+// 413 for an oversized file, 400 for one that is too long, invalid or of a
+// disallowed type, and 500 for anything else (i.e. Cloudinary failing).
+func AudioUploadStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAudioTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrAudioTooLong), errors.Is(err, ErrUnsupportedAudioType), errors.Is(err, ErrInvalidAudio):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
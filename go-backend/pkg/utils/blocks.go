@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context" // For context with MongoDB operations
+
+	"go-backend/internal/models" // Import models for the User struct
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For projecting the block lists
+)
+
+// BlockExists reports whether either user has blocked the other.
+// Blocks are stored on the blocker's document (User.BlockedUsers).
+func BlockExists(ctx context.Context, a, b primitive.ObjectID) (bool, error) {
+	count, err := db.DB.Collection("users").CountDocuments(ctx, bson.M{
+		"$or": []bson.M{
+			{"_id": a, "blockedUsers": b},
+			{"_id": b, "blockedUsers": a},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SenderBlockStatus is BlockExists for a message from senderID to
+// receiverID, which also reports whether the block is silent: only the
+// receiver blocked the sender, and chose to do it silently
+// (User.SilentBlocks). A sender who blocked the receiver themselves always
+// gets a normal block.
+func SenderBlockStatus(ctx context.Context, senderID, receiverID primitive.ObjectID) (blocked, silent bool, err error) {
+	cursor, err := db.DB.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": []primitive.ObjectID{senderID, receiverID}}},
+		options.Find().SetProjection(bson.M{"blockedUsers": 1, "silentBlocks": 1}))
+	if err != nil {
+		return false, false, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return false, false, err
+	}
+	for _, user := range users {
+		switch user.ID {
+		case senderID:
+			if containsID(user.BlockedUsers, receiverID) {
+				return true, false, nil
+			}
+		case receiverID:
+			if containsID(user.BlockedUsers, senderID) {
+				blocked, silent = true, containsID(user.SilentBlocks, senderID)
+			}
+		}
+	}
+	return blocked, silent, nil
+}
+
+// NotSilencedFor is a "$nor" clause hiding the silenced messages sent to
+// userID (see models.Message.Silenced). Their sender still sees them.
+func NotSilencedFor(userID primitive.ObjectID) []bson.M {
+	return []bson.M{{"silenced": true, "receiverId": userID}}
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
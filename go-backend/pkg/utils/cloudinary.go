@@ -4,7 +4,10 @@ import (
 	"context" // For context with Cloudinary upload operations
 	"fmt"     // For formatted error messages
 	"log"     // For logging errors
+	"log/slog" // Structured logging
+	"strings" // For parsing Cloudinary URLs
 	"time"    // For time-related operations (REQUIRED for context.WithTimeout)
+	"unicode" // For recognizing version segments in Cloudinary URLs
 
 	"go-backend/config" // Import your config package for Cloudinary credentials
 
@@ -12,10 +15,59 @@ import (
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader" // For upload specific functions
 )
 
+// ImageUploadProfile is where UploadImage stores an image and how it is
+// shrunk first. Callers pick the profile matching what the image is for.
+type ImageUploadProfile struct {
+	Folder       string // Cloudinary folder of the uploads
+	MaxDimension int    // Larger images are scaled down to fit this many pixels wide and high (0 = keep the size)
+
+	// ThumbnailSize bounds the thumbnail Cloudinary makes of each upload, in
+	// pixels (0 = no thumbnail). ThumbnailCrop is how the image is fitted:
+	// "c_fill,g_auto" crops it to a square around its subject, "c_limit"
+	// scales it down whole.
+	ThumbnailSize int
+	ThumbnailCrop string
+}
+
+// transformation returns the incoming transformation Cloudinary applies
+// before storing an image: scale it down to fit MaxDimension (never up), then
+// re-encode it at the lowest quality that looks the same ("q_auto").
+func (p ImageUploadProfile) transformation() string {
+	if p.MaxDimension <= 0 {
+		return "q_auto"
+	}
+	return fmt.Sprintf("c_limit,w_%d,h_%d/q_auto", p.MaxDimension, p.MaxDimension)
+}
+
+// thumbnailTransformation returns the eager transformation that makes the
+// thumbnail, or "" if the profile has none.
+func (p ImageUploadProfile) thumbnailTransformation() string {
+	if p.ThumbnailSize <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s,w_%d,h_%d/q_auto", p.ThumbnailCrop, p.ThumbnailSize, p.ThumbnailSize)
+}
+
+// deliveryTransformation is added to the URLs of uploaded images so Cloudinary
+// serves each browser the smallest format it supports (e.g. WebP or AVIF).
+const deliveryTransformation = "f_auto"
+
 // CloudinaryService struct holds the Cloudinary client instance.
 // This allows for dependency injection and easier testing.
 type CloudinaryService struct {
-	Client *cloudinary.Cloudinary
+	Client         *cloudinary.Cloudinary
+	CloudName      string        // Used to recognize URLs of our own assets
+	MaxUploadBytes int           // Largest decoded image accepted by UploadImage (0 = no limit)
+	Timeout        time.Duration // Deadline of each upload or delete (UPLOAD_TIMEOUT)
+
+	// Upload profiles of profile pictures and of images sent in messages.
+	ProfilePicUpload   ImageUploadProfile
+	MessageImageUpload ImageUploadProfile
+
+	// Voice note folder and limits enforced by UploadAudio (0 = no limit).
+	AudioFolder      string
+	MaxAudioBytes    int
+	MaxAudioDuration time.Duration
 }
 
 // NewCloudinaryService initializes and returns a new CloudinaryService.
@@ -32,40 +84,210 @@ func NewCloudinaryService(cfg *config.Config) *CloudinaryService {
 		// as Cloudinary is a critical dependency for image handling.
 		log.Fatalf("Failed to initialize Cloudinary: %v", err)
 	}
-	return &CloudinaryService{Client: cld}
+	return &CloudinaryService{
+		Client:           cld,
+		CloudName:        cfg.CloudinaryCloudName,
+		MaxUploadBytes:   cfg.MaxUploadBytes,
+		ProfilePicUpload: ImageUploadProfile{
+			Folder:        cfg.CloudinaryProfilePicFolder,
+			MaxDimension:  cfg.ProfilePicMaxDimension,
+			ThumbnailSize: cfg.ThumbnailSize,
+			ThumbnailCrop: "c_fill,g_auto", // Avatars are shown as squares
+		},
+		MessageImageUpload: ImageUploadProfile{
+			Folder:        cfg.CloudinaryImageFolder,
+			MaxDimension:  cfg.ImageMaxDimension,
+			ThumbnailSize: cfg.ThumbnailSize,
+			ThumbnailCrop: "c_limit", // Keep the aspect ratio of chat images
+		},
+		AudioFolder:      cfg.CloudinaryAudioFolder,
+		MaxAudioBytes:    cfg.MaxAudioBytes,
+		MaxAudioDuration: cfg.MaxAudioDuration,
+		Timeout:          cfg.UploadTimeout,
+	}
+}
+
+// UploadedImage describes an image stored on Cloudinary.
+type UploadedImage struct {
+	SecureURL string // HTTPS URL to serve the image from, in the best format for each browser
+	PublicID  string // Cloudinary's ID for the asset; needed to delete it later
+	Width     int    // Pixel dimensions as stored (after any resizing), so clients can reserve space before the image loads
+	Height    int
+	Format    string // File format, e.g. "jpg" or "png"
+
+	// ThumbnailURL is a small version of the image for lists and previews.
+	// Empty if the profile makes none (or Cloudinary didn't return one);
+	// clients then fall back to SecureURL.
+	ThumbnailURL string
 }
 
 // UploadImage uploads a base64 encoded image string to Cloudinary.
 // Mirrors backend/src/lib/cloudinary.js's upload functionality.
 //
 // Parameters:
+//   ctx: Usually the request's context, so the upload stops if the client goes away.
 //   base64Image: The base64 encoded image string (e.g., "data:image/jpeg;base64,...").
+//   profile: Where to store the image and how far to shrink it, usually
+//     cs.ProfilePicUpload or cs.MessageImageUpload.
 //
 // Returns:
-//   The secure URL of the uploaded image, or an error if the upload fails.
-func (cs *CloudinaryService) UploadImage(base64Image string) (string, error) {
+//   The uploaded image's URL, public ID (keep it to delete the asset later)
+//   and dimensions, or an error if the upload fails.
+//   Images that are too large, not an allowed type, or malformed are rejected
+//   before uploading with ErrImageTooLarge, ErrUnsupportedImageType or
+//   ErrInvalidImage; see ImageUploadStatus.
+func (cs *CloudinaryService) UploadImage(ctx context.Context, base64Image string, profile ImageUploadProfile) (UploadedImage, error) {
+	if err := validateImageDataURI(base64Image, cs.MaxUploadBytes); err != nil {
+		return UploadedImage{}, err
+	}
+
 	// REVERTED TO RECOMMENDED APPROACH:
-	// Create a context with a timeout for the upload operation.
+	// Add a timeout to the upload operation.
 	// This is good practice to prevent the application from hanging indefinitely
 	// if the external API (Cloudinary) is slow or unresponsive.
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, cs.Timeout)
 	defer cancel() // Ensure the context is cancelled when the function exits
 
 	// Define upload parameters.
-	// `Folder`: Optional, but good for organizing uploads (e.g., "chat_app_profile_pics").
+	// `Folder`: Organizes uploads per profile (e.g., "chat_app_profile_pics").
+	// `Transformation`: Applied before storing, so only the shrunk image is kept.
+	// `Eager`: Derived versions generated right away, here the thumbnail, so
+	//   its first request doesn't wait for Cloudinary to make it.
 	// `PublicID`: Cloudinary will generate a unique public ID if not specified.
-	// `ResourceType`: "image" is standard for image uploads.
 	uploadParams := uploader.UploadParams{
-		Folder: "chat_app_images", // You can customize this folder name
+		Folder:         profile.Folder,
+		Transformation: profile.transformation(),
+		Eager:          profile.thumbnailTransformation(),
 	}
 
 	// Perform the upload.
 	// The `base64Image` string is directly passed as the source.
 	uploadResult, err := cs.Client.Upload.Upload(ctx, base64Image, uploadParams)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload image to Cloudinary: %w", err)
+		return UploadedImage{}, fmt.Errorf("failed to upload image to Cloudinary: %w", err)
+	}
+
+	// Return the secure URL and metadata of the uploaded image.
+	image := UploadedImage{
+		SecureURL: withDeliveryTransformation(uploadResult.SecureURL),
+		PublicID:  uploadResult.PublicID,
+		Width:     uploadResult.Width,
+		Height:    uploadResult.Height,
+		Format:    uploadResult.Format,
+	}
+	// Only one eager transformation is requested, so it's the thumbnail.
+	// Its URL is used as is: changing it (e.g. adding f_auto) would make
+	// Cloudinary derive another version on the first request.
+	if len(uploadResult.Eager) > 0 {
+		image.ThumbnailURL = uploadResult.Eager[0].SecureURL
+	}
+	return image, nil
+}
+
+// UploadedAudio describes a voice note stored on Cloudinary.
+type UploadedAudio struct {
+	SecureURL string  // HTTPS URL to play the audio from
+	PublicID  string  // Cloudinary's ID for the asset; needed to delete it later
+	Duration  float64 // Length in seconds, as measured by Cloudinary
+	Format    string  // File format, e.g. "webm" or "mp3"
+}
+
+// UploadAudio uploads a base64 encoded voice note ("data:audio/webm;base64,...")
+// to Cloudinary. Cloudinary handles audio as the "video" resource type.
+// Files that are too large, not an allowed type, or malformed are rejected
+// before uploading (ErrAudioTooLarge, ErrUnsupportedAudioType, ErrInvalidAudio).
+// The duration can only be measured once uploaded: a file longer than
+// MaxAudioDuration is deleted again and ErrAudioTooLong returned.
+// See AudioUploadStatus for the matching HTTP statuses. ctx is usually the
+// request's context, as for UploadImage.
+func (cs *CloudinaryService) UploadAudio(ctx context.Context, base64Audio string) (UploadedAudio, error) {
+	if err := validateAudioDataURI(base64Audio, cs.MaxAudioBytes); err != nil {
+		return UploadedAudio{}, err
 	}
 
-	// Return the secure URL of the uploaded image.
-	return uploadResult.SecureURL, nil
+	ctx, cancel := context.WithTimeout(ctx, cs.Timeout)
+	defer cancel()
+
+	uploadParams := uploader.UploadParams{
+		Folder:       cs.AudioFolder,
+		ResourceType: "video",
+	}
+	uploadResult, err := cs.Client.Upload.Upload(ctx, base64Audio, uploadParams)
+	if err != nil {
+		return UploadedAudio{}, fmt.Errorf("failed to upload audio to Cloudinary: %w", err)
+	}
+
+	// The typed result has no duration field; it's in the raw response.
+	audio := UploadedAudio{
+		SecureURL: uploadResult.SecureURL,
+		PublicID:  uploadResult.PublicID,
+		Format:    uploadResult.Format,
+	}
+	if raw, ok := uploadResult.Response.(map[string]interface{}); ok {
+		audio.Duration, _ = raw["duration"].(float64)
+	}
+
+	if cs.MaxAudioDuration > 0 && audio.Duration > cs.MaxAudioDuration.Seconds() {
+		if err := cs.deleteAsset(audio.PublicID, "video"); err != nil {
+			slog.Error("Error deleting rejected audio from Cloudinary", "public_id", audio.PublicID, "error", err)
+		}
+		return UploadedAudio{}, fmt.Errorf("%w: the limit is %s", ErrAudioTooLong, cs.MaxAudioDuration)
+	}
+	return audio, nil
+}
+
+// DeleteImage removes an uploaded image from Cloudinary by its public ID.
+// Deleting an asset that no longer exists is not an error.
+func (cs *CloudinaryService) DeleteImage(publicID string) error {
+	return cs.deleteAsset(publicID, "image")
+}
+
+// deleteAsset removes an uploaded asset of the given Cloudinary resource type.
+func (cs *CloudinaryService) deleteAsset(publicID, resourceType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cs.Timeout)
+	defer cancel()
+
+	if _, err := cs.Client.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: publicID, ResourceType: resourceType}); err != nil {
+		return fmt.Errorf("failed to delete %s %s from Cloudinary: %w", resourceType, publicID, err)
+	}
+	return nil
+}
+
+// withDeliveryTransformation adds deliveryTransformation to the URL of an
+// uploaded image, e.g. .../image/upload/v1712345678/x.jpg ->
+// .../image/upload/f_auto/v1712345678/x.jpg. Other URLs are returned as is.
+func withDeliveryTransformation(imageURL string) string {
+	before, after, found := strings.Cut(imageURL, "/image/upload/")
+	if !found {
+		return imageURL
+	}
+	return before + "/image/upload/" + deliveryTransformation + "/" + after
+}
+
+// PublicIDFromURL extracts the public ID from the URL of an image uploaded to
+// our Cloudinary account, e.g.
+// https://res.cloudinary.com/<cloud>/image/upload/f_auto/v1712345678/chat_app_images/abc.jpg
+// -> "chat_app_images/abc". The delivery transformation and version are
+// optional, so URLs stored before either was added work too. It returns "" for
+// any other URL (such as external avatars from the seed data), so those are
+// never deleted.
+func (cs *CloudinaryService) PublicIDFromURL(imageURL string) string {
+	if cs.CloudName == "" {
+		return ""
+	}
+	path, ok := strings.CutPrefix(imageURL, "https://res.cloudinary.com/"+cs.CloudName+"/image/upload/")
+	if !ok {
+		return ""
+	}
+	path = strings.TrimPrefix(path, deliveryTransformation+"/")
+	// Skip the optional version segment ("v" followed by digits).
+	if version, rest, found := strings.Cut(path, "/"); found && len(version) > 1 && version[0] == 'v' &&
+		strings.IndexFunc(version[1:], func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+		path = rest
+	}
+	// Drop the file extension.
+	if dot := strings.LastIndex(path, "."); dot > strings.LastIndex(path, "/") {
+		path = path[:dot]
+	}
+	return path
 }
\ No newline at end of file
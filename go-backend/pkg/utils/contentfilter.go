@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"log"      // For logging fatal configuration errors
+	"log/slog" // For the empty-blocklist warning
+	"regexp"   // For the precompiled blocklist matcher
+	"strings"  // For splitting and trimming the blocklist
+
+	"go-backend/config" // Import your config package for the filter mode and blocklist
+)
+
+// Content filter modes, selected with CONTENT_FILTER_MODE.
+const (
+	ContentFilterOff    = "off"    // No filtering (default)
+	ContentFilterReject = "reject" // Refuse to send matching messages
+	ContentFilterFlag   = "flag"   // Send matching messages but mark them flagged for moderators
+)
+
+// ContentFilter checks message text against a configurable blocklist.
+// All blocklist entries are compiled into a single case-insensitive regular
+// expression once at startup, so each check is one pass over the text.
+type ContentFilter struct {
+	Mode    string
+	matcher *regexp.Regexp // nil when the filter is off or the blocklist is empty
+}
+
+// NewContentFilter builds the filter from config.
+// Blocklist entries are comma-separated. A plain entry matches as a whole word,
+// using Unicode letter/number classes for the word boundaries so it works for
+// non-Latin scripts too (Go's \b is ASCII-only). An entry wrapped in slashes,
+// like /fr[e3]{2}\s*money/, is used as a raw regular expression (it cannot
+// contain a comma, since commas separate entries).
+// An invalid mode or pattern is a fatal configuration error.
+func NewContentFilter(cfg *config.Config) *ContentFilter {
+	mode := strings.ToLower(strings.TrimSpace(cfg.ContentFilterMode))
+	switch mode {
+	case "":
+		mode = ContentFilterOff
+	case ContentFilterOff, ContentFilterReject, ContentFilterFlag:
+	default:
+		log.Fatalf("Invalid CONTENT_FILTER_MODE %q: expected off, reject or flag", cfg.ContentFilterMode)
+	}
+
+	filter := &ContentFilter{Mode: mode}
+	if mode == ContentFilterOff {
+		return filter
+	}
+
+	var alternatives []string
+	for _, entry := range strings.Split(cfg.ContentFilterBlocklist, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/"):
+			pattern := entry[1 : len(entry)-1]
+			if _, err := regexp.Compile(pattern); err != nil {
+				log.Fatalf("Invalid CONTENT_FILTER_BLOCKLIST pattern %q: %v", entry, err)
+			}
+			alternatives = append(alternatives, "(?:"+pattern+")")
+		default:
+			alternatives = append(alternatives, `(?:^|[^\p{L}\p{N}_])`+regexp.QuoteMeta(entry)+`(?:$|[^\p{L}\p{N}_])`)
+		}
+	}
+	if len(alternatives) == 0 {
+		slog.Warn("Content filter is enabled but CONTENT_FILTER_BLOCKLIST is empty; nothing will be filtered", "mode", mode)
+		return filter
+	}
+
+	// (?i) applies Unicode case folding, so "SPAM" and "Spam" both match "spam".
+	filter.matcher = regexp.MustCompile("(?i)" + strings.Join(alternatives, "|"))
+	return filter
+}
+
+// Matches reports whether the text contains any blocklisted word or pattern.
+// It always returns false when the filter is off.
+func (f *ContentFilter) Matches(text string) bool {
+	if f == nil || f.matcher == nil || text == "" {
+		return false
+	}
+	return f.matcher.MatchString(text)
+}
@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"context" // For operation deadlines
+
+	"go-backend/pkg/db" // For the configured database timeout
+
+	"github.com/gin-gonic/gin" // Gin context for the request's context
+)
+
+// RequestContext returns the context a handler's database work should use:
+// the request's own context, so the work is cancelled when the client
+// disconnects, with the DB_TIMEOUT deadline on top. The context ends when the
+// handler returns, so anything still running after the response (e.g. in a
+// goroutine) needs db.Context instead.
+func RequestContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return db.ContextFrom(c.Request.Context())
+}
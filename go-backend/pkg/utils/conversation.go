@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"crypto/sha256" // For hashing the participant pair
+	"encoding/hex"  // For a URL-safe string form
+
+	"go.mongodb.org/mongo-driver/bson/primitive" // For handling ObjectID
+)
+
+// ConversationIDFor derives a stable identifier for the 1-to-1 conversation
+// between two users. The IDs are sorted before hashing, so
+// ConversationIDFor(a, b) == ConversationIDFor(b, a). Clients use it as a cache
+// and routing key; nothing is stored under it in the database.
+func ConversationIDFor(a, b primitive.ObjectID) string {
+	first, second := a.Hex(), b.Hex()
+	if first > second {
+		first, second = second, first
+	}
+	sum := sha256.Sum256([]byte(first + ":" + second))
+	return hex.EncodeToString(sum[:16]) // 128 bits is plenty to avoid collisions
+}
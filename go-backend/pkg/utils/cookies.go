@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For the http.SameSite modes
+	"strings"  // For case-insensitive config values
+
+	"go-backend/config" // Import your config package for the SameSite mode and environment
+
+	"github.com/gin-gonic/gin" // Gin context for setting cookies
+)
+
+// ParseSameSite converts a COOKIE_SAMESITE value ("lax", "strict" or "none",
+// case-insensitive) to its http.SameSite mode. NewServer calls it at startup so
+// a typo fails fast instead of silently weakening the cookies.
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("invalid SameSite mode %q: expected lax, strict or none", value)
+}
+
+// SetAuthCookie sets an HTTP-only auth cookie (the access or refresh token)
+// with an explicit SameSite attribute from COOKIE_SAMESITE. Pass maxAge -1 to
+// delete the cookie. Cookies are Secure in production, and always when
+// SameSite is None, which browsers reject without Secure.
+func SetAuthCookie(c *gin.Context, cfg *config.Config, name, value string, maxAge int, path string) {
+	sameSite, err := ParseSameSite(cfg.CookieSameSite)
+	if err != nil {
+		sameSite = http.SameSiteLaxMode // Unreachable once NewServer has validated the config
+	}
+	secure := cfg.NodeEnv == "production" || sameSite == http.SameSiteNoneMode
+
+	// Gin's SetCookie has no SameSite parameter; it applies the mode set on the context.
+	c.SetSameSite(sameSite)
+	c.SetCookie(name, value, maxAge, path, "", secure, true)
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"log/slog" // Structured logging
+	"time"     // For timestamps and timeouts
+
+	"go-backend/internal/models" // Import models for the Message struct and statuses
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo"          // For mongo.ErrNoDocuments
+	"go.mongodb.org/mongo-driver/mongo/options"  // For FindOneAndUpdate options
+)
+
+// deliveryAck is the payload of the "messageDelivered" event a client sends
+// after receiving a newMessage, confirming it reached the device.
+type deliveryAck struct {
+	MessageID string `json:"messageId"`
+}
+
+// markDelivered records that a 1-to-1 message reached one of receiverID's
+// devices, and tells the sender with a "messageDelivered" event. Only the
+// first delivery counts: later ones (other devices, client acks after the Hub
+// already saw it delivered, messages already seen) change nothing. Messages
+// not addressed to receiverID are ignored, so clients can't ack others' messages.
+func (h *Hub) markDelivered(messageID, receiverID primitive.ObjectID) {
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	deliveredAt := time.Now()
+	var message models.Message
+	err := db.DB.Collection("messages").FindOneAndUpdate(ctx,
+		bson.M{
+			"_id":            messageID,
+			"receiverId":     receiverID,
+			"conversationId": bson.M{"$exists": false}, // Delivery isn't tracked for group messages
+			"deliveredAt":    bson.M{"$exists": false},
+			"seen":           bson.M{"$ne": true},
+			"silenced":       bson.M{"$ne": true}, // Never reported delivered
+		},
+		bson.M{"$set": bson.M{"deliveredAt": deliveredAt, "status": models.StatusDelivered, "updatedAt": deliveredAt}},
+		options.FindOneAndUpdate().SetProjection(bson.M{"senderId": 1}),
+	).Decode(&message)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			slog.Error("Error marking message delivered", "message_id", messageID.Hex(), "error", err)
+		}
+		return
+	}
+
+	h.SendToUser(message.SenderID, "messageDelivered", map[string]interface{}{
+		"messageId":   messageID.Hex(),
+		"deliveredTo": receiverID.Hex(),
+		"deliveredAt": deliveredAt,
+	})
+}
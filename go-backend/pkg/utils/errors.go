@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"  // For recognizing requests cancelled by the client
+	"errors"   // For matching upload and body size errors
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+
+	"go-backend/pkg/logging" // Per-request structured logger
+
+	"github.com/gin-gonic/gin" // Gin context for writing responses
+)
+
+// Error codes sent in the "code" field of error responses. They are part of
+// the API: clients branch on the code, while the message is for people and
+// may change. Add new codes rather than repurposing existing ones.
+const (
+	// Request problems
+	ErrCodeInvalidRequestBody = "INVALID_REQUEST_BODY" // Body isn't valid JSON or doesn't match the expected shape
+	ErrCodeValidation         = "VALIDATION_ERROR"     // A field or query param has an invalid value
+	ErrCodeInvalidID          = "INVALID_ID"           // An ID in the URL, query or body isn't a valid ObjectID
+	ErrCodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"    // The request body or an uploaded file exceeds the size limit
+	ErrCodeUnsupportedMedia   = "UNSUPPORTED_MEDIA"    // An uploaded file isn't an accepted type
+	ErrCodeInvalidMedia       = "INVALID_MEDIA"        // An uploaded file is corrupt or too long
+	ErrCodeContentBlocked     = "CONTENT_BLOCKED"      // Message text matched the content filter
+	ErrCodeFeatureDisabled    = "FEATURE_DISABLED"     // The endpoint depends on a feature this server has turned off
+
+	// Authentication and authorization
+	ErrCodeUnauthorized       = "UNAUTHORIZED"        // The request isn't authenticated
+	ErrCodeNoToken            = "NO_TOKEN"            // No access or refresh token was sent
+	ErrCodeInvalidToken       = "INVALID_TOKEN"       // A token (access, refresh, reset or verification) is invalid or revoked
+	ErrCodeTokenExpired       = "TOKEN_EXPIRED"       // The access token or session has expired
+	ErrCodeInvalidCredentials = "INVALID_CREDENTIALS" // Wrong email or password at login
+	ErrCodeIncorrectPassword  = "INCORRECT_PASSWORD"  // Password confirmation of a logged-in user failed
+	ErrCodeEmailNotVerified   = "EMAIL_NOT_VERIFIED"  // Login refused until the email address is verified
+	ErrCodeAccountBanned      = "ACCOUNT_BANNED"      // An admin banned the account; login and its tokens are refused
+	ErrCodeEmailTaken         = "EMAIL_TAKEN"         // Another account already uses the email
+	ErrCodeForbidden          = "FORBIDDEN"           // Authenticated, but not allowed to do this
+	ErrCodeWindowExpired      = "WINDOW_EXPIRED"      // The message is too old to be edited or deleted
+	ErrCodeOriginNotAllowed   = "ORIGIN_NOT_ALLOWED"  // The request didn't come from an allowed frontend origin
+	ErrCodeUserBlocked        = "USER_BLOCKED"        // A block between the two users prevents the action
+	ErrCodeAlreadyReported    = "ALREADY_REPORTED"    // The user has already reported this message
+	ErrCodeRateLimited        = "RATE_LIMITED"        // Too many requests; see the Retry-After header
+
+	// Missing resources
+	ErrCodeUserNotFound         = "USER_NOT_FOUND"
+	ErrCodeMessageNotFound      = "MESSAGE_NOT_FOUND"
+	ErrCodeConversationNotFound = "CONVERSATION_NOT_FOUND"
+
+	// Server problems
+	ErrCodeInternal = "INTERNAL_ERROR"
+)
+
+// internalErrorMessage is all clients learn about a server-side failure.
+const internalErrorMessage = "Something went wrong on our side. Please try again later."
+
+// statusClientClosedRequest is the (nginx) status logged for requests whose
+// client went away before the response, so they don't count as server errors.
+const statusClientClosedRequest = 499
+
+// ErrorBody is the "error" object of an error response.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// ReferenceID is set on internal errors: it is the request ID of the log
+	// line holding the details, for users to quote in bug reports.
+	ReferenceID string `json:"referenceId,omitempty"`
+}
+
+// RespondError writes the standard error response,
+// {"error": {"code": "...", "message": "..."}}, with the given status.
+// Middleware must still call c.Abort() to stop the handler chain.
+func RespondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{"error": ErrorBody{Code: code, Message: message}})
+}
+
+// RespondInternalError logs err with what was being done (e.g. "Error saving
+// message") and writes a 500 response that keeps both to the server: database
+// and SDK errors can reveal internals. The response references the log line
+// through the request ID.
+//
+// An error caused by the client disconnecting (the request context was
+// cancelled; see RequestContext) isn't a server failure: it is logged at info
+// level and answered with 499, which nobody is left to read.
+func RespondInternalError(c *gin.Context, what string, err error) {
+	if errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil {
+		logging.FromContext(c).Info(what+": client disconnected", "error", err)
+		c.AbortWithStatus(statusClientClosedRequest)
+		return
+	}
+	logging.FromContext(c).Error(what, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": ErrorBody{
+		Code:        ErrCodeInternal,
+		Message:     internalErrorMessage,
+		ReferenceID: c.GetString("requestId"),
+	}})
+}
+
+// RespondUploadError reports a failed image or audio upload: rejected files
+// get a 4xx with the reason, failures on our or Cloudinary's side an internal error.
+func RespondUploadError(c *gin.Context, status int, what string, err error) {
+	if status >= http.StatusInternalServerError {
+		RespondInternalError(c, what, err)
+		return
+	}
+	RespondError(c, status, UploadErrorCode(err), fmt.Sprintf("%s: %v", what, err))
+}
+
+// RespondBindError reports a request body that failed to bind: a 413 when it
+// was cut off by BodyLimitMiddleware, otherwise a 400 with code and message.
+func RespondBindError(c *gin.Context, err error, code, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		RespondError(c, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge,
+			fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit))
+		return
+	}
+	RespondError(c, http.StatusBadRequest, code, message)
+}
+
+// UploadErrorCode returns the error code matching an image or audio upload
+// error, like ImageUploadStatus and AudioUploadStatus do for the status.
+func UploadErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrImageTooLarge), errors.Is(err, ErrAudioTooLarge):
+		return ErrCodePayloadTooLarge
+	case errors.Is(err, ErrUnsupportedImageType), errors.Is(err, ErrUnsupportedAudioType):
+		return ErrCodeUnsupportedMedia
+	case errors.Is(err, ErrInvalidImage), errors.Is(err, ErrInvalidAudio), errors.Is(err, ErrAudioTooLong):
+		return ErrCodeInvalidMedia
+	default:
+		return ErrCodeInternal
+	}
+}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"context"       // For Redis command deadlines and stopping the subscription
+	"encoding/json" // For the envelopes exchanged between instances
+	"fmt"           // For formatted errors
+	"log/slog"      // Structured logging
+	"sort"          // For a stable online users list
+	"sync"          // For waiting on the cluster goroutines
+	"time"          // For presence heartbeats
+
+	"go-backend/config"          // For the Redis settings
+	"go-backend/internal/models" // For the Message struct in envelopes
+
+	"github.com/redis/go-redis/v9"               // Redis client (commands and pub/sub)
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// Hub backends, selected with HUB_BACKEND.
+//   - memory: a single instance; deliveries and presence stay in process.
+//   - redis: several instances behind a load balancer. Every delivery is
+//     published on a Redis channel and each instance's Hub hands it to its own
+//     connected clients; online users are shared through a Redis hash.
+//
+// Only deliveries and presence are shared. The rest of the Hub's state stays
+// per instance: who is typing (so typingStates on connect only lists typists
+// connected to the same instance), and whether a user already had a
+// connection, which decides whether missed messages are replayed. A user
+// whose other device is on another instance therefore gets a replay; direct
+// messages that device already received are marked delivered and skipped,
+// but group messages since their last disconnect are sent again.
+const (
+	HubBackendMemory = "memory"
+	HubBackendRedis  = "redis"
+)
+
+// redisTimeout bounds each Redis command.
+const redisTimeout = 5 * time.Second
+
+// Presence heartbeats: each instance rewrites its entry of the presence hash
+// every presenceHeartbeat, and an entry not refreshed within presenceTTL
+// (the instance crashed) is ignored and removed by the others.
+const (
+	presenceHeartbeat = 10 * time.Second
+	presenceTTL       = 3 * presenceHeartbeat
+)
+
+// Envelope types.
+const (
+	envelopeMessage    = "message"    // A new message for its recipients (and the sender's other devices)
+	envelopeEvent      = "event"      // An event for one user (UserID) or several (Recipients)
+	envelopeBroadcast  = "broadcast"  // An event for every connected user
+	envelopeDisconnect = "disconnect" // Close all of a user's connections
+	envelopePresence   = "presence"   // Some instance's online users changed; refetch the merged list
+)
+
+// hubEnvelope is a delivery published to every instance. Each instance applies
+// it to its own clients, so it reaches the users wherever they're connected.
+type hubEnvelope struct {
+	Type       string               `json:"type"`
+	Message    *models.Message      `json:"message,omitempty"`
+	Recipients []primitive.ObjectID `json:"recipients,omitempty"`
+	UserID     primitive.ObjectID   `json:"userId"`
+	Event      *WebSocketMessage    `json:"event,omitempty"`
+	Code       int                  `json:"code,omitempty"`
+	Reason     string               `json:"reason,omitempty"`
+}
+
+// presenceEntry is one instance's value in the presence hash. Statuses was
+// added later: users missing from it (entries written by an older instance)
+// are online.
+type presenceEntry struct {
+	Users     []string          `json:"users"`
+	Statuses  map[string]string `json:"statuses,omitempty"` // userId -> status
+	ExpiresAt int64             `json:"expiresAt"`          // Unix milliseconds
+}
+
+// redisCluster connects a Hub to the Hubs of other instances through Redis.
+type redisCluster struct {
+	hub         *Hub
+	client      *redis.Client
+	instanceID  string
+	channel     string // Pub/sub channel carrying hubEnvelopes
+	presenceKey string // Hash of instanceID -> presenceEntry
+
+	// snapshots holds the latest local online users list not yet written to
+	// Redis. Capacity 1: a newer snapshot replaces an unwritten older one.
+	snapshots chan []OnlineUser
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// newRedisCluster connects to REDIS_URL. An invalid URL or an unreachable
+// server is a fatal configuration error, like an unreachable MongoDB.
+func newRedisCluster(cfg *config.Config, hub *Hub) (*redisCluster, error) {
+	options, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("Redis is unreachable: %w", err)
+	}
+	return &redisCluster{
+		hub:         hub,
+		client:      client,
+		instanceID:  primitive.NewObjectID().Hex(),
+		channel:     cfg.RedisKeyPrefix + ":hub",
+		presenceKey: cfg.RedisKeyPrefix + ":presence",
+		snapshots:   make(chan []OnlineUser, 1),
+		stop:        make(chan struct{}),
+	}, nil
+}
+
+// start runs the subscription and the presence writer.
+func (rc *redisCluster) start() {
+	rc.stopped.Add(2)
+	go func() {
+		defer rc.stopped.Done()
+		rc.subscribe()
+	}()
+	go func() {
+		defer rc.stopped.Done()
+		rc.presenceLoop()
+	}()
+	slog.Info("WebSocket Hub clustered through Redis", "instance_id", rc.instanceID, "channel", rc.channel)
+}
+
+// close removes this instance's online users from the shared list and stops
+// the cluster goroutines. Call it once the Hub's Run loop has stopped.
+func (rc *redisCluster) close() {
+	close(rc.stop)
+	rc.stopped.Wait()
+	rc.client.Close()
+}
+
+// publish sends an envelope to every instance, this one included.
+func (rc *redisCluster) publish(envelope hubEnvelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return rc.client.Publish(ctx, rc.channel, data).Err()
+}
+
+// subscribe applies the envelopes published on the channel until stop is
+// closed. The client resubscribes by itself when its connection drops; every
+// (re)subscription refetches presence, since anything published in between
+// was missed.
+func (rc *redisCluster) subscribe() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-rc.stop
+		cancel()
+	}()
+	pubsub := rc.client.Subscribe(ctx, rc.channel)
+	defer pubsub.Close()
+
+	backoff := 100 * time.Millisecond
+	for {
+		received, err := pubsub.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("Redis subscription failed; retrying", "error", err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff < 10*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = 100 * time.Millisecond
+		switch received := received.(type) {
+		case *redis.Subscription:
+			rc.refreshPresence()
+		case *redis.Message:
+			rc.handleEnvelope([]byte(received.Payload))
+		}
+	}
+}
+
+// handleEnvelope applies an envelope published by any instance.
+func (rc *redisCluster) handleEnvelope(payload []byte) {
+	var envelope hubEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		slog.Error("Ignoring malformed Hub envelope from Redis", "error", err)
+		return
+	}
+	if envelope.Type == envelopePresence {
+		rc.refreshPresence()
+		return
+	}
+	rc.hub.deliverLocally(envelope)
+}
+
+// setLocalPresence queues this instance's online users to be written to
+// Redis, replacing a snapshot that hasn't been written yet. Never blocks, so
+// the Run loop can call it.
+func (rc *redisCluster) setLocalPresence(users []OnlineUser) {
+	for {
+		select {
+		case rc.snapshots <- users:
+			return
+		default:
+		}
+		select {
+		case <-rc.snapshots: // Drop the stale snapshot and try again
+		default:
+		}
+	}
+}
+
+// presenceLoop writes local presence snapshots to Redis and announces them,
+// and keeps this instance's entry alive with heartbeats.
+func (rc *redisCluster) presenceLoop() {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+	var users []OnlineUser
+	for {
+		select {
+		case users = <-rc.snapshots:
+			if err := rc.writePresence(users); err != nil {
+				slog.Error("Error publishing online users to Redis", "error", err)
+				continue
+			}
+			rc.announcePresence()
+
+		case <-ticker.C:
+			if err := rc.writePresence(users); err != nil {
+				slog.Error("Error refreshing online users in Redis", "error", err)
+			}
+			// Drop the entries of instances that stopped heartbeating.
+			if removed, err := rc.pruneExpired(); err != nil {
+				slog.Error("Error pruning expired online users in Redis", "error", err)
+			} else if removed {
+				rc.announcePresence()
+			}
+
+		case <-rc.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+			err := rc.client.HDel(ctx, rc.presenceKey, rc.instanceID).Err()
+			cancel()
+			if err != nil {
+				slog.Error("Error removing online users from Redis", "error", err)
+				return
+			}
+			rc.announcePresence()
+			return
+		}
+	}
+}
+
+// writePresence stores this instance's online users with a fresh expiry.
+func (rc *redisCluster) writePresence(users []OnlineUser) error {
+	entry := presenceEntry{
+		Users:     onlineUserIDs(users),
+		Statuses:  make(map[string]string, len(users)),
+		ExpiresAt: time.Now().Add(presenceTTL).UnixMilli(),
+	}
+	for _, user := range users {
+		entry.Statuses[user.UserID] = user.Status
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return rc.client.HSet(ctx, rc.presenceKey, rc.instanceID, data).Err()
+}
+
+// announcePresence tells every instance to refetch the merged online users list.
+func (rc *redisCluster) announcePresence() {
+	if err := rc.publish(hubEnvelope{Type: envelopePresence}); err != nil {
+		slog.Error("Error announcing presence change through Redis", "error", err)
+	}
+}
+
+// presenceEntries reads the presence hash, split into live entries and the
+// IDs of instances whose entry has expired.
+func (rc *redisCluster) presenceEntries() ([]presenceEntry, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	fields, err := rc.client.HGetAll(ctx, rc.presenceKey).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UnixMilli()
+	var live []presenceEntry
+	var expired []string
+	for instanceID, value := range fields {
+		var entry presenceEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil || entry.ExpiresAt < now {
+			expired = append(expired, instanceID)
+			continue
+		}
+		live = append(live, entry)
+	}
+	return live, expired, nil
+}
+
+// pruneExpired removes expired presence entries, reporting whether there were any.
+func (rc *redisCluster) pruneExpired() (bool, error) {
+	_, expired, err := rc.presenceEntries()
+	if err != nil || len(expired) == 0 {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	err = rc.client.HDel(ctx, rc.presenceKey, expired...).Err()
+	return err == nil, err
+}
+
+// refreshPresence fetches the online users of every instance and hands the
+// merged list to the Run loop, which sends it to this instance's clients.
+func (rc *redisCluster) refreshPresence() {
+	entries, _, err := rc.presenceEntries()
+	if err != nil {
+		slog.Error("Error fetching online users from Redis", "error", err)
+		return
+	}
+	// A user connected to several instances has a status on each; the most
+	// available one wins.
+	statuses := make(map[string]string)
+	for _, entry := range entries {
+		for _, userID := range entry.Users {
+			status := entry.Statuses[userID]
+			if status == "" {
+				status = PresenceOnline
+			}
+			if current, seen := statuses[userID]; !seen || presenceRank(status) > presenceRank(current) {
+				statuses[userID] = status
+			}
+		}
+	}
+	online := make([]OnlineUser, 0, len(statuses))
+	for userID, status := range statuses {
+		online = append(online, OnlineUser{UserID: userID, Status: status})
+	}
+	sort.Slice(online, func(i, j int) bool { return online[i].UserID < online[j].UserID })
+	select {
+	case rc.hub.clusterPresence <- online:
+	case <-rc.hub.done:
+	case <-rc.stop:
+	}
+}
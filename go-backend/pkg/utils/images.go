@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/base64" // For decoding the image payload
+	"errors"          // For the sentinel validation errors
+	"fmt"             // For formatted error messages
+	"net/http"        // For sniffing the decoded content type and status codes
+	"strings"         // For parsing the data URI
+)
+
+// Image validation errors returned by UploadImage before anything is sent to
+// Cloudinary. Handlers map them to a status code with ImageUploadStatus.
+var (
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrUnsupportedImageType = errors.New("unsupported image type")
+	ErrInvalidImage         = errors.New("invalid image data")
+)
+
+// allowedImageTypes are the MIME types accepted for uploads.
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// validateImageDataURI checks a "data:<mime>;base64,<data>" image: the declared
+// MIME type must be allowed, the payload must decode, its decoded size must not
+// exceed maxBytes (0 = no limit), and its content must really be an image of an
+// allowed type (the declared type alone is easy to fake).
+func validateImageDataURI(dataURI string, maxBytes int) error {
+	header, data, found := strings.Cut(dataURI, ",")
+	if !found || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return fmt.Errorf("%w: expected a base64 data URI", ErrInvalidImage)
+	}
+	mimeType := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64"))
+	if !allowedImageTypes[mimeType] {
+		return fmt.Errorf("%w: %q", ErrUnsupportedImageType, mimeType)
+	}
+
+	// Reject oversized payloads before decoding, so we never allocate for them.
+	if maxBytes > 0 && base64.StdEncoding.DecodedLen(len(data)) > maxBytes+2 {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrImageTooLarge, maxBytes)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if maxBytes > 0 && len(decoded) > maxBytes {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrImageTooLarge, maxBytes)
+	}
+
+	if sniffed := http.DetectContentType(decoded); !allowedImageTypes[sniffed] {
+		return fmt.Errorf("%w: content is %q", ErrUnsupportedImageType, sniffed)
+	}
+	return nil
+}
+
+// ImageUploadStatus returns the HTTP status for an UploadImage error:
+// 413 for an oversized image, 400 for an invalid or disallowed one, and 500
+// for anything else (i.e. Cloudinary failing).
+func ImageUploadStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnsupportedImageType), errors.Is(err, ErrInvalidImage):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"fmt"        // For formatted error messages
-	//"net/http"   // REQUIRED for http.SameSiteStrictMode and other HTTP constants
 	"time"       // For token expiration
 
 	"go-backend/config" // Import your config package to get JWT_SECRET. IMPORTANT: Replace "go-backend" with your actual Go module name from go.mod
@@ -11,12 +10,27 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive" // For handling ObjectID from user ID
 )
 
+// RefreshTokenAudience is the `aud` claim of refresh tokens. Access tokens have
+// no audience, so AuthMiddleware can refuse a refresh token presented in its place.
+const RefreshTokenAudience = "refresh"
+
 // Claims defines the structure of our JWT claims.
 // It embeds jwt.RegisteredClaims for standard JWT fields like Issuer, ExpiresAt, etc.
 // UserID is a custom claim to store the user's MongoDB ObjectID.
+// TokenVersion is the user's TokenVersion when the token was issued; tokens
+// from before a "log out everywhere" carry an older version and are refused.
 type Claims struct {
-	UserID primitive.ObjectID `json:"userId"` // Custom claim to store the user's ID
-	jwt.RegisteredClaims     // Standard JWT claims (e.g., expiration, issued at, subject)
+	UserID       primitive.ObjectID `json:"userId"`       // Custom claim to store the user's ID
+	TokenVersion int                `json:"tv,omitempty"` // User.TokenVersion at issue time
+	jwt.RegisteredClaims            // Standard JWT claims (e.g., expiration, issued at, subject)
+}
+
+// RefreshClaims are the claims of a refresh token. SessionID points at the
+// document in the "sessions" collection that must still exist for the token to be honored.
+type RefreshClaims struct {
+	UserID    primitive.ObjectID `json:"userId"`
+	SessionID primitive.ObjectID `json:"sid"`
+	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a JWT and sets it as an HTTP-only cookie.
@@ -24,13 +38,15 @@ type Claims struct {
 
 // Parameters:
 //   userID: The MongoDB ObjectID of the user for whom the token is being generated.
+//   tokenVersion: The user's current TokenVersion, embedded so the token can be revoked.
 //   c: The Gin context, used to set the HTTP cookie in the response.
 //   cfg: A pointer to the application's configuration, containing the JWT secret.
 
 // Returns: An error if token generation or cookie setting fails, otherwise nil.
-func GenerateToken(userID primitive.ObjectID, c *gin.Context, cfg *config.Config) error {
-	// Define the expiration time for the token (7 days from now).
-	expirationTime := time.Now().Add(7 * 24 * time.Hour)
+func GenerateToken(userID primitive.ObjectID, tokenVersion int, c *gin.Context, cfg *config.Config) error {
+	// Define the expiration time for the token (JWT_EXPIRY from now).
+	// Clients renew it through POST /api/auth/refresh before it runs out.
+	expirationTime := time.Now().Add(cfg.JWTExpiry)
 
 	// Create the JWT claims payload.
 	// The `UserID` field of our custom `Claims` struct is populated with the provided `userID`.
@@ -39,7 +55,8 @@ func GenerateToken(userID primitive.ObjectID, c *gin.Context, cfg *config.Config
 	//   - `IssuedAt`: The time when the token was created.
 	//   - `Subject`: A unique identifier for the subject of the token. Here, we use the hex string of the `userID`.
 	claims := &Claims{
-		UserID: userID,
+		UserID:       userID,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -63,28 +80,48 @@ func GenerateToken(userID primitive.ObjectID, c *gin.Context, cfg *config.Config
 	// Set the JWT as an HTTP-only cookie in the Gin context's response.
 	// This is critical for security:
 	//   - `httpOnly: true` prevents client-side JavaScript from accessing the cookie, mitigating XSS attacks.
-	//
-	// Parameters for `c.SetCookie`:
-	//   - `name`: "jwt" (This must match the cookie name your frontend expects).
-	//   - `value`: The `signedToken` string.
-	//   - `maxAge`: The maximum age of the cookie in seconds. We convert 7 days duration to seconds.
-	//   - `path`: "/" (The cookie is valid for all paths on the domain).
-	//   - `domain`: "" (An empty string means the cookie is valid for the current host only).
-	//   - `secure`: `cfg.NodeEnv == "production"` (The `Secure` flag ensures the cookie is only sent over HTTPS.
-	//     It's `true` in production, `false` in development for easier local testing).
-	//   - `httpOnly`: `true` (Makes the cookie inaccessible to JavaScript).
-	//   - `sameSite`: `http.SameSiteStrictMode` (The strictest SameSite policy).
-	// CORRECTED: Removed http.SameSiteStrictMode as it's not accepted by this Gin SetCookie signature.
-	c.SetCookie(
-		"jwt",
-		signedToken,
-		int(7*24*time.Hour/time.Second), // Convert 7 days duration to seconds
-		"/",
-		"",
-		cfg.NodeEnv == "production", // Secure flag: true if in production, false otherwise
-		true,                        // HttpOnly flag: true
-		// http.SameSiteStrictMode,     // COMMENTED OUT: SameSite flag. This argument is causing the error.
-	)
+	//   - The SameSite mode comes from COOKIE_SAMESITE (Lax by default); see SetAuthCookie,
+	//     which also sets the `Secure` flag in production or when SameSite is None.
+	//   - `maxAge` matches the token's lifetime; `path` "/" makes it valid for all paths.
+	SetAuthCookie(c, cfg, "jwt", signedToken, int(cfg.JWTExpiry/time.Second), "/")
 
 	return nil // Return nil if token generation and cookie setting were successful
 }
+
+// GenerateRefreshToken signs a refresh token for the given session.
+// Unlike GenerateToken it doesn't set a cookie: the caller stores the session
+// (and a hash of the token) first, then sets the cookie itself.
+func GenerateRefreshToken(userID, sessionID primitive.ObjectID, expiresAt time.Time, cfg *config.Config) (string, error) {
+	claims := &RefreshClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   userID.Hex(),
+			Audience:  jwt.ClaimStrings{RefreshTokenAudience},
+		},
+	}
+
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign refresh token: %w", err)
+	}
+	return signedToken, nil
+}
+
+// ParseRefreshToken validates a refresh token's signature, expiry and audience
+// and returns its claims. It does not check that the session still exists.
+func ParseRefreshToken(tokenString string, cfg *config.Config) (*RefreshClaims, error) {
+	claims := &RefreshClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(cfg.JWTSecret), nil
+	}, jwt.WithAudience(RefreshTokenAudience))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
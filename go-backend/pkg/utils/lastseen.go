@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"log/slog" // For logging errors
+	"time"     // For the timestamp and the query timeout
+
+	"go-backend/pkg/db" // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// RecordLastSeen stores seenAt as the user's lastSeen time. It is called
+// from the Hub's Run loop in its own goroutine, so a slow database never
+// stalls the Hub. Those goroutines may finish out of order; the filter only
+// ever moves lastSeen forward, so an older timestamp can't overwrite a newer one.
+func RecordLastSeen(userID primitive.ObjectID, seenAt time.Time) {
+	ctx, cancel := db.Context()
+	defer cancel()
+
+	filter := bson.M{
+		"_id": userID,
+		"$or": []bson.M{
+			{"lastSeen": bson.M{"$exists": false}},
+			{"lastSeen": bson.M{"$lt": seenAt}},
+		},
+	}
+	if _, err := db.DB.Collection("users").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"lastSeen": seenAt}}); err != nil {
+		slog.Error("Error recording last seen", "user_id", userID.Hex(), "error", err)
+	}
+}
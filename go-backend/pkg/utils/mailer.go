@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"log/slog" // For writing emails to the server log
+)
+
+// Mailer sends transactional emails (password resets, ...).
+// Handlers depend on this interface so a real provider can be plugged in later
+// without touching them.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer is the stand-in Mailer used until a mail provider is configured:
+// it writes each email to the server log instead of sending it.
+// Emails may contain secrets such as reset links, so don't use it in production.
+type LogMailer struct{}
+
+// NewLogMailer creates a LogMailer.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the email and never fails.
+func (m *LogMailer) Send(to, subject, body string) error {
+	slog.Info("Email", "to", to, "subject", subject, "body", body)
+	return nil
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"time" // For formatting timestamps
+
+	"go-backend/internal/models" // Import models for the Message struct
+)
+
+// timestampLayout is RFC 3339 with millisecond precision, the format of
+// JavaScript's Date.toISOString(). Timestamps are always written in UTC.
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Timestamp is a time that marshals to JSON as an RFC 3339 UTC string with
+// milliseconds ("2024-05-01T12:00:00.000Z"), or null when it is the zero time.
+type Timestamp time.Time
+
+// MarshalJSON implements json.Marshaler.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	tm := time.Time(t)
+	if tm.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + tm.UTC().Format(timestampLayout) + `"`), nil
+}
+
+// MessageResponse is the JSON shape of a message in every API response and
+// WebSocket event: ObjectIDs as hex strings, timestamps as Timestamp (null
+// when unset). Build it with NewMessageResponse.
+type MessageResponse struct {
+	ID             string `json:"_id"`
+	ConversationID string `json:"conversationId"`
+	IsGroup        bool   `json:"isGroup"`
+	SenderID       string `json:"senderId"`
+	ReceiverID     string `json:"receiverId"` // "" for group messages, which have no single receiver
+
+	Text          string  `json:"text"`
+	Image         string  `json:"image"`
+	ImageWidth    int     `json:"imageWidth"`
+	ImageHeight   int     `json:"imageHeight"`
+	ThumbnailURL  string  `json:"thumbnailUrl"` // Small version of Image; "" when there is none
+	Audio         string  `json:"audio"`
+	AudioDuration float64 `json:"audioDuration"`
+	Priority      string  `json:"priority"`
+
+	ReplyTo      *string               `json:"replyTo"`      // null unless the message is a reply
+	ReplyPreview *ReplyPreviewResponse `json:"replyPreview"` // null unless the message is a reply
+	Mentions     []string              `json:"mentions"`     // IDs of the @mentioned users; [] if none
+	Forwarded    bool                  `json:"forwarded"`
+	System       bool                  `json:"system"`
+	SystemType   string                `json:"systemType"`
+
+	Status      string    `json:"status"` // sent, delivered or seen
+	DeliveredAt Timestamp `json:"deliveredAt"`
+	Seen        bool      `json:"seen"`
+	SeenAt      Timestamp `json:"seenAt"`
+	Edited      bool      `json:"edited"`
+	EditedAt    Timestamp `json:"editedAt"`
+	Deleted     bool      `json:"deleted"`
+	DeletedAt   Timestamp `json:"deletedAt"`
+	Pinned      bool      `json:"pinned"`
+	PinnedBy    *string   `json:"pinnedBy"` // null unless pinned
+	PinnedAt    Timestamp `json:"pinnedAt"`
+	ExpiresAt   Timestamp `json:"expiresAt"` // null unless the message disappears
+
+	Reactions []ReactionSummary `json:"reactions"`
+
+	// Integrity is the signature check ("valid", "tampered", ...), only set by
+	// the handlers that verify signatures.
+	Integrity string `json:"integrity,omitempty"`
+
+	CreatedAt Timestamp `json:"createdAt"`
+	UpdatedAt Timestamp `json:"updatedAt"`
+}
+
+// ReplyPreviewResponse is the quoted part of a replied-to message.
+type ReplyPreviewResponse struct {
+	SenderID string `json:"senderId"`
+	Text     string `json:"text"`
+	HasImage bool   `json:"hasImage"`
+	HasAudio bool   `json:"hasAudio"`
+	Deleted  bool   `json:"deleted"` // The quoted message was deleted; text is empty
+}
+
+// ReactionSummary is one emoji's reactions to a message: how many, and who
+// reacted (so clients can highlight the caller's own reaction).
+type ReactionSummary struct {
+	Emoji   string   `json:"emoji"`
+	Count   int      `json:"count"`
+	UserIDs []string `json:"userIds"`
+}
+
+// NewMessageResponse converts a stored message into its response shape.
+func NewMessageResponse(msg models.Message) MessageResponse {
+	response := MessageResponse{
+		ID:             msg.ID.Hex(),
+		ConversationID: MessageConversationID(msg),
+		IsGroup:        msg.IsGroupMessage(),
+		SenderID:       msg.SenderID.Hex(),
+		Text:           msg.Text,
+		Image:          msg.Image,
+		ImageWidth:     msg.ImageWidth,
+		ImageHeight:    msg.ImageHeight,
+		ThumbnailURL:   msg.ThumbnailURL,
+		Audio:          msg.Audio,
+		AudioDuration:  msg.AudioDuration,
+		Priority:       msg.Priority,
+		Forwarded:      msg.Forwarded,
+		System:         msg.System,
+		SystemType:     msg.SystemType,
+		Status:         msg.DeliveryStatus(),
+		DeliveredAt:    Timestamp(msg.DeliveredAt),
+		Seen:           msg.Seen,
+		SeenAt:         Timestamp(msg.SeenAt),
+		Edited:         msg.Edited,
+		EditedAt:       Timestamp(msg.EditedAt),
+		Deleted:        msg.Deleted,
+		DeletedAt:      Timestamp(msg.DeletedAt),
+		Pinned:         msg.Pinned,
+		ExpiresAt:      Timestamp(msg.ExpiresAt),
+		Reactions:      ReactionSummaries(msg.Reactions),
+		Mentions:       make([]string, len(msg.Mentions)),
+		CreatedAt:      Timestamp(msg.CreatedAt),
+		UpdatedAt:      Timestamp(msg.UpdatedAt),
+	}
+	if !msg.IsGroupMessage() {
+		response.ReceiverID = msg.ReceiverID.Hex()
+	}
+	for i, userID := range msg.Mentions {
+		response.Mentions[i] = userID.Hex()
+	}
+	if response.Priority == "" {
+		response.Priority = models.PriorityNormal // Stored before priorities existed
+	}
+	if msg.ReplyTo != nil {
+		replyTo := msg.ReplyTo.Hex()
+		response.ReplyTo = &replyTo
+		if preview := msg.ReplyPreview; preview != nil {
+			response.ReplyPreview = &ReplyPreviewResponse{
+				SenderID: preview.SenderID.Hex(),
+				Text:     preview.Text,
+				HasImage: preview.HasImage,
+				HasAudio: preview.HasAudio,
+				Deleted:  preview.Deleted,
+			}
+		}
+	}
+	if msg.Pinned {
+		pinnedBy := msg.PinnedBy.Hex()
+		response.PinnedBy = &pinnedBy
+		response.PinnedAt = Timestamp(msg.PinnedAt)
+	}
+	return response
+}
+
+// NewMessageResponses converts a slice of messages, preserving order. Never nil.
+func NewMessageResponses(messages []models.Message) []MessageResponse {
+	response := make([]MessageResponse, len(messages))
+	for i, msg := range messages {
+		response[i] = NewMessageResponse(msg)
+	}
+	return response
+}
+
+// ReactionSummaries aggregates reactions: one entry per emoji, in order of
+// first use. Never nil.
+func ReactionSummaries(reactions []models.Reaction) []ReactionSummary {
+	summary := []ReactionSummary{}
+	index := map[string]int{}
+	for _, reaction := range reactions {
+		i, seen := index[reaction.Emoji]
+		if !seen {
+			i = len(summary)
+			index[reaction.Emoji] = i
+			summary = append(summary, ReactionSummary{Emoji: reaction.Emoji, UserIDs: []string{}})
+		}
+		summary[i].Count++
+		summary[i].UserIDs = append(summary[i].UserIDs, reaction.UserID.Hex())
+	}
+	return summary
+}
+
+// MessageConversationID returns the ID clients use to route a message: the
+// group Conversation's ID, or the derived ID of the 1-to-1 chat.
+func MessageConversationID(msg models.Message) string {
+	if msg.IsGroupMessage() {
+		return msg.ConversationID.Hex()
+	}
+	return ConversationIDFor(msg.SenderID, msg.ReceiverID)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"strings" // For splitting and trimming list values
+)
+
+// AnyOrigin in ALLOWED_ORIGINS allows every origin. Meant for development only:
+// with credentialed CORS it lets any site make authenticated requests.
+const AnyOrigin = "*"
+
+// SplitCommaList splits a comma-separated config value, trimming whitespace
+// and dropping empty entries. An empty value yields an empty (nil) list.
+func SplitCommaList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// OriginAllowed reports whether origin matches one of the allowed origins
+// (exactly, ignoring a trailing slash) or the list contains AnyOrigin.
+// An empty origin never matches.
+func OriginAllowed(allowedOrigins []string, origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range allowedOrigins {
+		if allowed == AnyOrigin || strings.TrimSuffix(allowed, "/") == origin {
+			return true
+		}
+	}
+	return false
+}
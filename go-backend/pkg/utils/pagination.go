@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"      // For formatted error messages
+	"net/http" // For HTTP status codes
+	"strconv"  // For parsing query params
+
+	"github.com/gin-gonic/gin" // Gin context for reading query params and writing errors
+)
+
+// ParseLimit reads the optional `limit` query param.
+// A missing value yields defaultLimit. A value that isn't a positive integer, or
+// that exceeds maxLimit, is rejected with a 400: we deliberately don't clamp
+// silently, so clients find out their request wasn't honored.
+// Returns false if a response has already been written.
+func ParseLimit(c *gin.Context, defaultLimit, maxLimit int) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		RespondError(c, http.StatusBadRequest, ErrCodeValidation, "limit must be a positive integer")
+		return 0, false
+	}
+	if limit > maxLimit {
+		RespondError(c, http.StatusBadRequest, ErrCodeValidation, fmt.Sprintf("limit must be at most %d", maxLimit))
+		return 0, false
+	}
+	return limit, true
+}
+
+// ParsePage reads the optional 1-based `page` query param (default 1).
+// Returns false if a 400 response has already been written.
+func ParsePage(c *gin.Context) (int, bool) {
+	raw := c.Query("page")
+	if raw == "" {
+		return 1, true
+	}
+	page, err := strconv.Atoi(raw)
+	if err != nil || page <= 0 {
+		RespondError(c, http.StatusBadRequest, ErrCodeValidation, "page must be a positive integer")
+		return 0, false
+	}
+	return page, true
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"      // For the error message
+	"net/http" // For HTTP status codes
+
+	"github.com/gin-gonic/gin"                   // Gin context and handler types
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// objectIDParamKey is the context key under which ValidateObjectIDParam
+// stores a parsed path parameter.
+func objectIDParamKey(param string) string {
+	return "objectIDParam:" + param
+}
+
+// ValidateObjectIDParam parses the :param path parameter as an ObjectID and
+// stores it for ObjectIDParam. A malformed ID is refused with a 400
+// INVALID_ID before the handler runs. label names what the ID refers to in
+// the error message ("message" -> "Invalid message ID format").
+func ValidateObjectIDParam(param, label string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(c.Param(param))
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, ErrCodeInvalidID, fmt.Sprintf("Invalid %s ID format", label))
+			c.Abort()
+			return
+		}
+		c.Set(objectIDParamKey(param), id)
+		c.Next()
+	}
+}
+
+// ObjectIDParam returns the :param path parameter parsed by
+// ValidateObjectIDParam. It panics if the route doesn't use that middleware,
+// which is a wiring mistake rather than a client error.
+func ObjectIDParam(c *gin.Context, param string) primitive.ObjectID {
+	return c.MustGet(objectIDParamKey(param)).(primitive.ObjectID)
+}
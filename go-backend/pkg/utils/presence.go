@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"time" // For the inactivity timeout
+
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// Presence statuses. A connected user is online, away or do-not-disturb;
+// users who aren't connected are simply absent from the online users list.
+const (
+	PresenceOnline       = "online"
+	PresenceAway         = "away"
+	PresenceDoNotDisturb = "dnd"
+)
+
+// validPresenceStatus reports whether status can be chosen with setStatus.
+func validPresenceStatus(status string) bool {
+	switch status {
+	case PresenceOnline, PresenceAway, PresenceDoNotDisturb:
+		return true
+	}
+	return false
+}
+
+// presenceRank orders statuses by availability, for merging a user's
+// statuses across instances: do-not-disturb was chosen deliberately and wins,
+// then online, then away.
+func presenceRank(status string) int {
+	switch status {
+	case PresenceDoNotDisturb:
+		return 2
+	case PresenceOnline:
+		return 1
+	}
+	return 0
+}
+
+// OnlineUser is one entry of the status-aware getOnlineUsers payload.
+type OnlineUser struct {
+	UserID string `json:"userId"`
+	Status string `json:"status"`
+}
+
+// onlineUserIDs strips the statuses off an online users list, for clients
+// that only understand the original payload (a list of IDs).
+func onlineUserIDs(users []OnlineUser) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.UserID
+	}
+	return ids
+}
+
+// userPresence is the presence of a connected user (or one inside their
+// offline grace period), shared by all of their connections.
+type userPresence struct {
+	status       string    // Chosen with setStatus; online when they connect
+	lastActivity time.Time // Last event received from any of their connections
+	idle         bool      // Shown as away after PRESENCE_AWAY_AFTER without activity
+}
+
+// effectiveStatus is the status other users see: an idle online user is away.
+// Away and do-not-disturb were chosen explicitly and are left alone.
+func (p *userPresence) effectiveStatus() string {
+	if p.status == PresenceOnline && p.idle {
+		return PresenceAway
+	}
+	return p.status
+}
+
+// presenceUpdate reports activity from one of a user's connections to the
+// Run loop, optionally choosing a new status (empty = activity only).
+type presenceUpdate struct {
+	userID primitive.ObjectID
+	status string
+}
+
+// touchPresence records activity of a connected user and applies a chosen
+// status, reporting whether what others see changed. Must be called with mu held.
+func (h *Hub) touchPresence(update presenceUpdate) bool {
+	p, ok := h.presence[update.userID]
+	if !ok {
+		return false // Disconnected in the meantime
+	}
+	before := p.effectiveStatus()
+	p.lastActivity = time.Now()
+	p.idle = false
+	if update.status != "" {
+		p.status = update.status
+	}
+	return p.effectiveStatus() != before
+}
+
+// markIdleUsers flags online users without activity for awayAfter as away,
+// reporting whether anyone changed. Must be called with mu held.
+func (h *Hub) markIdleUsers(awayAfter time.Duration) bool {
+	changed := false
+	cutoff := time.Now().Add(-awayAfter)
+	for _, p := range h.presence {
+		if p.status == PresenceOnline && !p.idle && p.lastActivity.Before(cutoff) {
+			p.idle = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// statusOf returns a user's effective status, online if they have no presence
+// recorded. Must be called with mu held.
+func (h *Hub) statusOf(userID primitive.ObjectID) string {
+	if p, ok := h.presence[userID]; ok {
+		return p.effectiveStatus()
+	}
+	return PresenceOnline
+}
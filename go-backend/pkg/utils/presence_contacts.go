@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"context"  // For context with MongoDB operations
+	"log/slog" // Structured logging
+
+	"go-backend/pkg/db" // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// With PRESENCE_MODE=contacts-only a user only sees the presence of their
+// contacts: the users they have exchanged 1-to-1 messages with. The relation
+// is symmetric, so nobody sees someone who can't see them. The Hub keeps the
+// contact set of each connected user, loaded from the messages collection on
+// connect and extended as new 1-to-1 messages go through it.
+
+// contactsLoaded hands a user's contact set, loaded off the Run goroutine, to the Run loop.
+type contactsLoaded struct {
+	userID   primitive.ObjectID
+	contacts map[primitive.ObjectID]bool
+}
+
+// loadPresenceContacts returns the users userID has sent 1-to-1 messages to
+// or received them from. Both lookups are served by the sender/receiver indexes.
+func loadPresenceContacts(ctx context.Context, userID primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	messages := db.DB.Collection("messages")
+	contacts := make(map[primitive.ObjectID]bool)
+
+	receivers, err := messages.Distinct(ctx, "receiverId", bson.M{"senderId": userID})
+	if err != nil {
+		return nil, err
+	}
+	senders, err := messages.Distinct(ctx, "senderId", bson.M{"receiverId": userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range append(receivers, senders...) {
+		// Group messages have no receiver; their zero receiverId is skipped.
+		if id, ok := value.(primitive.ObjectID); ok && !id.IsZero() && id != userID {
+			contacts[id] = true
+		}
+	}
+	return contacts, nil
+}
+
+// fetchContacts loads a newly connected user's contacts and passes them to
+// the Run loop. If loading fails the user only sees themselves until they reconnect.
+func (h *Hub) fetchContacts(userID primitive.ObjectID) {
+	ctx, cancel := db.Context()
+	defer cancel()
+	contacts, err := loadPresenceContacts(ctx, userID)
+	if err != nil {
+		slog.Error("Error loading presence contacts", "user_id", userID.Hex(), "error", err)
+		return
+	}
+	select {
+	case h.contactsLoaded <- contactsLoaded{userID: userID, contacts: contacts}:
+	case <-h.done:
+	}
+}
+
+// addContacts records that a and b are now contacts, for whichever of them is
+// connected, reporting whether anything changed. Must be called with mu held.
+func (h *Hub) addContacts(a, b primitive.ObjectID) bool {
+	changed := false
+	if contacts := h.contacts[a]; contacts != nil && !contacts[b] {
+		contacts[b] = true
+		changed = true
+	}
+	if contacts := h.contacts[b]; contacts != nil && !contacts[a] {
+		contacts[a] = true
+		changed = true
+	}
+	return changed
+}
+
+// visibleTo filters an online users list down to what userID may see: in
+// contacts-only mode, themselves and their contacts. Must be called with mu held.
+func (h *Hub) visibleTo(userID primitive.ObjectID, onlineUsers []OnlineUser) []OnlineUser {
+	if h.presenceMode != PresenceModeContactsOnly {
+		return onlineUsers
+	}
+	contacts := h.contacts[userID] // nil while still loading
+	visible := make([]OnlineUser, 0, len(contacts)+1)
+	self := userID.Hex()
+	for _, user := range onlineUsers {
+		if user.UserID == self {
+			visible = append(visible, user)
+			continue
+		}
+		if id, err := primitive.ObjectIDFromHex(user.UserID); err == nil && contacts[id] {
+			visible = append(visible, user)
+		}
+	}
+	return visible
+}
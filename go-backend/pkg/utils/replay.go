@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"       // For the query context
+	"encoding/json" // For marshaling the replayed events
+	"log/slog"      // Structured logging
+	"time"          // For the expiry check
+
+	"go-backend/internal/models" // Import models for the Message and User structs
+	"go-backend/pkg/db"          // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson"           // For MongoDB queries
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+	"go.mongodb.org/mongo-driver/mongo/options"  // For sort and limit
+)
+
+// maxReplayedMessages caps how many missed messages are replayed to a
+// reconnecting user, well below sendBufferSize so the replay can't get the
+// connection dropped as a slow client. Clients catch up on the rest through
+// GET /api/messages/sync.
+const maxReplayedMessages = 100
+
+// missedMessages is what replayMissed hands to the Run loop: the newMessage
+// events to queue for a connection that has just registered.
+type missedMessages struct {
+	client  *Client
+	events  []missedEvent // Oldest first; empty if nothing was missed or loading failed
+	hasMore bool          // More messages were missed than replayed
+}
+
+// missedEvent is one replayed message, marshaled as a newMessage event.
+type missedEvent struct {
+	messageID     primitive.ObjectID
+	event         []byte
+	markDelivered bool // A 1-to-1 message, to mark delivered once queued
+}
+
+// missedMessagesEvent is the payload of the "missedMessages" event sent after
+// a replay, so clients know whether to sync the rest.
+type missedMessagesEvent struct {
+	Count   int  `json:"count"`
+	HasMore bool `json:"hasMore"`
+}
+
+// loadMissedMessages returns the messages userID missed while offline, newest
+// first, up to limit+1 of them. Offline delivery needs no queue of its own:
+// 1-to-1 messages that never reached any of the user's devices are the ones
+// without deliveredAt, and group messages (whose delivery isn't tracked) are
+// those sent to the user's groups since their last connection closed.
+// Only messages created up to connectedAt count: later ones are delivered live.
+func loadMissedMessages(ctx context.Context, userID primitive.ObjectID, connectedAt time.Time, limit int) ([]models.Message, error) {
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"lastSeen": 1})).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs, err := db.DB.Collection("conversations").Distinct(ctx, "_id", bson.M{"participants": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	missed := []bson.M{{
+		"receiverId":     userID,
+		"conversationId": bson.M{"$exists": false},
+		"deliveredAt":    bson.M{"$exists": false},
+		"seen":           bson.M{"$ne": true},
+		"silenced":       bson.M{"$ne": true},
+	}}
+	if len(groupIDs) > 0 {
+		groupMessages := bson.M{"conversationId": bson.M{"$in": groupIDs}, "senderId": bson.M{"$ne": userID}}
+		if !user.LastSeen.IsZero() {
+			groupMessages["createdAt"] = bson.M{"$gt": user.LastSeen}
+		}
+		missed = append(missed, groupMessages)
+	}
+	filter := bson.M{
+		"$or":       missed,
+		"createdAt": bson.M{"$lte": connectedAt},
+		"deleted":   bson.M{"$ne": true},
+		"expiresAt": bson.M{"$not": bson.M{"$lte": time.Now()}}, // Expired disappearing messages are gone
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+	cursor, err := db.DB.Collection("messages").Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	var messages []models.Message
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// replayMissed loads the messages a user missed while offline and passes them
+// to the Run loop, which queues them on client as ordinary newMessage events
+// (skipping any that reached it live in the meantime). Runs in its own
+// goroutine; connectedAt is read before it starts, when the client registers.
+// The Run loop is always told, even with nothing to replay, so it stops
+// tracking live messages; if loading fails the client still has the REST
+// endpoints to catch up with.
+func (h *Hub) replayMissed(client *Client, connectedAt time.Time) {
+	replay := missedMessages{client: client}
+	defer func() {
+		select {
+		case h.missed <- replay:
+		case <-h.done:
+		}
+	}()
+
+	ctx, cancel := db.Context()
+	defer cancel()
+	messages, err := loadMissedMessages(ctx, client.UserID, connectedAt, maxReplayedMessages)
+	if err != nil {
+		slog.Error("Error loading missed messages", "user_id", client.UserID.Hex(), "error", err)
+		return
+	}
+
+	if len(messages) > maxReplayedMessages {
+		messages = messages[:maxReplayedMessages]
+		replay.hasMore = true
+	}
+	// Loaded newest first so the cap keeps the latest; replayed oldest first.
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		event, err := json.Marshal(WebSocketMessage{Event: "newMessage", Payload: NewMessageResponse(message)})
+		if err != nil {
+			slog.Error("Error marshaling missed message", "message_id", message.ID.Hex(), "error", err)
+			continue
+		}
+		replay.events = append(replay.events, missedEvent{
+			messageID:     message.ID,
+			event:         event,
+			markDelivered: !message.IsGroupMessage() && !message.System,
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"context" // For context with MongoDB operations
+
+	"go-backend/pkg/db" // Import db to access MongoDB client
+
+	"go.mongodb.org/mongo-driver/bson" // For MongoDB queries
+)
+
+// RedactReplyPreviews removes the quoted content from the reply previews of
+// the replies matching filter and marks them deleted. Replies snapshot the
+// message they quote, so whenever a message is blanked its replies must be
+// redacted too, or its content would stay readable through them.
+func RedactReplyPreviews(ctx context.Context, filter bson.M) error {
+	filter["replyPreview"] = bson.M{"$exists": true}
+	_, err := db.DB.Collection("messages").UpdateMany(ctx, filter, bson.M{
+		"$set":   bson.M{"replyPreview.deleted": true},
+		"$unset": bson.M{"replyPreview.text": "", "replyPreview.hasImage": "", "replyPreview.hasAudio": ""},
+	})
+	return err
+}
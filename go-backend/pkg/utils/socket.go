@@ -1,33 +1,72 @@
 package utils
 
 import (
+	"bytes"         // For skipping unchanged presence lists
+	"context"       // For the Shutdown deadline
 	"encoding/json" // For marshaling/unmarshaling JSON messages
-	"log"           // For logging messages
+	"errors"        // For recognizing the read limit error
+	"log"           // For fatal configuration errors
+	"log/slog"      // Structured logging
 	"net/http"      // For HTTP status codes and upgrading HTTP to WebSocket
+	"sort"          // For a stable online users order
 	"sync"          // For mutex to protect concurrent map access
+	"time"          // For the offline grace period
 
+	"go-backend/config" // Import config for presence and connection settings
 	"go-backend/internal/models" // Import models for Message struct
+	"go-backend/pkg/db" // For the block lookup's timeout
+	"go-backend/pkg/logging" // Per-request logger of the upgrade request
+	"go-backend/pkg/metrics" // Connection gauges
 
 	"github.com/gin-gonic/gin" // Gin context for handling WebSocket upgrade
 	"github.com/gorilla/websocket" // WebSocket library for Go
 	"go.mongodb.org/mongo-driver/bson/primitive" // For handling ObjectID
 )
 
-// Upgrader is used to upgrade HTTP connections to WebSocket connections.
-// CheckOrigin: allows cross-origin requests. In production, you'd want to restrict this.
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow requests from your frontend origin.
-		return r.Header.Get("Origin") == "http://localhost:5173"
-	},
-}
+// Heartbeat timing. The server pings every connection each pingPeriod; a client
+// that sends nothing (not even a pong) for pongWait is considered dead, its read
+// fails, and it is unregistered like any other disconnect.
+const (
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10 // Must be shorter than pongWait
+	writeWait  = 10 * time.Second    // Time allowed to write a message or ping
+)
+
+// sendBufferSize is how many outgoing messages may queue up for one connection.
+// A client that falls this far behind is disconnected rather than allowed to
+// hold up everyone else; it reconnects and refetches what it missed.
+const sendBufferSize = 256
 
 // Client represents a single WebSocket connection.
 type Client struct {
 	Conn *websocket.Conn
 	UserID primitive.ObjectID // The ID of the user associated with this connection
+	ID     string             // Unique per connection, to correlate its log lines
+
+	// logger carries the connection's request ID, user ID and connection ID.
+	logger *slog.Logger
+
+	// wantsStatuses is set for clients that connected with ?presence=status:
+	// they get getOnlineUsers as {userId, status} entries instead of bare IDs.
+	wantsStatuses bool
+
+	// lastPresence is the last getOnlineUsers message queued for this client,
+	// so an unchanged list isn't sent again. Only touched by the Run goroutine.
+	lastPresence []byte
+
+	// connectedAt is when the Hub registered this connection. A replay only
+	// covers messages created up to then; later ones arrive live.
+	connectedAt time.Time
+
+	// liveMessages collects the messages delivered live to this connection
+	// while its replay is loading, so the replay can skip them. nil when no
+	// replay is pending. Only touched by the Run goroutine.
+	liveMessages map[primitive.ObjectID]bool
+
+	// send queues outgoing messages for this connection's writePump, the only
+	// goroutine that writes to Conn. The Hub enqueues without ever blocking on
+	// socket I/O, and closes send when it unregisters the client.
+	send chan []byte
 }
 
 // WebSocketMessage defines the generic structure for messages sent over WebSocket.
@@ -40,109 +79,659 @@ type WebSocketMessage struct {
 // Hub manages the WebSocket clients (connections) and broadcasting.
 // This is the Go equivalent of Socket.IO's server instance and userSocketMap.
 type Hub struct {
-	clients    map[primitive.ObjectID]*Client // Registered clients: {userID: *Client}
-	broadcast  chan models.Message            // Channel for incoming messages from clients
+	clients    map[primitive.ObjectID]map[*Client]bool // Registered clients: {userID: set of that user's connections}
+	broadcast  chan outgoingMessage           // Channel for new messages to deliver to their recipients
+	events     chan targetedEvent             // Channel for non-message events addressed to specific users
 	register   chan *Client                   // Channel for clients to register
+	disconnect chan disconnectRequest         // Channel for force-closing all of a user's connections
 	unregister chan *Client                   // Channel for clients to unregister
 	mu         sync.Mutex                     // Mutex to protect concurrent access to `clients` map
+
+	// Offline grace period: a user who disconnects stays "online" for
+	// offlineGrace, and is only announced offline if they haven't reconnected
+	// by then. This hides the offline->online flicker of a quick reconnect.
+	offlineGrace   time.Duration
+	pendingOffline map[primitive.ObjectID]pendingOffline // Users inside their grace period
+	offlineGen     uint64                                // Distinguishes successive grace timers for the same user
+	offline        chan offlineEvent                     // Fired by grace timers when they expire
+
+	// Per-IP connection accounting, to stop one host from opening many
+	// connections across different accounts.
+	maxConnsPerIP int            // 0 = unlimited
+	ipConns       map[string]int // Open connections per client IP, guarded by mu
+
+	// Presence broadcast coalescing: with a non-zero debounce, bursts of
+	// connects/disconnects produce a single getOnlineUsers broadcast.
+	// presencePending is only touched from the Run goroutine.
+	presenceDebounce time.Duration
+	presencePending  bool
+	presenceFlush    chan struct{}
+	presenceMode     string
+
+	// Presence statuses (online, away, dnd) of connected users, guarded by mu.
+	// Clients report activity and chosen statuses on presenceUpdates; users
+	// without activity for awayAfter are shown as away (0 = never).
+	presence        map[primitive.ObjectID]*userPresence
+	presenceUpdates chan presenceUpdate
+	awayAfter       time.Duration
+
+	// Contacts-only presence (see presence_contacts.go): the contact set of
+	// each connected user, guarded by mu, filled in from contactsLoaded.
+	contacts       map[primitive.ObjectID]map[primitive.ObjectID]bool
+	contactsLoaded chan contactsLoaded
+
+	// Offline delivery (see replay.go): messages a user missed while offline,
+	// loaded when they reconnect and queued by the Run loop.
+	missed chan missedMessages
+
+	// Typing indicators (see typing.go): who is typing to whom, guarded by mu.
+	typing    map[typingKey]*typingState
+	typingGen uint64 // Distinguishes successive expiry timers of the same state
+
+	// Clustering (HUB_BACKEND=redis): deliveries are published through cluster
+	// and applied by every instance to its own clients, and the online users
+	// list merged across instances arrives on clusterPresence. cluster is nil
+	// with the in-memory backend.
+	cluster         *redisCluster
+	clusterPresence chan []OnlineUser
+	clusterOnline   []OnlineUser // Latest merged list from clusterPresence, guarded by mu
+
+	// healthCheck lets Responsive verify the Run loop is still processing events.
+	healthCheck chan chan struct{}
+
+	// Shutdown: Shutdown sends on shutdown, and Run closes every connection,
+	// closes done and returns. Everything that hands work to the Run loop also
+	// selects on done, so nothing blocks forever once the loop is gone.
+	shutdown chan chan struct{}
+	done     chan struct{}
+	writers  sync.WaitGroup // Running writePumps, so Shutdown can wait for close frames to go out
+
+	// upgrader upgrades HTTP connections to WebSocket connections. Its
+	// CheckOrigin only accepts the configured ALLOWED_ORIGINS.
+	upgrader websocket.Upgrader
+
+	// maxMessageBytes is the read limit of every connection (WS_MAX_MESSAGE_BYTES).
+	maxMessageBytes int64
 }
 
-// NewHub creates and returns a new Hub instance.
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[primitive.ObjectID]*Client),
-		broadcast:  make(chan models.Message),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+// Presence modes, selected with PRESENCE_MODE. full-list and contacts-only
+// are implemented. Tradeoffs:
+//   - full-list: every connect/disconnect sends the whole online list to every
+//     client. Simplest, but O(n²) traffic and everyone sees everyone's presence.
+//   - contacts-only: each user only hears about people they've chatted with.
+//     Less traffic and better privacy, at the cost of tracking contact sets.
+//   - subscription: clients explicitly subscribe to the users they display.
+//     Scales best but needs client cooperation.
+const (
+	PresenceModeFullList     = "full-list"
+	PresenceModeContactsOnly = "contacts-only"
+	PresenceModeSubscription = "subscription"
+)
+
+// pendingOffline tracks the grace timer of a user who recently disconnected.
+type pendingOffline struct {
+	timer *time.Timer
+	gen   uint64
+}
+
+// offlineEvent is sent by an expired grace timer. gen lets the Hub ignore a
+// timer that fired after being superseded by a reconnect and a newer disconnect.
+type offlineEvent struct {
+	userID primitive.ObjectID
+	gen    uint64
+}
+
+// outgoingMessage is a new message queued for delivery, with the users who
+// should receive it: the receiver of a 1-to-1 message, or every other
+// participant of a group conversation.
+type outgoingMessage struct {
+	message    models.Message
+	recipients []primitive.ObjectID
+}
+
+// targetedEvent is an event queued for delivery to some users' connections.
+// Events go through the Run loop (like broadcast messages), which is the only
+// goroutine that touches the clients' send channels.
+type targetedEvent struct {
+	userIDs []primitive.ObjectID
+	all     bool // Every connected user, instead of userIDs
+	message WebSocketMessage
+}
+
+// disconnectRequest asks the Run loop to close every connection of a user,
+// telling the clients why with a close frame.
+type disconnectRequest struct {
+	userID primitive.ObjectID
+	code   int
+	reason string
+}
+
+// NewHub creates and returns a new Hub instance configured from cfg.
+// An invalid or unsupported PRESENCE_MODE, an invalid HUB_BACKEND or, with the
+// redis backend, an unreachable Redis is a fatal configuration error.
+func NewHub(cfg *config.Config) *Hub {
+	switch cfg.PresenceMode {
+	case PresenceModeFullList, PresenceModeContactsOnly:
+	case PresenceModeSubscription:
+		log.Fatalf("PRESENCE_MODE %q is not supported yet; use %q or %q", cfg.PresenceMode, PresenceModeFullList, PresenceModeContactsOnly)
+	default:
+		log.Fatalf("Invalid PRESENCE_MODE %q: expected %q, %q or %q", cfg.PresenceMode,
+			PresenceModeFullList, PresenceModeContactsOnly, PresenceModeSubscription)
 	}
+
+	allowedOrigins := SplitCommaList(cfg.AllowedOrigins)
+
+	hub := &Hub{
+		clients:        make(map[primitive.ObjectID]map[*Client]bool),
+		broadcast:      make(chan outgoingMessage),
+		events:         make(chan targetedEvent),
+		register:       make(chan *Client),
+		disconnect:     make(chan disconnectRequest),
+		unregister:     make(chan *Client),
+		offlineGrace:   cfg.PresenceOfflineGrace,
+		pendingOffline: make(map[primitive.ObjectID]pendingOffline),
+		offline:        make(chan offlineEvent),
+		maxConnsPerIP:  cfg.MaxWSConnectionsPerIP,
+		ipConns:        make(map[string]int),
+
+		presenceDebounce: cfg.PresenceBroadcastDebounce,
+		presenceFlush:    make(chan struct{}),
+		presenceMode:     cfg.PresenceMode,
+
+		presence:        make(map[primitive.ObjectID]*userPresence),
+		presenceUpdates: make(chan presenceUpdate),
+		awayAfter:       cfg.PresenceAwayAfter,
+
+		contacts:       make(map[primitive.ObjectID]map[primitive.ObjectID]bool),
+		contactsLoaded: make(chan contactsLoaded),
+		missed:         make(chan missedMessages),
+
+		typing: make(map[typingKey]*typingState),
+
+		clusterPresence: make(chan []OnlineUser),
+
+		healthCheck: make(chan chan struct{}),
+		shutdown:    make(chan chan struct{}),
+		done:        make(chan struct{}),
+
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  cfg.WSReadBufferSize,
+			WriteBufferSize: cfg.WSWriteBufferSize,
+			CheckOrigin: func(r *http.Request) bool {
+				// Browsers always send Origin, so a present one must be a configured
+				// frontend origin. Non-browser clients (mobile apps, CLIs) send none;
+				// they can't be used for cross-site hijacking and still need the JWT.
+				origin := r.Header.Get("Origin")
+				return origin == "" || OriginAllowed(allowedOrigins, origin)
+			},
+		},
+		maxMessageBytes: cfg.WSMaxMessageBytes,
+	}
+
+	switch cfg.HubBackend {
+	case HubBackendMemory:
+	case HubBackendRedis:
+		cluster, err := newRedisCluster(cfg, hub)
+		if err != nil {
+			log.Fatalf("Failed to set up the Redis Hub backend: %v", err)
+		}
+		hub.cluster = cluster
+	default:
+		log.Fatalf("Invalid HUB_BACKEND %q: expected %q or %q", cfg.HubBackend, HubBackendMemory, HubBackendRedis)
+	}
+	return hub
 }
 
 // Run starts the Hub's goroutines to manage clients and broadcast messages.
 // This should be run as a goroutine in your main function.
 func (h *Hub) Run() {
+	if h.cluster != nil {
+		h.cluster.start()
+	}
+	// Check for idle users a few times per away period, so they turn away
+	// at most a quarter period late.
+	var awayCheck <-chan time.Time
+	if h.awayAfter > 0 {
+		ticker := time.NewTicker(h.awayAfter / 4)
+		defer ticker.Stop()
+		awayCheck = ticker.C
+	}
 	for {
 		select {
 		case client := <-h.register:
 			// A new client wants to register.
+			// A user may be connected from several devices at once; each connection is kept.
 			h.mu.Lock() // Protect map access
-			h.clients[client.UserID] = client
+			_, alreadyConnected := h.clients[client.UserID]
+			pending, reconnected := h.pendingOffline[client.UserID]
+			if reconnected {
+				// Back within the grace period: cancel the pending offline announcement.
+				pending.timer.Stop()
+				delete(h.pendingOffline, client.UserID)
+			}
+			if !alreadyConnected {
+				h.clients[client.UserID] = make(map[*Client]bool)
+			}
+			h.clients[client.UserID][client] = true
+			client.connectedAt = time.Now()
+			if !alreadyConnected {
+				client.liveMessages = make(map[primitive.ObjectID]bool) // Until the replay below arrives
+			}
+			metrics.WebSocketConnections.Inc()
+			metrics.WebSocketUsers.Set(float64(len(h.clients)))
+			// Connecting counts as activity; a fresh connect starts out online.
+			h.touchPresence(presenceUpdate{userID: client.UserID})
+			if _, ok := h.presence[client.UserID]; !ok {
+				h.presence[client.UserID] = &userPresence{status: PresenceOnline, lastActivity: time.Now()}
+			}
+			// Tell the new connection who is typing to its user right now.
+			typingJSON, err := json.Marshal(WebSocketMessage{Event: "typingStates", Payload: h.typingTo(client.UserID)})
+			if err == nil {
+				h.enqueue(client, typingJSON)
+			}
 			h.mu.Unlock()
-			h.sendOnlineUsers() // Notify all clients about updated online users
-			log.Printf("User %s connected. Total online: %d", client.UserID.Hex(), len(h.clients))
+			if !alreadyConnected && h.presenceMode == PresenceModeContactsOnly {
+				go h.fetchContacts(client.UserID) // Until loaded, they only see themselves
+			}
+			if !alreadyConnected {
+				// They were offline: replay what they missed. A user connecting
+				// another device already receives messages live.
+				go h.replayMissed(client, client.connectedAt)
+			}
+			// The new connection needs the online users list. Clients whose list
+			// didn't change (e.g. when the user was already online on another
+			// device, and not shown as away) aren't sent it again.
+			h.schedulePresenceBroadcast()
+			client.logger.Info("WebSocket connected", "online_users", h.OnlineCount())
 
 		case client := <-h.unregister:
 			// A client wants to unregister (disconnect).
-			h.mu.Lock() // Protect map access
-			if _, ok := h.clients[client.UserID]; ok {
-				delete(h.clients, client.UserID)
-				client.Conn.Close() // Close the WebSocket connection
+			client.Conn.Close() // Close the WebSocket connection
+			h.mu.Lock()         // Protect map access
+			connections := h.clients[client.UserID]
+			if !connections[client] {
+				h.mu.Unlock() // Already unregistered
+				continue
+			}
+			delete(connections, client)
+			close(client.send) // Stops the client's writePump; nothing enqueues to it any more
+			metrics.WebSocketConnections.Dec()
+			if len(connections) > 0 {
+				// The user is still connected from another device, so still online.
+				h.mu.Unlock()
+				continue
+			}
+			delete(h.clients, client.UserID)
+			delete(h.contacts, client.UserID) // Reloaded when they connect again
+			go h.clearTyping(client.UserID)    // Sends events, so not on the Run goroutine
+			metrics.WebSocketUsers.Set(float64(len(h.clients)))
+			// Their last connection closed: record when they were last seen, off the Run goroutine.
+			go RecordLastSeen(client.UserID, time.Now())
+			if h.offlineGrace > 0 {
+				// Keep the user "online" for the grace period; see the offline case below.
+				h.offlineGen++
+				userID, gen := client.UserID, h.offlineGen
+				h.pendingOffline[userID] = pendingOffline{
+					timer: time.AfterFunc(h.offlineGrace, func() {
+						select {
+						case h.offline <- offlineEvent{userID: userID, gen: gen}:
+						case <-h.done:
+						}
+					}),
+					gen:   gen,
+				}
+				h.mu.Unlock()
+				client.logger.Info("WebSocket disconnected; user goes offline unless they reconnect", "grace", h.offlineGrace.String())
+				continue
 			}
+			delete(h.presence, client.UserID)
 			h.mu.Unlock()
-			h.sendOnlineUsers() // Notify all clients about updated online users
-			log.Printf("User %s disconnected. Total online: %d", client.UserID.Hex(), len(h.clients))
+			h.schedulePresenceBroadcast() // Notify all clients about updated online users
+			client.logger.Info("WebSocket disconnected; user offline", "online_users", h.OnlineCount())
 
-		case message := <-h.broadcast:
-			// A message needs to be broadcasted to the receiver.
-			h.mu.Lock() // Protect map access
-			receiverClient, ok := h.clients[message.ReceiverID]
+		case req := <-h.disconnect:
+			// Close each of the user's connections. Their read loops then fail
+			// and unregister them through the usual path. WriteControl may be
+			// called concurrently with writePump, but may block up to its
+			// deadline, so it runs off the Run goroutine.
+			h.mu.Lock()
+			for client := range h.clients[req.userID] {
+				go func(conn *websocket.Conn) {
+					closeMsg := websocket.FormatCloseMessage(req.code, req.reason)
+					conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+					conn.Close()
+				}(client.Conn)
+			}
+			h.mu.Unlock()
+
+		case event := <-h.offline:
+			// A grace period expired without the user reconnecting: now they're offline.
+			h.mu.Lock()
+			pending, ok := h.pendingOffline[event.userID]
+			if !ok || pending.gen != event.gen {
+				h.mu.Unlock() // Reconnected (and maybe disconnected again) since this timer started
+				continue
+			}
+			delete(h.pendingOffline, event.userID)
+			delete(h.presence, event.userID)
 			h.mu.Unlock()
+			h.schedulePresenceBroadcast()
+			slog.Info("User offline after grace period", "user_id", event.userID.Hex(), "online_users", h.OnlineCount())
+
+		case reply := <-h.healthCheck:
+			// A readiness probe checking that this loop isn't stuck.
+			close(reply)
 
-			if ok {
-				// Wrap the message in our generic WebSocketMessage structure.
-				wsMessage := WebSocketMessage{
-					Event:   "newMessage", // The event name the frontend expects
-					Payload: message,      // The actual message data
+		case reply := <-h.shutdown:
+			// The server is stopping: close every connection and stop this loop.
+			// Closing send makes each writePump send a "going away" close frame.
+			h.mu.Lock()
+			for _, pending := range h.pendingOffline {
+				pending.timer.Stop()
+			}
+			for _, state := range h.typing {
+				state.timer.Stop()
+			}
+			for userID, connections := range h.clients {
+				for client := range connections {
+					close(client.send)
 				}
-				msgJSON, err := json.Marshal(wsMessage) // Marshal the wrapped message
-				if err != nil {
-					log.Printf("Error marshaling message for receiver %s: %v", message.ReceiverID.Hex(), err)
+				delete(h.clients, userID)
+			}
+			metrics.WebSocketConnections.Set(0)
+			metrics.WebSocketUsers.Set(0)
+			h.mu.Unlock()
+			close(h.done)
+			close(reply)
+			slog.Info("WebSocket Hub stopped")
+			return
+
+		case loaded := <-h.contactsLoaded:
+			// A connected user's contacts arrived: send them their contacts' presence.
+			h.mu.Lock()
+			stillConnected := len(h.clients[loaded.userID]) > 0
+			if stillConnected {
+				h.contacts[loaded.userID] = loaded.contacts
+			}
+			h.mu.Unlock()
+			if stillConnected {
+				h.schedulePresenceBroadcast()
+			}
+
+		case replay := <-h.missed:
+			// Messages a reconnecting user missed: queue them, unless that
+			// connection has closed in the meantime.
+			h.mu.Lock()
+			stillConnected := h.clients[replay.client.UserID][replay.client]
+			var delivery []primitive.ObjectID
+			count := 0
+			if stillConnected {
+				// Skip what already went out live since the connection registered.
+				for _, missed := range replay.events {
+					if replay.client.liveMessages[missed.messageID] {
+						continue
+					}
+					h.enqueue(replay.client, missed.event)
+					count++
+					if missed.markDelivered {
+						delivery = append(delivery, missed.messageID)
+					}
+				}
+				if count > 0 {
+					summary, err := json.Marshal(WebSocketMessage{Event: "missedMessages", Payload: missedMessagesEvent{
+						Count:   count,
+						HasMore: replay.hasMore,
+					}})
+					if err == nil {
+						h.enqueue(replay.client, summary)
+					}
+				}
+			}
+			replay.client.liveMessages = nil // Replay done
+			h.mu.Unlock()
+			if stillConnected && count > 0 {
+				replay.client.logger.Info("Replayed missed messages", "count", count, "has_more", replay.hasMore)
+				// Now on their way to a device, like messages delivered live.
+				go func(messageIDs []primitive.ObjectID, receiverID primitive.ObjectID) {
+					for _, messageID := range messageIDs {
+						h.markDelivered(messageID, receiverID)
+					}
+				}(delivery, replay.client.UserID)
+			}
+
+		case update := <-h.presenceUpdates:
+			// Activity or a setStatus from one of the user's connections.
+			h.mu.Lock()
+			changed := h.touchPresence(update)
+			h.mu.Unlock()
+			if changed {
+				h.schedulePresenceBroadcast()
+			}
+
+		case <-awayCheck:
+			// Show users who have been inactive for too long as away.
+			h.mu.Lock()
+			changed := h.markIdleUsers(h.awayAfter)
+			h.mu.Unlock()
+			if changed {
+				h.schedulePresenceBroadcast()
+			}
+
+		case <-h.presenceFlush:
+			// The debounce window closed: send one coalesced presence update.
+			h.presencePending = false
+			h.sendOnlineUsers()
+
+		case onlineUsers := <-h.clusterPresence:
+			// The online users of every instance, merged: remember them for
+			// OnlineUserIDs and pass them on to our clients.
+			h.mu.Lock()
+			h.clusterOnline = onlineUsers
+			h.mu.Unlock()
+			h.broadcastOnlineUsers(onlineUsers)
+
+		case outgoing := <-h.broadcast:
+			// A message needs to be delivered to each of its recipients that is online.
+			// Wrap the message in our generic WebSocketMessage structure once for all of them,
+			// in the same shape the REST endpoints return it.
+			wsMessage := WebSocketMessage{
+				Event:   "newMessage",                          // The event name the frontend expects
+				Payload: NewMessageResponse(outgoing.message), // The actual message data
+			}
+			msgJSON, err := json.Marshal(wsMessage) // Marshal the wrapped message
+			if err != nil {
+				slog.Error("Error marshaling message", "message_id", outgoing.message.ID.Hex(), "error", err)
+				continue
+			}
+
+			for _, recipientID := range outgoing.recipients {
+				if !h.writeMessageToUser(recipientID, outgoing.message.ID, msgJSON) {
+					// Replayed from the database when they reconnect (see replay.go).
+					slog.Debug("Recipient offline; message will be replayed on reconnect", "user_id", recipientID.Hex(), "message_id", outgoing.message.ID.Hex())
 					continue
 				}
-				if err := receiverClient.Conn.WriteMessage(websocket.TextMessage, msgJSON); err != nil {
-					log.Printf("Error sending message to receiver %s: %v", message.ReceiverID.Hex(), err)
-					// Consider unregistering client if write fails consistently
+				// Queued for a live connection of the receiver: mark a 1-to-1
+				// message delivered, off the Run goroutine. Clients also ack each
+				// newMessage they receive (see handleClientMessage); whichever
+				// comes first counts.
+				if !outgoing.message.IsGroupMessage() && !outgoing.message.System {
+					go h.markDelivered(outgoing.message.ID, recipientID)
+				}
+			}
+			// A first 1-to-1 message makes its sender and receiver contacts, who
+			// now see each other's presence in contacts-only mode.
+			if h.presenceMode == PresenceModeContactsOnly && !outgoing.message.IsGroupMessage() {
+				h.mu.Lock()
+				newContacts := h.addContacts(outgoing.message.SenderID, outgoing.message.ReceiverID)
+				h.mu.Unlock()
+				if newContacts {
+					h.schedulePresenceBroadcast()
 				}
-			} else {
-				log.Printf("Receiver %s is offline. Message not sent via WebSocket.", message.ReceiverID.Hex())
-				// In a real app, you might queue this message for offline delivery or push notifications.
+			}
+			// Also echo the message to the sender's own connections, so their other
+			// devices show it live. The device that sent it already has it from the
+			// HTTP response and must ignore the duplicate (same _id).
+			h.writeToUser(outgoing.message.SenderID, msgJSON)
+
+		case event := <-h.events:
+			// An event (e.g. a read receipt) needs to reach its users, on all their devices.
+			// Marshaled once, however many users it goes to.
+			msgJSON, err := json.Marshal(event.message)
+			if err != nil {
+				slog.Error("Error marshaling event", "event", event.message.Event, "recipients", len(event.userIDs), "error", err)
+				continue
+			}
+			if event.all {
+				h.writeToAll(msgJSON)
+				continue
+			}
+			for _, userID := range event.userIDs {
+				h.writeToUser(userID, msgJSON) // Offline users simply miss transient events
 			}
 		}
 	}
 }
 
-// sendOnlineUsers sends the list of currently online user IDs to all connected clients.
-func (h *Hub) sendOnlineUsers() {
+// writeToUser queues msgJSON for every connection of the given user and
+// reports whether the user had any. Must only be called from the Run goroutine,
+// which owns closing the clients' send channels.
+func (h *Hub) writeToUser(userID primitive.ObjectID, msgJSON []byte) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	for client := range h.clients[userID] {
+		h.enqueue(client, msgJSON)
+	}
+	return len(h.clients[userID]) > 0
+}
 
-	onlineUserIDs := make([]string, 0, len(h.clients))
-	for userID := range h.clients {
-		onlineUserIDs = append(onlineUserIDs, userID.Hex())
+// writeMessageToUser is writeToUser for a newMessage event: connections
+// waiting for their replay also remember the message, so it isn't replayed
+// to them a second time.
+func (h *Hub) writeMessageToUser(userID, messageID primitive.ObjectID, msgJSON []byte) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients[userID] {
+		if client.liveMessages != nil {
+			client.liveMessages[messageID] = true
+		}
+		h.enqueue(client, msgJSON)
 	}
+	return len(h.clients[userID]) > 0
+}
 
-	// Create a structured message for online users, similar to Socket.IO's event.
-	// The frontend will expect an event like "getOnlineUsers".
-	// Now using the generic WebSocketMessage struct.
-	onlineUsersMessage := WebSocketMessage{
-		Event:   "getOnlineUsers",
-		Payload: onlineUserIDs, // The list of user IDs
+// writeToAll queues msgJSON for every connected client. Must only be called
+// from the Run goroutine, like writeToUser.
+func (h *Hub) writeToAll(msgJSON []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, connections := range h.clients {
+		for client := range connections {
+			h.enqueue(client, msgJSON)
+		}
 	}
+}
 
-	msgJSON, err := json.Marshal(onlineUsersMessage)
-	if err != nil {
-		log.Printf("Error marshaling online users message: %v", err)
+// enqueue hands msgJSON to the client's writePump without blocking. If the
+// client's buffer is full, the client is too slow to keep up: its connection
+// is closed, which ends its read loop and unregisters it through the usual path.
+// Must only be called from the Run goroutine.
+func (h *Hub) enqueue(client *Client, msgJSON []byte) {
+	select {
+	case client.send <- msgJSON:
+	default:
+		client.logger.Warn("Send buffer full; disconnecting slow client")
+		client.Conn.Close()
+	}
+}
+
+// schedulePresenceBroadcast sends the online users list now, or, when a debounce
+// window is configured, once the window closes (coalescing everything that
+// changed in between). Must only be called from the Run goroutine.
+func (h *Hub) schedulePresenceBroadcast() {
+	if h.presenceDebounce <= 0 {
+		h.sendOnlineUsers()
 		return
 	}
+	if h.presencePending {
+		return // A broadcast is already scheduled and will include this change
+	}
+	h.presencePending = true
+	time.AfterFunc(h.presenceDebounce, func() {
+		select {
+		case h.presenceFlush <- struct{}{}:
+		case <-h.done:
+		}
+	})
+}
+
+// sendOnlineUsers sends the list of currently online users, with their
+// statuses, to all connected clients.
+// When clustered, it shares this instance's users instead; the merged list of
+// all instances then comes back on clusterPresence.
+// Must only be called from the Run goroutine.
+func (h *Hub) sendOnlineUsers() {
+	h.mu.Lock()
+	// Users inside their offline grace period still count as online.
+	onlineUsers := make([]OnlineUser, 0, len(h.clients)+len(h.pendingOffline))
+	for userID := range h.clients {
+		onlineUsers = append(onlineUsers, OnlineUser{UserID: userID.Hex(), Status: h.statusOf(userID)})
+	}
+	for userID := range h.pendingOffline {
+		onlineUsers = append(onlineUsers, OnlineUser{UserID: userID.Hex(), Status: h.statusOf(userID)})
+	}
+	h.mu.Unlock()
+	// A stable order lets broadcastOnlineUsers spot unchanged lists.
+	sort.Slice(onlineUsers, func(i, j int) bool { return onlineUsers[i].UserID < onlineUsers[j].UserID })
+
+	if h.cluster != nil {
+		h.cluster.setLocalPresence(onlineUsers)
+		return
+	}
+	h.broadcastOnlineUsers(onlineUsers)
+}
+
+// broadcastOnlineUsers sends the given online users list to all connected clients.
+// Must only be called from the Run goroutine.
+func (h *Hub) broadcastOnlineUsers(onlineUsers []OnlineUser) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	// Iterate over all clients and send the online users list.
-	for _, client := range h.clients {
-		if err := client.Conn.WriteMessage(websocket.TextMessage, msgJSON); err != nil {
-			log.Printf("Error sending online users to client %s: %v", client.UserID.Hex(), err)
-			// Potentially unregister this client if write fails
+	// Iterate over all users (and each of their devices) and queue the part
+	// of the online users list they may see, skipping clients that already
+	// have exactly that list.
+	for userID, connections := range h.clients {
+		withStatuses, idsOnly, err := onlineUsersMessages(h.visibleTo(userID, onlineUsers))
+		if err != nil {
+			slog.Error("Error marshaling online users message", "error", err)
+			return
+		}
+		for client := range connections {
+			msgJSON := idsOnly
+			if client.wantsStatuses {
+				msgJSON = withStatuses
+			}
+			if bytes.Equal(msgJSON, client.lastPresence) {
+				continue
+			}
+			client.lastPresence = msgJSON
+			h.enqueue(client, msgJSON)
 		}
 	}
 }
 
+// onlineUsersMessages builds the getOnlineUsers message in its two formats,
+// similar to Socket.IO's event: clients that asked for statuses get
+// {userId, status} entries; the others keep getting the original list of IDs
+// (away and dnd users are online too).
+func onlineUsersMessages(onlineUsers []OnlineUser) (withStatuses, idsOnly []byte, err error) {
+	withStatuses, err = json.Marshal(WebSocketMessage{Event: "getOnlineUsers", Payload: onlineUsers})
+	if err != nil {
+		return nil, nil, err
+	}
+	idsOnly, err = json.Marshal(WebSocketMessage{Event: "getOnlineUsers", Payload: onlineUserIDs(onlineUsers)})
+	return withStatuses, idsOnly, err
+}
+
 // WebSocketHandler upgrades the HTTP connection to a WebSocket connection.
 // It registers the new client with the Hub.
 // This will be used as a Gin route handler.
@@ -150,46 +739,193 @@ func WebSocketHandler(c *gin.Context, hub *Hub) {
 	// Get the authenticated user from the context (set by AuthMiddleware)
 	userAny, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized - User not found in context"})
+		RespondError(c, http.StatusUnauthorized, ErrCodeUnauthorized, "Unauthorized - User not found in context")
 		return
 	}
 	loggedInUser := userAny.(models.User)
 
 	// Upgrade the HTTP connection to a WebSocket connection.
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := hub.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection to WebSocket: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to establish WebSocket connection"})
+		logging.FromContext(c).Warn("Failed to upgrade connection to WebSocket", "error", err)
+		RespondError(c, http.StatusInternalServerError, ErrCodeInternal, "Failed to establish WebSocket connection")
+		return
+	}
+
+	// Enforce the per-IP cap. The check happens after the upgrade so the client
+	// receives a proper "try again later" close frame instead of a bare HTTP error.
+	ip := c.ClientIP()
+	if !hub.acquireIPSlot(ip) {
+		logging.FromContext(c).Warn("Rejecting WebSocket: too many connections from this IP", "client_ip", ip)
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Too many connections from your network")
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
 		return
 	}
 
 	// Create a new Client instance and register it with the Hub.
-	client := &Client{Conn: conn, UserID: loggedInUser.ID}
-	hub.register <- client // Send client to the register channel
+	connID := primitive.NewObjectID().Hex()
+	client := &Client{
+		Conn:   conn,
+		UserID: loggedInUser.ID,
+		ID:     connID,
+		logger: logging.FromContext(c).With("conn_id", connID), // Already carries request_id and user_id
+		send:   make(chan []byte, sendBufferSize),
+
+		wantsStatuses: c.Query("presence") == "status",
+	}
+	hub.writers.Add(1) // Before registering, so Shutdown can't miss this client's writePump
+	select {
+	case hub.register <- client: // Send client to the register channel
+	case <-hub.done:
+		// The server is shutting down.
+		hub.writers.Done()
+		conn.Close()
+		hub.releaseIPSlot(ip)
+		return
+	}
+
+	// All writes to the connection (messages and pings) happen on this goroutine.
+	go func() {
+		defer hub.writers.Done()
+		client.writePump()
+	}()
 
 	// Start a goroutine to continuously read messages from the WebSocket connection.
 	// This loop keeps the connection alive and handles incoming messages (if any, though chat is outbound).
 	go func() {
 		defer func() {
-			hub.unregister <- client // Ensure client is unregistered on exit
+			select {
+			case hub.unregister <- client: // Ensure client is unregistered on exit
+			case <-hub.done: // The Hub already closed every connection on shutdown
+			}
 			conn.Close()
+			hub.releaseIPSlot(ip)
 		}()
 
+		// A message larger than the limit makes ReadMessage fail before it is
+		// buffered; gorilla sends a 1009 (message too big) close frame and we
+		// unregister the client like any other disconnect.
+		conn.SetReadLimit(hub.maxMessageBytes)
+
+		// Any frame from the client, including a pong, proves the connection is alive.
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+
 		for {
-			// ReadMessage blocks until a message is received or an error occurs.
+			// ReadMessage blocks until a message is received or an error occurs
+			// (including the read deadline passing because pongs stopped arriving).
 			// We primarily send messages from server to client, but this keeps the connection open.
 			// If clients were sending messages to the server, this is where they'd be processed.
-			_, _, err := conn.ReadMessage()
+			_, data, err := conn.ReadMessage()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("WebSocket read error for user %s: %v", loggedInUser.ID.Hex(), err)
+				if errors.Is(err, websocket.ErrReadLimit) {
+					client.logger.Warn("Closing WebSocket: message exceeds the size limit", "limit_bytes", hub.maxMessageBytes)
+				} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					client.logger.Warn("WebSocket read error", "error", err)
 				}
 				break // Exit the loop on error (e.g., client disconnected)
 			}
-			// If a message was read, you could process it here if your frontend sends messages
-			// via this same WebSocket connection for other purposes.
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			hub.handleClientMessage(client, data)
+		}
+	}()
+}
+
+// handleClientMessage processes an event sent by a client. Unknown events and
+// malformed messages are ignored. Runs on the client's read goroutine, so a
+// client flooding events only slows down its own connection.
+func (h *Hub) handleClientMessage(client *Client, data []byte) {
+	var incoming struct {
+		Event   string          `json:"event"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &incoming); err != nil {
+		return
+	}
+	// Any event from the client shows the user is active; setStatus also
+	// chooses their status.
+	update := presenceUpdate{userID: client.UserID}
+	if incoming.Event == "setStatus" {
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(incoming.Payload, &payload); err != nil || !validPresenceStatus(payload.Status) {
+			return
+		}
+		update.status = payload.Status
+	}
+	select {
+	case h.presenceUpdates <- update:
+	case <-h.done:
+		return
+	}
+
+	switch incoming.Event {
+	case "messageDelivered":
+		// The client received a newMessage: confirm its delivery to the sender.
+		var ack deliveryAck
+		if err := json.Unmarshal(incoming.Payload, &ack); err != nil {
+			return
 		}
+		messageID, err := primitive.ObjectIDFromHex(ack.MessageID)
+		if err != nil {
+			return
+		}
+		h.markDelivered(messageID, client.UserID)
+	case "typing", "stopTyping":
+		// The user started (or is still) typing to, or stopped typing to, receiverId.
+		var payload struct {
+			ReceiverID string `json:"receiverId"`
+		}
+		if err := json.Unmarshal(incoming.Payload, &payload); err != nil {
+			return
+		}
+		receiverID, err := primitive.ObjectIDFromHex(payload.ReceiverID)
+		if err != nil {
+			return
+		}
+		if incoming.Event == "typing" {
+			h.startTyping(client.UserID, receiverID)
+		} else {
+			h.stopTyping(client.UserID, receiverID)
+		}
+	}
+}
+
+// writePump writes the messages queued on c.send to the connection and pings
+// it every pingPeriod. It returns when the Hub closes c.send (sending a "going
+// away" close frame, which only reaches clients still connected, i.e. on server
+// shutdown) or when a write fails; a failed write closes the connection, which
+// ends the read loop and unregisters the client.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
 	}()
+	for {
+		select {
+		case msgJSON, ok := <-c.send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait)) // A dead peer mustn't hang this goroutine forever
+			if !ok {
+				// The Hub unregistered this client.
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down"))
+				return
+			}
+			if err := c.Conn.WriteMessage(websocket.TextMessage, msgJSON); err != nil {
+				c.logger.Warn("Error writing to WebSocket", "error", err)
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
 }
 
 // EmitNewMessage is a public function to send a new message via the Hub's broadcast channel.
@@ -197,8 +933,8 @@ func WebSocketHandler(c *gin.Context, hub *Hub) {
 var currentHub *Hub // Global reference to the Hub
 
 // InitWebSocketHub initializes the global Hub. Call this once in main.go.
-func InitWebSocketHub() *Hub {
-	currentHub = NewHub()
+func InitWebSocketHub(cfg *config.Config) *Hub {
+	currentHub = NewHub(cfg)
 	go currentHub.Run() // Start the Hub's goroutine
 	return currentHub
 }
@@ -209,11 +945,253 @@ func GetHub() *Hub {
 }
 
 // EmitNewMessage sends a message to the broadcast channel of the global Hub.
-// This is the function that will be called from `chat.handler.go`'s `SendMessage` method.
+// Handlers should prefer an injected MessageEmitter; this remains for callers
+// that don't have one.
 func EmitNewMessage(message models.Message) {
 	if currentHub != nil {
-		currentHub.broadcast <- message
+		currentHub.EmitNewMessage(message)
 	} else {
-		log.Println("WebSocket Hub not initialized. Cannot emit message.")
+		slog.Error("WebSocket Hub not initialized; cannot emit message", "message_id", message.ID.Hex())
+	}
+}
+
+// MessageEmitter is the dependency handlers use to push real-time message events.
+// *Hub implements it; tests can substitute sockettest.FakeEmitter.
+type MessageEmitter interface {
+	EmitNewMessage(message models.Message)
+	EmitConversationMessage(message models.Message, participants []primitive.ObjectID)
+	SendToUser(userID primitive.ObjectID, event string, payload interface{})
+	SendToUsers(userIDs []primitive.ObjectID, event string, payload interface{})
+	Broadcast(event string, payload interface{})
+}
+
+// OnlineUsersSource reports who is online. Implemented by Hub; handlers depend
+// on the interface so they can be exercised without a running Hub.
+type OnlineUsersSource interface {
+	OnlineUserIDs() []primitive.ObjectID
+}
+
+// EmitNewMessage queues a message for delivery to its receiver as a "newMessage" event.
+func (h *Hub) EmitNewMessage(message models.Message) {
+	// SendMessage already refuses blocked pairs; this catches a block that
+	// landed in between. On a lookup error we still deliver: the message is stored anyway.
+	ctx, cancel := db.Context()
+	defer cancel()
+	blocked, err := BlockExists(ctx, message.SenderID, message.ReceiverID)
+	if err != nil {
+		slog.Error("Error checking blocks before delivering message", "message_id", message.ID.Hex(), "error", err)
+	}
+	if blocked {
+		return
+	}
+	h.dispatch(hubEnvelope{Type: envelopeMessage, Message: &message, Recipients: []primitive.ObjectID{message.ReceiverID}})
+}
+
+// EmitConversationMessage queues a group message for delivery, as a "newMessage"
+// event, to every participant of its conversation except the sender.
+func (h *Hub) EmitConversationMessage(message models.Message, participants []primitive.ObjectID) {
+	recipients := make([]primitive.ObjectID, 0, len(participants))
+	for _, participantID := range participants {
+		if participantID != message.SenderID {
+			recipients = append(recipients, participantID)
+		}
+	}
+	h.dispatch(hubEnvelope{Type: envelopeMessage, Message: &message, Recipients: recipients})
+}
+
+// SendToUser, SendToUsers and Broadcast are the way to push any event that
+// isn't a new message: the payload is wrapped in a WebSocketMessage and
+// marshaled once by the Run loop, then queued for each connection. Sending
+// never waits on clients: a connection whose buffer is full is closed (see
+// enqueue), and one that stops reading hits the write deadline in writePump.
+// Offline users miss the event; nothing is stored for later.
+
+// SendToUser queues an arbitrary event for all of the given user's connections, if they are online.
+func (h *Hub) SendToUser(userID primitive.ObjectID, event string, payload interface{}) {
+	h.dispatch(hubEnvelope{Type: envelopeEvent, UserID: userID, Event: &WebSocketMessage{Event: event, Payload: payload}})
+}
+
+// SendToUsers queues an event for every connection of each of the given users
+// who is online. It is one delivery however many users there are, so prefer
+// it to calling SendToUser in a loop.
+func (h *Hub) SendToUsers(userIDs []primitive.ObjectID, event string, payload interface{}) {
+	if len(userIDs) == 0 {
+		return
+	}
+	h.dispatch(hubEnvelope{Type: envelopeEvent, Recipients: userIDs, Event: &WebSocketMessage{Event: event, Payload: payload}})
+}
+
+// Broadcast queues an event for every connected client. When clustered, that
+// is every client of every instance.
+func (h *Hub) Broadcast(event string, payload interface{}) {
+	h.dispatch(hubEnvelope{Type: envelopeBroadcast, Event: &WebSocketMessage{Event: event, Payload: payload}})
+}
+
+// DisconnectUser closes all of the user's WebSocket connections, sending a
+// close frame with the given code and reason (e.g. after account deletion).
+func (h *Hub) DisconnectUser(userID primitive.ObjectID, code int, reason string) {
+	h.dispatch(hubEnvelope{Type: envelopeDisconnect, UserID: userID, Code: code, Reason: reason})
+}
+
+// dispatch delivers an envelope to the users' connections on every instance
+// when clustered, or on this one otherwise. If publishing fails, this
+// instance's clients still get it.
+func (h *Hub) dispatch(envelope hubEnvelope) {
+	if h.cluster != nil {
+		err := h.cluster.publish(envelope)
+		if err == nil {
+			return // Comes back through the subscription, like for every other instance
+		}
+		slog.Error("Error publishing to Redis; delivering to this instance only", "type", envelope.Type, "error", err)
+	}
+	h.deliverLocally(envelope)
+}
+
+// deliverLocally hands an envelope to the Run loop, for the clients connected
+// to this instance.
+func (h *Hub) deliverLocally(envelope hubEnvelope) {
+	switch envelope.Type {
+	case envelopeMessage:
+		if envelope.Message == nil {
+			return
+		}
+		select {
+		case h.broadcast <- outgoingMessage{message: *envelope.Message, recipients: envelope.Recipients}:
+		case <-h.done: // Shutting down; the message is stored and clients refetch on reconnect
+		}
+	case envelopeEvent:
+		if envelope.Event == nil {
+			return
+		}
+		recipients := envelope.Recipients
+		if len(recipients) == 0 {
+			recipients = []primitive.ObjectID{envelope.UserID} // Sent by SendToUser
+		}
+		select {
+		case h.events <- targetedEvent{userIDs: recipients, message: *envelope.Event}:
+		case <-h.done:
+		}
+	case envelopeBroadcast:
+		if envelope.Event == nil {
+			return
+		}
+		select {
+		case h.events <- targetedEvent{all: true, message: *envelope.Event}:
+		case <-h.done:
+		}
+	case envelopeDisconnect:
+		select {
+		case h.disconnect <- disconnectRequest{userID: envelope.UserID, code: envelope.Code, reason: envelope.Reason}:
+		case <-h.done:
+		}
+	}
+}
+
+// Shutdown closes every WebSocket connection with a "going away" close frame
+// and stops the Run loop. It waits until the close frames have been written,
+// or until ctx is done. Calling it again is a no-op.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case h.shutdown <- reply:
+		<-reply
+	case <-h.done:
+		return nil // Already stopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		if h.cluster != nil {
+			h.cluster.close() // Take this instance's users off the shared online list
+		}
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Responsive reports whether the Run loop picks up and answers a health check
+// within timeout. A stuck loop fails it.
+func (h *Hub) Responsive(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	reply := make(chan struct{})
+	select {
+	case h.healthCheck <- reply:
+	case <-timer.C:
+		return false
+	}
+	select {
+	case <-reply:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// OnlineCount returns the number of users currently online, including those
+// inside their offline grace period. When clustered, only users connected to
+// this instance are counted.
+func (h *Hub) OnlineCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients) + len(h.pendingOffline)
+}
+
+// OnlineUserIDs returns the users currently online, including those inside
+// their offline grace period. When clustered, it's the list merged across
+// every instance as of the last presence refresh.
+func (h *Hub) OnlineUserIDs() []primitive.ObjectID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cluster != nil {
+		userIDs := make([]primitive.ObjectID, 0, len(h.clusterOnline))
+		for _, user := range h.clusterOnline {
+			if userID, err := primitive.ObjectIDFromHex(user.UserID); err == nil {
+				userIDs = append(userIDs, userID)
+			}
+		}
+		return userIDs
+	}
+
+	userIDs := make([]primitive.ObjectID, 0, len(h.clients)+len(h.pendingOffline))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID)
+	}
+	for userID := range h.pendingOffline {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// acquireIPSlot reserves a connection slot for ip, reporting false when the
+// per-IP cap is already reached. Every successful call must be paired with releaseIPSlot.
+func (h *Hub) acquireIPSlot(ip string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maxConnsPerIP > 0 && h.ipConns[ip] >= h.maxConnsPerIP {
+		return false
+	}
+	h.ipConns[ip]++
+	return true
+}
+
+// releaseIPSlot frees a slot reserved by acquireIPSlot.
+func (h *Hub) releaseIPSlot(ip string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ipConns[ip] <= 1 {
+		delete(h.ipConns, ip)
+		return
 	}
+	h.ipConns[ip]--
 }
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-backend/config"
+	"go-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newCapTestServer serves WebSocketHandler behind a stand-in for
+// AuthMiddleware. Instead of the Run loop (which needs MongoDB), a goroutine
+// accepts registrations and, on unregister, closes the client's send channel
+// like the Hub does, so its writePump ends.
+func newCapTestServer(t *testing.T, maxPerIP int) (*Hub, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(&config.Config{
+		PresenceMode:          PresenceModeFullList,
+		HubBackend:            HubBackendMemory,
+		MaxWSConnectionsPerIP: maxPerIP,
+		WSReadBufferSize:      1024,
+		WSWriteBufferSize:     1024,
+		WSMaxMessageBytes:     1 << 10,
+	})
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hub.register:
+			case client := <-hub.unregister:
+				close(client.send)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	engine := gin.New()
+	engine.GET("/ws", func(c *gin.Context) {
+		c.Set("user", models.User{ID: primitive.NewObjectID()})
+		WebSocketHandler(c, hub)
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(func() {
+		server.Close()
+		close(stop)
+	})
+	return hub, "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+// ipConnCount returns the open connections the Hub counts for ip.
+func ipConnCount(hub *Hub, ip string) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return hub.ipConns[ip]
+}
+
+// waitForIPConns waits until the Hub counts want connections for ip.
+func waitForIPConns(t *testing.T, hub *Hub, ip string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for ipConnCount(hub, ip) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("connections from %s = %d, want %d", ip, ipConnCount(hub, ip), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketPerIPCap(t *testing.T) {
+	const maxPerIP = 3
+	const ip = "127.0.0.1" // httptest listens on loopback
+	hub, url := newCapTestServer(t, maxPerIP)
+
+	var conns []*websocket.Conn
+	for i := 0; i < maxPerIP; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("connection %d: %v", i+1, err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	waitForIPConns(t, hub, ip, maxPerIP)
+
+	// One more from the same IP is upgraded, then closed with "try again later".
+	extra, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("connection over the cap: %v", err)
+	}
+	defer extra.Close()
+	extra.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = extra.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseTryAgainLater {
+		t.Fatalf("connection over the cap: got %v, want a %d close", err, websocket.CloseTryAgainLater)
+	}
+	if got := ipConnCount(hub, ip); got != maxPerIP {
+		t.Errorf("rejected connection changed the count to %d, want %d", got, maxPerIP)
+	}
+
+	// Disconnecting frees a slot, and a new connection may take it.
+	conns[0].Close()
+	waitForIPConns(t, hub, ip, maxPerIP-1)
+
+	again, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("connection after a disconnect: %v", err)
+	}
+	defer again.Close()
+	waitForIPConns(t, hub, ip, maxPerIP)
+	again.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := again.ReadMessage(); errors.As(err, &closeErr) {
+		t.Errorf("connection after a disconnect was closed: %v", err)
+	}
+}
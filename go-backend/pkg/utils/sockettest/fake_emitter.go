@@ -0,0 +1,112 @@
+// Package sockettest provides test doubles for the WebSocket layer in pkg/utils,
+// so handlers that push real-time events can be tested without a live Hub.
+package sockettest
+
+import (
+	"sync" // For guarding the recorded events across goroutines
+
+	"go-backend/internal/models" // Import models for the Message struct
+	"go-backend/pkg/utils"       // Import utils for the WebSocketMessage envelope
+
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// FakeEmitter implements utils.MessageEmitter by recording every emitted event
+// instead of writing to sockets. The zero value is ready to use.
+type FakeEmitter struct {
+	mu         sync.Mutex
+	events     []utils.WebSocketMessage
+	recipients [][]primitive.ObjectID // recipients[i] are the users events[i] was sent to
+}
+
+// record appends an event and the users it was sent to. Callers hold mu.
+func (f *FakeEmitter) record(recipients []primitive.ObjectID, event string, payload interface{}) {
+	f.events = append(f.events, utils.WebSocketMessage{Event: event, Payload: payload})
+	f.recipients = append(f.recipients, append([]primitive.ObjectID(nil), recipients...))
+}
+
+// EmitNewMessage records the message wrapped exactly as the Hub would send it,
+// in the same shape the REST endpoints return it.
+func (f *FakeEmitter) EmitNewMessage(message models.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record([]primitive.ObjectID{message.ReceiverID}, "newMessage", utils.NewMessageResponse(message))
+}
+
+// EmitConversationMessage records the group message like EmitNewMessage does,
+// sent to every participant except the sender, as the Hub delivers it.
+func (f *FakeEmitter) EmitConversationMessage(message models.Message, participants []primitive.ObjectID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var recipients []primitive.ObjectID
+	for _, participantID := range participants {
+		if participantID != message.SenderID {
+			recipients = append(recipients, participantID)
+		}
+	}
+	f.record(recipients, "newMessage", utils.NewMessageResponse(message))
+}
+
+// SendToUser records the event, sent to userID.
+func (f *FakeEmitter) SendToUser(userID primitive.ObjectID, event string, payload interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record([]primitive.ObjectID{userID}, event, payload)
+}
+
+// SendToUsers records the event once, sent to all of userIDs, like the Hub's
+// single delivery. Nothing is recorded for an empty list.
+func (f *FakeEmitter) SendToUsers(userIDs []primitive.ObjectID, event string, payload interface{}) {
+	if len(userIDs) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(userIDs, event, payload)
+}
+
+// Broadcast records the event with nil recipients, meaning everyone.
+func (f *FakeEmitter) Broadcast(event string, payload interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record(nil, event, payload)
+}
+
+// SentTo returns the recipients of every recorded event, index-aligned with
+// Events: SentTo()[i] are the users Events()[i] was sent to (nil for a Broadcast).
+func (f *FakeEmitter) SentTo() [][]primitive.ObjectID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]primitive.ObjectID(nil), f.recipients...)
+}
+
+// Events returns a copy of all events recorded so far, in emission order.
+func (f *FakeEmitter) Events() []utils.WebSocketMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]utils.WebSocketMessage(nil), f.events...)
+}
+
+// Messages returns the payloads of the recorded "newMessage" events.
+func (f *FakeEmitter) Messages() []utils.MessageResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []utils.MessageResponse
+	for _, event := range f.events {
+		if msg, ok := event.Payload.(utils.MessageResponse); ok && event.Event == "newMessage" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Reset clears the recorded events.
+func (f *FakeEmitter) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = nil
+	f.recipients = nil
+}
+
+// Compile-time check that FakeEmitter satisfies the interface handlers depend on.
+var _ utils.MessageEmitter = (*FakeEmitter)(nil)
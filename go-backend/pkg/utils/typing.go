@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"log/slog" // Structured logging
+	"time"     // For the typing expiry
+
+	"go-backend/pkg/db" // For the block lookup's timeout
+
+	"go.mongodb.org/mongo-driver/bson/primitive" // For ObjectID
+)
+
+// Typing indicators for 1-to-1 chats. A client sends "typing" with a
+// receiverId while its user types, repeating it at least every typingTimeout,
+// and "stopTyping" when they stop. The Hub remembers who is typing to whom, so
+// the receiver gets a "typing" event when a state starts or ends, and a new
+// connection gets a "typingStates" snapshot of the users currently typing to it.
+// A state that isn't refreshed expires after typingTimeout, so an indicator
+// can't get stuck when a client disappears without sending "stopTyping".
+
+// typingTimeout is how long a typing state lasts without a refresh.
+const typingTimeout = 3 * time.Second
+
+// typingKey identifies one typing state: from is typing a message to to.
+type typingKey struct {
+	from primitive.ObjectID
+	to   primitive.ObjectID
+}
+
+// typingState is an active typing state and the timer that expires it. gen
+// tells a stale timer (one that was reset or stopped) from the current one.
+type typingState struct {
+	timer *time.Timer
+	gen   uint64
+}
+
+// typingEvent is the payload of the "typing" event sent to the receiver.
+type typingEvent struct {
+	UserID string `json:"userId"` // Who is typing
+	Typing bool   `json:"typing"` // false when they stopped, or their state expired
+}
+
+// startTyping records (or refreshes) that from is typing to to, and tells to
+// when the state is new. Called from the client's read goroutine.
+func (h *Hub) startTyping(from, to primitive.ObjectID) {
+	if from == to || to.IsZero() {
+		return
+	}
+	key := typingKey{from: from, to: to}
+
+	h.mu.Lock()
+	if state, ok := h.typing[key]; ok {
+		// Still typing: push the expiry back.
+		h.typingGen++
+		state.gen = h.typingGen
+		state.timer.Stop()
+		state.timer = h.typingTimer(key, state.gen)
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+
+	// Only new states reach the receiver, so only they need the block check.
+	ctx, cancel := db.Context()
+	defer cancel()
+	blocked, err := BlockExists(ctx, from, to)
+	if err != nil {
+		slog.Error("Error checking blocks before sending typing state", "user_id", from.Hex(), "error", err)
+		return
+	}
+	if blocked {
+		return
+	}
+
+	h.mu.Lock()
+	if _, ok := h.typing[key]; ok {
+		h.mu.Unlock() // Another connection of the same user got there first
+		return
+	}
+	h.typingGen++
+	h.typing[key] = &typingState{timer: h.typingTimer(key, h.typingGen), gen: h.typingGen}
+	h.mu.Unlock()
+
+	h.SendToUser(to, "typing", typingEvent{UserID: from.Hex(), Typing: true})
+}
+
+// stopTyping ends the typing state of from towards to, if there is one.
+func (h *Hub) stopTyping(from, to primitive.ObjectID) {
+	key := typingKey{from: from, to: to}
+	h.mu.Lock()
+	state, ok := h.typing[key]
+	if ok {
+		state.timer.Stop()
+		delete(h.typing, key)
+	}
+	h.mu.Unlock()
+	if ok {
+		h.SendToUser(to, "typing", typingEvent{UserID: from.Hex(), Typing: false})
+	}
+}
+
+// typingTimer starts the timer that expires a typing state, unless the state
+// has been refreshed or stopped (changing its gen) by then. Caller holds mu.
+func (h *Hub) typingTimer(key typingKey, gen uint64) *time.Timer {
+	return time.AfterFunc(typingTimeout, func() {
+		h.mu.Lock()
+		state, ok := h.typing[key]
+		current := ok && state.gen == gen
+		if current {
+			delete(h.typing, key)
+		}
+		h.mu.Unlock()
+		if current {
+			h.SendToUser(key.to, "typing", typingEvent{UserID: key.from.Hex(), Typing: false})
+		}
+	})
+}
+
+// clearTyping ends every typing state of a user, e.g. when their last
+// connection closes. Must not be called from the Run goroutine, as it sends events.
+func (h *Hub) clearTyping(userID primitive.ObjectID) {
+	h.mu.Lock()
+	var receivers []primitive.ObjectID
+	for key, state := range h.typing {
+		if key.from == userID {
+			state.timer.Stop()
+			delete(h.typing, key)
+			receivers = append(receivers, key.to)
+		}
+	}
+	h.mu.Unlock()
+	for _, to := range receivers {
+		h.SendToUser(to, "typing", typingEvent{UserID: userID.Hex(), Typing: false})
+	}
+}
+
+// typingTo returns the hex IDs of the users currently typing to userID, for
+// the "typingStates" snapshot of a new connection. Caller holds mu.
+func (h *Hub) typingTo(userID primitive.ObjectID) []string {
+	typers := []string{}
+	for key := range h.typing {
+		if key.to == userID {
+			typers = append(typers, key.from.Hex())
+		}
+	}
+	return typers
+}
@@ -0,0 +1,57 @@
+package workers
+
+import (
+	"context"  // For the shared cancellation signal
+	"fmt"      // For formatted error messages
+	"log/slog" // For logging worker lifecycle events
+	"sync"     // For tracking running workers with a WaitGroup
+	"time"     // For the shutdown timeout
+)
+
+// Manager starts background workers (scheduled jobs, cleanup loops, ...) and
+// stops them together during graceful shutdown.
+// Every worker receives the same context, which is cancelled by Shutdown;
+// workers must return promptly once ctx.Done() is closed.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a Manager with a fresh cancellable context.
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in its own goroutine and tracks it until it returns.
+// The name is only used for logging.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		slog.Info("Worker started", "worker", name)
+		fn(m.ctx)
+		slog.Info("Worker stopped cleanly", "worker", name)
+	}()
+}
+
+// Shutdown cancels the shared context and waits for all workers to return.
+// If they haven't all finished within the timeout it gives up and returns an
+// error, so a stuck worker can't block process exit forever.
+func (m *Manager) Shutdown(timeout time.Duration) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("background workers did not stop within %s", timeout)
+	}
+}